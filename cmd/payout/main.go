@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"dojo-api/pkg/payout"
+
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+/*
+Usage:
+This script is used to generate and check payout batches, it does not make any chain calls.
+Run the script with one of the following commands:
+
+go run cmd/payout/main.go create-batch
+  - Moves every available worker balance into a new payout batch and builds its merkle tree.
+
+go run cmd/payout/main.go finalise-batch <batch-id>
+  - Builds the merkle tree of a batch that failed to finalise when it was created.
+
+go run cmd/payout/main.go verify-batch <batch-id>
+  - Recomputes every leaf of a batch and checks its proof against the merkle root.
+
+go run cmd/payout/main.go settle-batch <batch-id>
+  - Records that a batch was paid on chain, moving its payouts from pending to paid out in the ledger.
+*/
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Error().Msg("No action provided. Use 'create-batch', 'finalise-batch', 'verify-batch' or 'settle-batch'")
+		return
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Warn().Err(err).Msg("Error loading .env file")
+	}
+
+	ctx := context.Background()
+	payoutService := payout.NewPayoutService()
+
+	action := os.Args[1]
+	switch action {
+	case "create-batch":
+		batch, err := payoutService.CreateBatch(ctx)
+		if err != nil {
+			if errors.Is(err, payout.ErrNothingToPay) {
+				log.Info().Msg("No available balances to pay out")
+				return
+			}
+			log.Error().Err(err).Msg("Failed to create payout batch")
+			return
+		}
+		merkleRoot, _ := batch.MerkleRoot()
+		log.Info().Str("batchId", batch.ID).Str("merkleRoot", merkleRoot).Str("totalAmount", batch.TotalAmount.String()).Msg("Payout batch created")
+	case "finalise-batch", "verify-batch", "settle-batch":
+		if len(os.Args) < 3 {
+			log.Error().Msgf("No batch id provided. Use '%s <batch-id>'", action)
+			return
+		}
+		batchId := os.Args[2]
+
+		if action == "finalise-batch" {
+			if err := payoutService.FinaliseBatch(ctx, batchId); err != nil {
+				log.Error().Err(err).Str("batchId", batchId).Msg("Failed to finalise payout batch")
+			}
+			return
+		}
+
+		if action == "settle-batch" {
+			if err := payoutService.SettleBatch(ctx, batchId); err != nil {
+				log.Error().Err(err).Str("batchId", batchId).Msg("Failed to settle payout batch")
+			}
+			return
+		}
+
+		if err := payoutService.VerifyBatch(ctx, batchId); err != nil {
+			log.Error().Err(err).Str("batchId", batchId).Msg("Payout batch verification failed")
+			return
+		}
+		log.Info().Str("batchId", batchId).Msg("Every payout proof matches the merkle root")
+	default:
+		log.Error().Msg("Unknown action. Use 'create-batch', 'finalise-batch', 'verify-batch' or 'settle-batch'")
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"syscall"
 	"time"
 
+	"dojo-api/db"
 	"dojo-api/pkg/api"
 	"dojo-api/pkg/cache"
 	"dojo-api/pkg/orm"
+	"dojo-api/pkg/task"
+	"dojo-api/pkg/webhook"
 	"dojo-api/utils"
 
 	_ "dojo-api/docs"
@@ -31,7 +34,16 @@ import (
 func main() {
 	loadEnvVars()
 	go continuouslyReadEnv()
-	go orm.NewTaskORM().UpdateExpiredTasks(context.Background())
+	webhookService := webhook.GetWebhookService()
+	taskFeedService := task.NewTaskFeedService()
+	go orm.NewTaskORM().UpdateExpiredTasks(context.Background(), func(ctx context.Context, tasks []db.TaskModel) {
+		webhookService.NotifyTasksExpired(ctx, tasks)
+		taskFeedService.PublishTasksExpired(ctx, tasks)
+	})
+	go task.NewSettlementService().SettlePendingTasks(context.Background())
+	go task.NewCommitmentService().CommitPendingTasks(context.Background())
+	go task.NewDraftService().DeleteStaleDrafts(context.Background())
+	go webhookService.ProcessDeliveries(context.Background())
 
 	runtimeEnv := utils.LoadDotEnv("RUNTIME_ENV")
 	if runtimeEnv == "aws" {
@@ -0,0 +1,35 @@
+-- AlterTable
+ALTER TABLE "Task" ADD COLUMN     "gold_answers" JSONB,
+ADD COLUMN     "is_gold" BOOLEAN NOT NULL DEFAULT false;
+
+-- AlterTable
+ALTER TABLE "DojoWorker" ADD COLUMN     "is_flagged" BOOLEAN NOT NULL DEFAULT false;
+
+-- CreateTable
+CREATE TABLE "GoldTaskGrade" (
+    "id" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+    "task_id" TEXT NOT NULL,
+    "worker_id" TEXT NOT NULL,
+    "task_result_id" TEXT NOT NULL,
+    "passed" BOOLEAN NOT NULL,
+    "details" JSONB NOT NULL,
+
+    CONSTRAINT "GoldTaskGrade_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "GoldTaskGrade_task_result_id_key" ON "GoldTaskGrade"("task_result_id");
+
+-- CreateIndex
+CREATE INDEX "GoldTaskGrade_worker_id_created_at_idx" ON "GoldTaskGrade"("worker_id", "created_at");
+
+-- AddForeignKey
+ALTER TABLE "GoldTaskGrade" ADD CONSTRAINT "GoldTaskGrade_task_id_fkey" FOREIGN KEY ("task_id") REFERENCES "Task"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "GoldTaskGrade" ADD CONSTRAINT "GoldTaskGrade_worker_id_fkey" FOREIGN KEY ("worker_id") REFERENCES "DojoWorker"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "GoldTaskGrade" ADD CONSTRAINT "GoldTaskGrade_task_result_id_fkey" FOREIGN KEY ("task_result_id") REFERENCES "TaskResult"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "DojoWorker" ADD COLUMN     "reputation" DOUBLE PRECISION NOT NULL DEFAULT 0.5;
+
+-- AlterTable
+ALTER TABLE "Task" ADD COLUMN     "min_reputation" DOUBLE PRECISION;
@@ -0,0 +1,46 @@
+-- CreateEnum
+CREATE TYPE "LedgerEntryType" AS ENUM ('REWARD_ACCRUED', 'PENALTY', 'PAYOUT_REQUESTED', 'PAYOUT_SETTLED');
+
+-- CreateEnum
+CREATE TYPE "LedgerAccount" AS ENUM ('WORKER_AVAILABLE', 'WORKER_PENDING_PAYOUT', 'REWARD_POOL', 'PAID_OUT');
+
+-- CreateTable
+CREATE TABLE "LedgerEntry" (
+    "id" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "transaction_id" TEXT NOT NULL,
+    "type" "LedgerEntryType" NOT NULL,
+    "account" "LedgerAccount" NOT NULL,
+    "amount" DECIMAL(36,18) NOT NULL,
+    "worker_id" TEXT NOT NULL,
+    "task_id" TEXT,
+    "task_result_id" TEXT,
+
+    CONSTRAINT "LedgerEntry_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "LedgerEntry_transaction_id_account_key" ON "LedgerEntry"("transaction_id", "account");
+
+-- CreateIndex
+CREATE INDEX "LedgerEntry_worker_id_account_created_at_idx" ON "LedgerEntry"("worker_id", "account", "created_at");
+
+-- AddForeignKey
+ALTER TABLE "LedgerEntry" ADD CONSTRAINT "LedgerEntry_worker_id_fkey" FOREIGN KEY ("worker_id") REFERENCES "DojoWorker"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "LedgerEntry" ADD CONSTRAINT "LedgerEntry_task_id_fkey" FOREIGN KEY ("task_id") REFERENCES "Task"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "LedgerEntry" ADD CONSTRAINT "LedgerEntry_task_result_id_fkey" FOREIGN KEY ("task_result_id") REFERENCES "TaskResult"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- Ledger entries are immutable, corrections are posted as new entries
+CREATE FUNCTION "ledger_entry_immutable"() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'LedgerEntry rows cannot be updated or deleted';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "LedgerEntry_immutable"
+BEFORE UPDATE OR DELETE ON "LedgerEntry"
+FOR EACH ROW EXECUTE FUNCTION "ledger_entry_immutable"();
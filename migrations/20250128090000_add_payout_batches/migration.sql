@@ -0,0 +1,41 @@
+-- CreateEnum
+CREATE TYPE "PayoutBatchStatus" AS ENUM ('PENDING', 'READY', 'SETTLED');
+
+-- CreateTable
+CREATE TABLE "PayoutBatch" (
+    "id" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+    "status" "PayoutBatchStatus" NOT NULL DEFAULT 'PENDING',
+    "merkle_root" TEXT,
+    "token_decimals" INTEGER NOT NULL,
+    "total_amount" DECIMAL(36,18) NOT NULL,
+
+    CONSTRAINT "PayoutBatch_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+CREATE TABLE "PayoutBatchLeaf" (
+    "id" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+    "batch_id" TEXT NOT NULL,
+    "worker_id" TEXT NOT NULL,
+    "wallet_address" TEXT NOT NULL,
+    "chain_id" TEXT NOT NULL,
+    "amount" DECIMAL(36,18) NOT NULL,
+    "amount_units" TEXT,
+    "leaf_hash" TEXT,
+    "proof" JSONB,
+
+    CONSTRAINT "PayoutBatchLeaf_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "PayoutBatchLeaf_batch_id_worker_id_key" ON "PayoutBatchLeaf"("batch_id", "worker_id");
+
+-- AddForeignKey
+ALTER TABLE "PayoutBatchLeaf" ADD CONSTRAINT "PayoutBatchLeaf_batch_id_fkey" FOREIGN KEY ("batch_id") REFERENCES "PayoutBatch"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "PayoutBatchLeaf" ADD CONSTRAINT "PayoutBatchLeaf_worker_id_fkey" FOREIGN KEY ("worker_id") REFERENCES "DojoWorker"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
@@ -0,0 +1,4 @@
+-- AlterTable
+ALTER TABLE "TaskResult" ADD COLUMN     "signature" TEXT,
+ADD COLUMN     "signature_scheme" TEXT,
+ADD COLUMN     "signed_result_data" TEXT;
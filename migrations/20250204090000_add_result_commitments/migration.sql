@@ -0,0 +1,17 @@
+-- AlterTable
+ALTER TABLE "Task" ADD COLUMN     "commitment_batch_id" TEXT,
+ADD COLUMN     "commitment_proof" JSONB,
+ADD COLUMN     "results_hash" TEXT;
+
+-- CreateTable
+CREATE TABLE "CommitmentBatch" (
+    "id" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "merkle_root" TEXT NOT NULL,
+    "num_tasks" INTEGER NOT NULL,
+
+    CONSTRAINT "CommitmentBatch_pkey" PRIMARY KEY ("id")
+);
+
+-- AddForeignKey
+ALTER TABLE "Task" ADD CONSTRAINT "Task_commitment_batch_id_fkey" FOREIGN KEY ("commitment_batch_id") REFERENCES "CommitmentBatch"("id") ON DELETE SET NULL ON UPDATE CASCADE;
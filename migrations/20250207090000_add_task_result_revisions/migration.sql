@@ -0,0 +1,20 @@
+-- CreateTable
+CREATE TABLE "TaskResultRevision" (
+    "id" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "task_result_id" TEXT NOT NULL,
+    "revision" INTEGER NOT NULL,
+    "submitted_at" TIMESTAMP(3) NOT NULL,
+    "result_data" JSONB NOT NULL,
+    "signature" TEXT,
+    "signature_scheme" TEXT,
+    "signed_result_data" TEXT,
+
+    CONSTRAINT "TaskResultRevision_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "TaskResultRevision_task_result_id_revision_key" ON "TaskResultRevision"("task_result_id", "revision");
+
+-- AddForeignKey
+ALTER TABLE "TaskResultRevision" ADD CONSTRAINT "TaskResultRevision_task_result_id_fkey" FOREIGN KEY ("task_result_id") REFERENCES "TaskResult"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
@@ -0,0 +1,24 @@
+-- CreateTable
+CREATE TABLE "TaskDraft" (
+    "id" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL,
+    "task_id" TEXT NOT NULL,
+    "worker_id" TEXT NOT NULL,
+    "result_data" JSONB NOT NULL,
+    "expire_at" TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT "TaskDraft_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "TaskDraft_expire_at_idx" ON "TaskDraft"("expire_at");
+
+-- CreateIndex
+CREATE UNIQUE INDEX "TaskDraft_task_id_worker_id_key" ON "TaskDraft"("task_id", "worker_id");
+
+-- AddForeignKey
+ALTER TABLE "TaskDraft" ADD CONSTRAINT "TaskDraft_task_id_fkey" FOREIGN KEY ("task_id") REFERENCES "Task"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "TaskDraft" ADD CONSTRAINT "TaskDraft_worker_id_fkey" FOREIGN KEY ("worker_id") REFERENCES "DojoWorker"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
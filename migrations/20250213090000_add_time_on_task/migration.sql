@@ -0,0 +1,23 @@
+-- AlterTable
+ALTER TABLE "TaskResult" ADD COLUMN     "is_fast_submission" BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN     "opened_at" TIMESTAMP(3),
+ADD COLUMN     "time_on_task_seconds" DOUBLE PRECISION;
+
+-- CreateTable
+CREATE TABLE "TaskOpen" (
+    "id" TEXT NOT NULL,
+    "opened_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "task_id" TEXT NOT NULL,
+    "worker_id" TEXT NOT NULL,
+
+    CONSTRAINT "TaskOpen_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "TaskOpen_task_id_worker_id_key" ON "TaskOpen"("task_id", "worker_id");
+
+-- AddForeignKey
+ALTER TABLE "TaskOpen" ADD CONSTRAINT "TaskOpen_task_id_fkey" FOREIGN KEY ("task_id") REFERENCES "Task"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "TaskOpen" ADD CONSTRAINT "TaskOpen_worker_id_fkey" FOREIGN KEY ("worker_id") REFERENCES "DojoWorker"("id") ON DELETE RESTRICT ON UPDATE CASCADE;
@@ -0,0 +1,16 @@
+-- AlterTable
+ALTER TABLE "Task" ADD COLUMN     "completed_at" TIMESTAMP(3);
+
+-- Backfill completed tasks with the submission time of their last result
+UPDATE "Task" t
+SET completed_at = r.completed_at
+FROM (
+  SELECT task_id, MAX(created_at) AS completed_at
+  FROM "TaskResult"
+  WHERE status = 'COMPLETED'
+  GROUP BY task_id
+) r
+WHERE t.id = r.task_id AND t.status = 'COMPLETED';
+
+-- CreateIndex
+CREATE INDEX "Task_miner_user_id_created_at_idx" ON "Task"("miner_user_id", "created_at");
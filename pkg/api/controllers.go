@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,14 +16,18 @@ import (
 	"dojo-api/pkg/auth"
 	"dojo-api/pkg/blockchain/siws"
 	"dojo-api/pkg/cache"
+	"dojo-api/pkg/export"
 	"dojo-api/pkg/metric"
 	"dojo-api/pkg/miner"
 	"dojo-api/pkg/orm"
+	"dojo-api/pkg/payout"
 	"dojo-api/pkg/task"
+	"dojo-api/pkg/webhook"
 	"dojo-api/pkg/worker"
 	"dojo-api/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/gorilla/securecookie"
@@ -105,6 +111,8 @@ func WorkerLoginController(c *gin.Context) {
 //	@Param			TaskData		formData	string						true	"Task data in JSON format"
 //	@Param			MaxResults		formData	int							true	"Maximum results"
 //	@Param			TotalRewards	formData	float64						true	"Total rewards"
+//	@Param			IsGold			formData	bool						false	"Whether the tasks are gold tasks, graded against the goldAnswers of each task data"
+//	@Param			MinReputation	formData	float64						false	"Minimum worker reputation between 0 and 1 required to see the tasks"
 //	@Param			files			formData	[]file						true	"Files to upload (can upload multiple files)"
 //	@Success		200				{object}	ApiResponse{body=[]string}	"Tasks created successfully"
 //	@Failure		400				{object}	ApiResponse					"Bad request, invalid form data, or failed to process request"
@@ -182,10 +190,64 @@ func CreateTasksController(c *gin.Context) {
 	for _, task := range tasks {
 		taskIds = append(taskIds, task.ID)
 	}
+	handleTaskFeedEvent(task.TaskFeedEventCreated, tasks...)
 
 	c.JSON(http.StatusOK, defaultSuccessResponse(taskIds))
 }
 
+// CancelTaskController godoc
+//
+//	@Summary		Cancel task
+//	@Description	Cancel an in-progress task created by the miner, so that it is no longer shown to workers
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			x-api-key	header		string									true	"API Key for Miner Authentication"
+//	@Param			task-id		path		string									true	"Task ID"
+//	@Success		200			{object}	ApiResponse{body=task.CancelTaskResponse}	"Task cancelled successfully"
+//	@Failure		401			{object}	ApiResponse								"Unauthorized access"
+//	@Failure		403			{object}	ApiResponse								"Task does not belong to miner"
+//	@Failure		404			{object}	ApiResponse								"Task not found"
+//	@Failure		409			{object}	ApiResponse								"Task is not in progress"
+//	@Failure		500			{object}	ApiResponse								"Internal server error"
+//	@Router			/tasks/{task-id} [delete]
+func CancelTaskController(c *gin.Context) {
+	minerUserInterface, exists := c.Get("minerUser")
+	minerUser, _ := minerUserInterface.(*db.MinerUserModel)
+	if !exists || minerUser == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, defaultErrorResponse("Unauthorized"))
+		return
+	}
+
+	taskId := c.Param("task-id")
+	if taskId == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("task id is required"))
+		return
+	}
+
+	taskService := task.NewTaskService()
+	cancelledTask, err := taskService.CancelTask(c.Request.Context(), taskId, minerUser.ID)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Str("minerUserId", minerUser.ID).Msg("Failed to cancel task")
+		switch {
+		case errors.Is(err, task.ErrTaskNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse(err.Error()))
+		case errors.Is(err, task.ErrTaskNotOwnedByMiner):
+			c.AbortWithStatusJSON(http.StatusForbidden, defaultErrorResponse(err.Error()))
+		case errors.Is(err, task.ErrTaskNotCancellable):
+			c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to cancel task"))
+		}
+		return
+	}
+
+	log.Info().Str("taskId", taskId).Str("minerUserId", minerUser.ID).Msg("Task cancelled successfully")
+	c.JSON(http.StatusOK, defaultSuccessResponse(task.CancelTaskResponse{
+		TaskId: cancelledTask.ID,
+		Status: cancelledTask.Status,
+	}))
+}
+
 // SubmitTaskResultController godoc
 //
 //	@Summary		Submit task result
@@ -197,11 +259,13 @@ func CreateTasksController(c *gin.Context) {
 //	@Param			task-id			path		string											true	"Task ID"
 //	@Param			body			body		task.SubmitTaskResultRequest					true	"Request body containing the task result data"
 //	@Success		200				{object}	ApiResponse{body=task.SubmitTaskResultResponse}	"Task result submitted successfully"
-//	@Failure		400				{object}	ApiResponse										"Invalid request body or task is expired"
+//	@Failure		400				{object}	ApiResponse										"Invalid request body or signature, task is expired or cancelled"
 //	@Failure		401				{object}	ApiResponse										"Unauthorized"
+//	@Failure		403				{object}	ApiResponse										"Worker reputation is below the task's minimum"
 //	@Failure		404				{object}	ApiResponse										"Task not found"
 //	@Failure		409				{object}	ApiResponse										"Task result already completed by worker"
 //	@Failure		409				{object}	ApiResponse										"Task has reached max results"
+//	@Failure		409				{object}	ApiResponse										"Task slots are reserved by other workers"
 //	@Failure		500				{object}	ApiResponse										"Internal server error"
 //	@Router			/tasks/submit-result/{task-id} [put]
 func SubmitTaskResultController(c *gin.Context) {
@@ -228,9 +292,9 @@ func SubmitTaskResultController(c *gin.Context) {
 		return
 	}
 
-	// Validate the request body for required fields [resultData]
+	// Validate the request body for required fields [resultData], the body is kept to verify the signature
 	var requestBody task.SubmitTaskResultRequest
-	if err := c.BindJSON(&requestBody); err != nil {
+	if err := c.ShouldBindBodyWith(&requestBody, binding.JSON); err != nil {
 		log.Error().Err(err).Msg("Failed to bind JSON to requestBody")
 		c.JSON(http.StatusBadRequest, defaultErrorResponse("Invalid request body"))
 		c.Abort()
@@ -241,10 +305,21 @@ func SubmitTaskResultController(c *gin.Context) {
 	ctx := c.Request.Context()
 	taskService := task.NewTaskService()
 
+	var signedResult *task.SignedResult
+	if requestBody.Signature != nil {
+		signedResult, err = verifySubmissionSignature(c, taskId, *requestBody.Signature, worker)
+		if err != nil {
+			log.Info().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Result signature rejected")
+			c.JSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+			c.Abort()
+			return
+		}
+	}
+
 	// Fetch the task data
 	taskData, err := taskService.GetTaskById(ctx, taskId)
 	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
+		if errors.Is(err, task.ErrTaskNotFound) {
 			log.Error().Err(err).Str("taskId", taskId).Msg("Task not found")
 			c.JSON(http.StatusNotFound, defaultErrorResponse(err.Error()))
 			c.Abort()
@@ -263,10 +338,18 @@ func SubmitTaskResultController(c *gin.Context) {
 		return
 	}
 
+	// Check if the task was withdrawn by the miner
+	if taskData.Status == db.TaskStatusCancelled {
+		log.Info().Str("taskId", taskId).Msg("Task is cancelled")
+		c.JSON(http.StatusBadRequest, defaultErrorResponse("Task has been cancelled by miner"))
+		c.Abort()
+		return
+	}
+
 	// Check if the task has reached max results
 	if taskData.MaxResults == taskData.NumResults || taskData.Status == db.TaskStatusCompleted {
 		log.Info().Str("taskId", taskId).Msg("Task has reached max results")
-		c.JSON(http.StatusBadRequest, defaultErrorResponse("Task has reached max results"))
+		c.JSON(http.StatusConflict, defaultErrorResponse("Task has reached max results"))
 		c.Abort()
 		return
 	}
@@ -287,11 +370,48 @@ func SubmitTaskResultController(c *gin.Context) {
 		return
 	}
 
+	// Once every remaining slot is leased, only lease holders may submit
+	leaseService := task.NewLeaseService()
+	canSubmit, err := leaseService.CanSubmit(ctx, taskData, worker.ID)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error checking task leases")
+		c.JSON(http.StatusInternalServerError, defaultErrorResponse(err.Error()))
+		c.Abort()
+		return
+	}
+
+	if !canSubmit {
+		log.Info().Str("taskId", taskId).Str("workerId", worker.ID).Msg("Task slots are reserved by other workers")
+		c.JSON(http.StatusConflict, defaultErrorResponse(task.ErrNoLeaseSlotAvailable.Error()))
+		c.Abort()
+		return
+	}
+
 	log.Info().Str("Dojo Worker ID", worker.ID).Str("Task ID", taskId).Msg("Dojo Worker and Task ID pulled")
 
 	// Update the task with the result data
-	updatedTask, err := taskService.UpdateTaskResults(ctx, taskData, worker.ID, requestBody.ResultData)
+	updatedTask, err := taskService.UpdateTaskResults(ctx, taskData, worker, requestBody.ResultData, signedResult)
 	if err != nil {
+		if errors.Is(err, task.ErrInsufficientReputation) {
+			log.Info().Str("Dojo Worker ID", worker.ID).Str("Task ID", taskId).Msg("Worker reputation is below the task's minimum")
+			c.JSON(http.StatusForbidden, defaultErrorResponse(err.Error()))
+			c.Abort()
+			return
+		}
+		// Other workers leased the remaining slots between the lease check and the insert
+		if errors.Is(err, task.ErrNoLeaseSlotAvailable) {
+			log.Info().Str("Dojo Worker ID", worker.ID).Str("Task ID", taskId).Msg("Task slots are reserved by other workers")
+			c.JSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+			c.Abort()
+			return
+		}
+		// Another worker filled the last slot between our read and the conditional update
+		if errors.Is(err, orm.ErrMaxResultsReached) {
+			log.Info().Str("Dojo Worker ID", worker.ID).Str("Task ID", taskId).Msg("Task has reached max results")
+			c.JSON(http.StatusConflict, defaultErrorResponse("Task has reached max results"))
+			c.Abort()
+			return
+		}
 		log.Error().Err(err).Str("Dojo Worker ID", worker.ID).Str("Task ID", taskId).Msg("Error updating task with result data")
 		c.JSON(http.StatusInternalServerError, defaultErrorResponse(err.Error()))
 		c.Abort()
@@ -301,15 +421,373 @@ func SubmitTaskResultController(c *gin.Context) {
 	// Remove from cache
 	cache := cache.GetCacheInstance()
 	cache.DeleteWithSuffix(cache.Keys.TaskResultByWorker, worker.ID)
+	if err := task.NewAggregationService().InvalidateTaskAggregate(taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to invalidate task aggregate")
+	}
+	if err := task.NewAgreementService().InvalidateTaskAgreement(taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to invalidate task agreement")
+	}
+
+	// The worker's slot is now filled by the result, so free up the lease
+	if err := leaseService.ReleaseLease(ctx, taskId, worker.ID); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Failed to release task lease")
+	}
+	if err := task.NewDraftService().DiscardDraft(ctx, taskId, worker.ID); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Failed to discard task draft")
+	}
+
+	// Commit the results before responding so the commitment can be read as soon as the task completes,
+	// tasks that fail to commit here are picked up by the periodic commitment
+	if updatedTask.Status == db.TaskStatusCompleted {
+		if err := task.NewCommitmentService().CommitTask(ctx, updatedTask.ID); err != nil {
+			log.Error().Err(err).Str("taskId", updatedTask.ID).Msg("Failed to commit task results")
+		}
+	}
 
 	// Update the metric data with goroutine
 	handleMetricData(taskData, updatedTask)
+	handleReputationUpdate(worker.ID)
+	handleWebhookEvent(webhook.EventResultSubmitted, updatedTask)
+	if updatedTask.Status == db.TaskStatusCompleted {
+		handleTaskSettlement(updatedTask.ID)
+		handleWebhookEvent(webhook.EventTaskCompleted, updatedTask)
+		handleTaskFeedEvent(task.TaskFeedEventFilled, updatedTask)
+	}
 
 	c.JSON(http.StatusOK, defaultSuccessResponse(task.SubmitTaskResultResponse{
 		NumResults: updatedTask.NumResults,
 	}))
 }
 
+// ReviseTaskResultController godoc
+//
+//	@Summary		Revise task result
+//	@Description	Replace the worker's completed result while the task is still in progress, the previous version is kept in the result's history
+//	@Tags			Tasks
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string											true	"Bearer token"
+//	@Param			task-id			path		string											true	"Task ID"
+//	@Param			body			body		task.SubmitTaskResultRequest					true	"Request body containing the revised task result data"
+//	@Success		200				{object}	ApiResponse{body=task.SubmitTaskResultResponse}	"Task result revised successfully"
+//	@Failure		400				{object}	ApiResponse										"Invalid request body or signature"
+//	@Failure		401				{object}	ApiResponse										"Unauthorized"
+//	@Failure		403				{object}	ApiResponse										"Worker reputation is below the task's minimum"
+//	@Failure		404				{object}	ApiResponse										"Task or worker's completed task result not found"
+//	@Failure		409				{object}	ApiResponse										"Task is no longer in progress or the result is being revised by another request"
+//	@Failure		500				{object}	ApiResponse										"Internal server error"
+//	@Router			/tasks/{task-id}/result [put]
+func ReviseTaskResultController(c *gin.Context) {
+	worker := getAuthenticatedWorker(c)
+	if worker == nil {
+		return
+	}
+
+	// the body is kept to verify the signature
+	var requestBody task.SubmitTaskResultRequest
+	if err := c.ShouldBindBodyWith(&requestBody, binding.JSON); err != nil {
+		log.Error().Err(err).Msg("Failed to bind JSON to requestBody")
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("Invalid request body"))
+		return
+	}
+
+	taskId := c.Param("task-id")
+	ctx := c.Request.Context()
+	taskService := task.NewTaskService()
+
+	var signedResult *task.SignedResult
+	if requestBody.Signature != nil {
+		var err error
+		signedResult, err = verifySubmissionSignature(c, taskId, *requestBody.Signature, worker)
+		if err != nil {
+			log.Info().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Result signature rejected")
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	taskData, err := taskService.GetTaskById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, task.ErrTaskNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
+			return
+		}
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error getting Task")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse(err.Error()))
+		return
+	}
+
+	if taskData.Status != db.TaskStatusInProgress || taskData.ExpireAt.Before(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(orm.ErrTaskResultNotRevisable.Error()))
+		return
+	}
+
+	isCompletedTResult, err := taskService.ValidateCompletedTResultByWorker(ctx, taskId, worker.ID)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error validating completed task result")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse(err.Error()))
+		return
+	}
+
+	if !isCompletedTResult {
+		c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("No completed task result to revise"))
+		return
+	}
+
+	revisedTaskResult, err := taskService.ReviseTaskResult(ctx, taskData, worker, requestBody.ResultData, signedResult)
+	if err != nil {
+		if errors.Is(err, task.ErrInsufficientReputation) {
+			c.AbortWithStatusJSON(http.StatusForbidden, defaultErrorResponse(err.Error()))
+			return
+		}
+		// The task completed or expired between our read and the revision, or another revision got in first
+		if errors.Is(err, orm.ErrTaskResultNotRevisable) || errors.Is(err, orm.ErrTaskResultRevisionConflict) {
+			c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("Dojo Worker ID", worker.ID).Str("Task ID", taskId).Msg("Error revising task result")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse(err.Error()))
+		return
+	}
+
+	log.Info().Str("taskId", taskId).Str("workerId", worker.ID).Str("taskResultId", revisedTaskResult.ID).Msg("Task result revised")
+
+	// Remove from cache
+	cache := cache.GetCacheInstance()
+	cache.DeleteWithSuffix(cache.Keys.TaskResultByWorker, worker.ID)
+	if err := task.NewAggregationService().InvalidateTaskAggregate(taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to invalidate task aggregate")
+	}
+	if err := task.NewAgreementService().InvalidateTaskAgreement(taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to invalidate task agreement")
+	}
+
+	if err := task.NewDraftService().DiscardDraft(ctx, taskId, worker.ID); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Failed to discard task draft")
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(task.SubmitTaskResultResponse{
+		NumResults: revisedTaskResult.Task().NumResults,
+	}))
+}
+
+// ClaimTaskLeaseController godoc
+//
+//	@Summary		Claim task lease
+//	@Description	Reserve one of the task's remaining result slots for the worker for a limited time, so the result can still be submitted once other workers fill the task
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			Authorization	header		string								true	"Bearer token"
+//	@Param			task-id			path		string								true	"Task ID"
+//	@Success		200				{object}	ApiResponse{body=task.TaskLease}	"Task lease claimed successfully"
+//	@Failure		400				{object}	ApiResponse							"Task is not in progress"
+//	@Failure		401				{object}	ApiResponse							"Unauthorized"
+//	@Failure		403				{object}	ApiResponse							"Worker reputation is below the task's minimum"
+//	@Failure		404				{object}	ApiResponse							"Task not found"
+//	@Failure		409				{object}	ApiResponse							"Task result already completed by worker or no slots available"
+//	@Failure		500				{object}	ApiResponse							"Internal server error"
+//	@Router			/tasks/{task-id}/lease [post]
+func ClaimTaskLeaseController(c *gin.Context) {
+	worker := getAuthenticatedWorker(c)
+	if worker == nil {
+		return
+	}
+
+	taskId := c.Param("task-id")
+	ctx := c.Request.Context()
+	taskService := task.NewTaskService()
+
+	taskData, err := taskService.GetTaskById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, task.ErrTaskNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
+			return
+		}
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error getting Task")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse(err.Error()))
+		return
+	}
+
+	isCompletedTResult, err := taskService.ValidateCompletedTResultByWorker(ctx, taskId, worker.ID)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error validating completed task result")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse(err.Error()))
+		return
+	}
+
+	if isCompletedTResult {
+		c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse("Task Result is already completed by worker"))
+		return
+	}
+
+	lease, err := task.NewLeaseService().ClaimLease(ctx, taskData, worker)
+	if err != nil {
+		switch {
+		case errors.Is(err, task.ErrTaskNotLeasable):
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+		case errors.Is(err, task.ErrInsufficientReputation):
+			c.AbortWithStatusJSON(http.StatusForbidden, defaultErrorResponse(err.Error()))
+		case errors.Is(err, task.ErrNoLeaseSlotAvailable):
+			c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to claim task lease"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(lease))
+}
+
+// ReleaseTaskLeaseController godoc
+//
+//	@Summary		Release task lease
+//	@Description	Give up the worker's reserved slot on a task
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			Authorization	header		string						true	"Bearer token"
+//	@Param			task-id			path		string						true	"Task ID"
+//	@Success		200				{object}	ApiResponse{body=string}	"Task lease released successfully"
+//	@Failure		401				{object}	ApiResponse					"Unauthorized"
+//	@Failure		404				{object}	ApiResponse					"Worker not found"
+//	@Failure		500				{object}	ApiResponse					"Internal server error"
+//	@Router			/tasks/{task-id}/lease [delete]
+func ReleaseTaskLeaseController(c *gin.Context) {
+	worker := getAuthenticatedWorker(c)
+	if worker == nil {
+		return
+	}
+
+	taskId := c.Param("task-id")
+	if err := task.NewLeaseService().ReleaseLease(c.Request.Context(), taskId, worker.ID); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to release task lease"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse("Task lease released successfully"))
+}
+
+// SaveTaskDraftController godoc
+//
+//	@Summary		Save task draft
+//	@Description	Store the worker's partial result of an in progress task, replacing any previous draft. Drafts are removed once the result is submitted or the task closes.
+//	@Tags			Tasks
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string									true	"Bearer token"
+//	@Param			task-id			path		string									true	"Task ID"
+//	@Param			body			body		task.SaveTaskDraftRequest				true	"Request body containing the partial task result data"
+//	@Success		200				{object}	ApiResponse{body=task.TaskDraftResponse}	"Task draft saved successfully"
+//	@Failure		400				{object}	ApiResponse								"Invalid request body or draft is too large"
+//	@Failure		401				{object}	ApiResponse								"Unauthorized"
+//	@Failure		403				{object}	ApiResponse								"Worker reputation is below the task's minimum"
+//	@Failure		404				{object}	ApiResponse								"Task not found or not listed for the worker"
+//	@Failure		409				{object}	ApiResponse								"Task is not in progress or the worker already submitted a result"
+//	@Failure		500				{object}	ApiResponse								"Internal server error"
+//	@Router			/tasks/{task-id}/draft [put]
+func SaveTaskDraftController(c *gin.Context) {
+	worker := getAuthenticatedWorker(c)
+	if worker == nil {
+		return
+	}
+
+	var requestBody task.SaveTaskDraftRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("Invalid request body"))
+		return
+	}
+
+	taskId := c.Param("task-id")
+	draft, err := task.NewDraftService().SaveDraft(c.Request.Context(), taskId, worker, requestBody.ResultData)
+	if err != nil {
+		switch {
+		case errors.Is(err, task.ErrTaskNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
+		case errors.Is(err, task.ErrInsufficientReputation):
+			c.AbortWithStatusJSON(http.StatusForbidden, defaultErrorResponse(err.Error()))
+		case errors.Is(err, task.ErrDraftTooLarge):
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+		case errors.Is(err, task.ErrTaskNotDraftable), errors.Is(err, task.ErrTaskAlreadySubmitted):
+			c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+		default:
+			log.Error().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Error saving task draft")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to save task draft"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(draft))
+}
+
+// GetTaskDraftController godoc
+//
+//	@Summary		Load task draft
+//	@Description	Retrieve the worker's saved partial result of a task
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			Authorization	header		string									true	"Bearer token"
+//	@Param			task-id			path		string									true	"Task ID"
+//	@Success		200				{object}	ApiResponse{body=task.TaskDraftResponse}	"Successfully retrieved task draft"
+//	@Failure		401				{object}	ApiResponse								"Unauthorized"
+//	@Failure		403				{object}	ApiResponse								"Worker reputation is below the task's minimum"
+//	@Failure		404				{object}	ApiResponse								"Draft not found, or task not listed for the worker"
+//	@Failure		500				{object}	ApiResponse								"Internal server error"
+//	@Router			/tasks/{task-id}/draft [get]
+func GetTaskDraftController(c *gin.Context) {
+	worker := getAuthenticatedWorker(c)
+	if worker == nil {
+		return
+	}
+
+	taskId := c.Param("task-id")
+	draft, err := task.NewDraftService().GetDraft(c.Request.Context(), taskId, worker)
+	if err != nil {
+		switch {
+		case errors.Is(err, task.ErrDraftNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse(err.Error()))
+			return
+		case errors.Is(err, task.ErrTaskNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
+			return
+		case errors.Is(err, task.ErrInsufficientReputation):
+			c.AbortWithStatusJSON(http.StatusForbidden, defaultErrorResponse(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Error getting task draft")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get task draft"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(draft))
+}
+
+// DiscardTaskDraftController godoc
+//
+//	@Summary		Discard task draft
+//	@Description	Remove the worker's saved partial result of a task
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			Authorization	header		string		true	"Bearer token"
+//	@Param			task-id			path		string		true	"Task ID"
+//	@Success		200				{object}	ApiResponse	"Task draft discarded successfully"
+//	@Failure		401				{object}	ApiResponse	"Unauthorized"
+//	@Failure		500				{object}	ApiResponse	"Internal server error"
+//	@Router			/tasks/{task-id}/draft [delete]
+func DiscardTaskDraftController(c *gin.Context) {
+	worker := getAuthenticatedWorker(c)
+	if worker == nil {
+		return
+	}
+
+	taskId := c.Param("task-id")
+	if err := task.NewDraftService().DiscardDraft(c.Request.Context(), taskId, worker.ID); err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Error discarding task draft")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to discard task draft"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse("Task draft discarded successfully"))
+}
+
 // WorkerPartnerCreateController godoc
 //
 //	@Summary		Create worker-miner partnership
@@ -457,6 +935,223 @@ func GetWorkerPartnerListController(c *gin.Context) {
 	c.JSON(http.StatusOK, defaultSuccessResponse(listWorkerPartnersResponse))
 }
 
+// GetWorkerRewardsController godoc
+//
+//	@Summary		Get worker rewards
+//	@Description	Retrieve the finalised rewards and losses of the worker's settled task results, newest first
+//	@Tags			Worker
+//	@Produce		json
+//	@Param			Authorization	header		string											true	"Bearer token"
+//	@Param			limit			query		int												false	"Number of settled task results to return (default is 50, max 200)"
+//	@Success		200				{object}	ApiResponse{body=worker.WorkerRewardsResponse}	"Successfully retrieved worker rewards"
+//	@Failure		400				{object}	ApiResponse										"Invalid limit parameter"
+//	@Failure		401				{object}	ApiResponse										"Unauthorized"
+//	@Failure		404				{object}	ApiResponse										"Worker not found"
+//	@Failure		500				{object}	ApiResponse										"Internal server error"
+//	@Router			/worker/rewards [get]
+func GetWorkerRewardsController(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(worker.DefaultRewardsLimit)))
+	if err != nil || limit < 1 || limit > worker.MaxRewardsLimit {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(fmt.Sprintf("limit must be between 1 and %d", worker.MaxRewardsLimit)))
+		return
+	}
+
+	foundWorker := getAuthenticatedWorker(c)
+	if foundWorker == nil {
+		return
+	}
+
+	taskResults, err := orm.NewTaskResultORM().GetSettledTResultsByWorker(c.Request.Context(), foundWorker.ID, limit)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", foundWorker.ID).Msg("Failed to get settled task results")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get worker rewards"))
+		return
+	}
+
+	rewardsResponse := &worker.WorkerRewardsResponse{
+		Rewards: make([]worker.WorkerReward, 0, len(taskResults)),
+	}
+	for _, taskResult := range taskResults {
+		finalisedReward, _ := taskResult.FinalisedReward()
+		finalisedLoss, _ := taskResult.FinalisedLoss()
+		rewardsResponse.Rewards = append(rewardsResponse.Rewards, worker.WorkerReward{
+			TaskId:          taskResult.TaskID,
+			TaskResultId:    taskResult.ID,
+			Status:          string(taskResult.Status),
+			FinalisedReward: finalisedReward,
+			FinalisedLoss:   finalisedLoss,
+			SettledAt:       taskResult.UpdatedAt,
+		})
+		rewardsResponse.TotalReward += finalisedReward
+		rewardsResponse.TotalLoss += finalisedLoss
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(rewardsResponse))
+}
+
+// GetWorkerBalanceController godoc
+//
+//	@Summary		Get worker balance
+//	@Description	Retrieve the worker's balance, derived from their ledger entries
+//	@Tags			Worker
+//	@Produce		json
+//	@Param			Authorization	header		string											true	"Bearer token"
+//	@Success		200				{object}	ApiResponse{body=worker.WorkerBalanceResponse}	"Successfully retrieved worker balance"
+//	@Failure		401				{object}	ApiResponse										"Unauthorized"
+//	@Failure		404				{object}	ApiResponse										"Worker not found"
+//	@Failure		500				{object}	ApiResponse										"Internal server error"
+//	@Router			/worker/balance [get]
+func GetWorkerBalanceController(c *gin.Context) {
+	foundWorker := getAuthenticatedWorker(c)
+	if foundWorker == nil {
+		return
+	}
+
+	balance, err := orm.NewLedgerORM().GetWorkerBalance(c.Request.Context(), foundWorker.ID)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", foundWorker.ID).Msg("Failed to get worker balance")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get worker balance"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(worker.WorkerBalanceResponse{
+		Available:     balance.Available,
+		PendingPayout: balance.PendingPayout,
+		PaidOut:       balance.PaidOut,
+	}))
+}
+
+// GetWorkerLedgerController godoc
+//
+//	@Summary		Get worker ledger history
+//	@Description	Retrieve a page of the ledger entries on the worker's accounts, newest first
+//	@Tags			Worker
+//	@Produce		json
+//	@Param			Authorization	header		string											true	"Bearer token"
+//	@Param			page			query		int												false	"Page number (default is 1)"
+//	@Param			limit			query		int												false	"Number of entries per page (default is 20, max 100)"
+//	@Success		200				{object}	ApiResponse{body=worker.LedgerHistoryResponse}	"Successfully retrieved worker ledger history"
+//	@Failure		400				{object}	ApiResponse										"Invalid page or limit parameter"
+//	@Failure		401				{object}	ApiResponse										"Unauthorized"
+//	@Failure		404				{object}	ApiResponse										"Worker not found"
+//	@Failure		500				{object}	ApiResponse										"Internal server error"
+//	@Router			/worker/ledger [get]
+func GetWorkerLedgerController(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("Invalid page parameter"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(worker.DefaultLedgerPageSize)))
+	if err != nil || limit < 1 || limit > worker.MaxLedgerPageSize {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(fmt.Sprintf("limit must be between 1 and %d", worker.MaxLedgerPageSize)))
+		return
+	}
+
+	foundWorker := getAuthenticatedWorker(c)
+	if foundWorker == nil {
+		return
+	}
+
+	entries, totalEntries, err := orm.NewLedgerORM().GetWorkerEntries(c.Request.Context(), foundWorker.ID, page, limit)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", foundWorker.ID).Msg("Failed to get worker ledger entries")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get worker ledger history"))
+		return
+	}
+
+	historyResponse := &worker.LedgerHistoryResponse{
+		Entries: make([]worker.LedgerEntry, 0, len(entries)),
+		Pagination: task.Pagination{
+			Page:       page,
+			Limit:      limit,
+			TotalPages: (totalEntries + limit - 1) / limit,
+			TotalItems: totalEntries,
+		},
+	}
+	for _, entry := range entries {
+		taskId, _ := entry.TaskID()
+		taskResultId, _ := entry.TaskResultID()
+		historyResponse.Entries = append(historyResponse.Entries, worker.LedgerEntry{
+			Id:            entry.ID,
+			CreatedAt:     entry.CreatedAt,
+			TransactionId: entry.TransactionID,
+			Type:          string(entry.Type),
+			Account:       string(entry.Account),
+			Amount:        entry.Amount,
+			TaskId:        taskId,
+			TaskResultId:  taskResultId,
+		})
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(historyResponse))
+}
+
+// GetWorkerTimeOnTaskController godoc
+//
+//	@Summary		Get worker time on task
+//	@Description	Retrieves the time between the worker first opening and submitting tasks, per modality, and the number of their submissions flagged as too fast
+//	@Tags			Worker
+//	@Produce		json
+//	@Param			Authorization	header		string											true	"Bearer token"
+//	@Success		200				{object}	ApiResponse{body=task.TimeOnTaskMetricsResponse}	"Successfully retrieved worker time on task"
+//	@Failure		401				{object}	ApiResponse										"Unauthorized"
+//	@Failure		500				{object}	ApiResponse										"Internal server error"
+//	@Router			/worker/time-on-task [get]
+func GetWorkerTimeOnTaskController(c *gin.Context) {
+	foundWorker := getAuthenticatedWorker(c)
+	if foundWorker == nil {
+		return
+	}
+
+	metrics, err := task.NewTimeOnTaskService().GetTimeOnTaskMetrics(c.Request.Context(), foundWorker.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get worker time on task"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(metrics))
+}
+
+// GetPayoutProofController godoc
+//
+//	@Summary		Get worker payout proof
+//	@Description	Retrieve the worker's (wallet_address, chain_id, amount) leaf of a payout batch and its merkle proof
+//	@Tags			Worker
+//	@Produce		json
+//	@Param			Authorization	header		string											true	"Bearer token"
+//	@Param			batch-id		path		string											true	"Payout batch ID"
+//	@Success		200				{object}	ApiResponse{body=payout.PayoutProofResponse}	"Successfully retrieved payout proof"
+//	@Failure		401				{object}	ApiResponse										"Unauthorized"
+//	@Failure		404				{object}	ApiResponse										"Payout batch or worker leaf not found"
+//	@Failure		409				{object}	ApiResponse										"Payout batch is not ready"
+//	@Failure		500				{object}	ApiResponse										"Internal server error"
+//	@Router			/worker/payouts/{batch-id}/proof [get]
+func GetPayoutProofController(c *gin.Context) {
+	foundWorker := getAuthenticatedWorker(c)
+	if foundWorker == nil {
+		return
+	}
+
+	batchId := c.Param("batch-id")
+	proof, err := payout.NewPayoutService().GetWorkerProof(c.Request.Context(), batchId, foundWorker.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, payout.ErrBatchNotFound), errors.Is(err, payout.ErrLeafNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse(err.Error()))
+		case errors.Is(err, payout.ErrBatchNotReady):
+			c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+		default:
+			log.Error().Err(err).Str("batchId", batchId).Str("workerId", foundWorker.ID).Msg("Failed to get payout proof")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get payout proof"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(proof))
+}
+
 // GetTaskById godoc
 //
 //	@Summary		Retrieve task by ID
@@ -466,6 +1161,7 @@ func GetWorkerPartnerListController(c *gin.Context) {
 //	@Produce		json
 //	@Param			task-id	path		string								true	"Task ID"
 //	@Success		200		{object}	ApiResponse{body=task.TaskResponse}	"Successfully retrieved task response"
+//	@Failure		403		{object}	ApiResponse{error=string}			"Worker reputation is below the task's minimum"
 //	@Failure		404		{object}	ApiResponse{error=string}			"Task not found"
 //	@Failure		500		{object}	ApiResponse{error=string}			"Internal server error"
 //	@Router			/tasks/{task-id} [get]
@@ -473,20 +1169,45 @@ func GetTaskByIdController(c *gin.Context) {
 	taskID := c.Param("task-id")
 	taskService := task.NewTaskService()
 
-	task, err := taskService.GetTaskResponseById(c.Request.Context(), taskID)
+	// tasks with a minimum reputation are only shown to signed in workers that meet it
+	var worker *db.DojoWorkerModel
+	if jwtClaims, ok := c.Get("userInfo"); ok {
+		if userInfo, ok := jwtClaims.(*jwt.RegisteredClaims); ok {
+			var err error
+			worker, err = orm.NewDojoWorkerORM().GetDojoWorkerByWalletAddress(userInfo.Subject)
+			if err != nil {
+				log.Error().Err(err).Str("walletAddress", userInfo.Subject).Msg("Failed to get worker by wallet address")
+				c.JSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get worker"))
+				c.Abort()
+				return
+			}
+		}
+	}
+
+	taskResponse, err := taskService.GetTaskResponseById(c.Request.Context(), taskID, worker)
 	if err != nil {
+		if errors.Is(err, task.ErrInsufficientReputation) {
+			c.JSON(http.StatusForbidden, defaultErrorResponse(err.Error()))
+			c.Abort()
+			return
+		}
 		c.JSON(http.StatusInternalServerError, defaultErrorResponse("Internal server error"))
 		c.Abort()
 		return
 	}
 
-	if task == nil {
+	if taskResponse == nil {
 		c.JSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
 		return
 	}
 
+	// Signed in workers start their time on task when they first open it
+	if worker != nil && taskResponse.Status == db.TaskStatusInProgress {
+		handleTaskOpen(taskID, worker.ID)
+	}
+
 	// Successful response
-	c.JSON(http.StatusOK, defaultSuccessResponse(task))
+	c.JSON(http.StatusOK, defaultSuccessResponse(taskResponse))
 }
 
 // GetTasksByPageController godoc
@@ -498,10 +1219,14 @@ func GetTaskByIdController(c *gin.Context) {
 //	@Produce		json
 //	@Param			Authorization	header		string									true	"Bearer token"
 //	@Param			task			query		string									true	"Comma-separated list of task types (e.g., CODE_GENERATION,IMAGE,THREE_D). Use 'All' to include all types."
-//	@Param			page			query		int										false	"Page number (default is 1)"
+//	@Param			page			query		int										false	"Page number (default is 1), ignored when cursor is set"
 //	@Param			limit			query		int										false	"Number of tasks per page (default is 10)"
-//	@Param			sort			query		string									false	"Sort field (default is createdAt)"
+//	@Param			sort			query		string									false	"Sort field, one of createdAt, numResult or highestYield (default is createdAt)"
 //	@Param			order			query		string									false	"Order field (default is desc order) e.g., asc or desc"
+//	@Param			yieldMin		query		number									false	"Minimum yield (total reward per result), inclusive"
+//	@Param			yieldMax		query		number									false	"Maximum yield (total reward per result), inclusive"
+//	@Param			cursor			query		string									false	"nextCursor of the previous page, used with the same sort and order"
+//	@Param			includeTotal	query		bool									false	"Whether to count the total tasks of cursor based requests (default is false)"
 //	@Success		200				{object}	ApiResponse{body=task.TaskPagination}	"Successfully retrieved task pagination response"
 //	@Failure		400				{object}	ApiResponse								"Invalid request parameters"
 //	@Failure		401				{object}	ApiResponse								"Unauthorized"
@@ -564,6 +1289,17 @@ func GetTasksByPageController(c *gin.Context) {
 		return
 	}
 
+	if page < 1 || limit < 1 {
+		c.JSON(http.StatusBadRequest, defaultErrorResponse("page and limit must be positive"))
+		return
+	}
+
+	includeTotal, err := strconv.ParseBool(c.DefaultQuery("includeTotal", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, defaultErrorResponse("Invalid includeTotal parameter"))
+		return
+	}
+
 	if orderStr != "asc" && orderStr != "desc" {
 		log.Error().Msgf("Invalid order parameter: %s", orderStr)
 		c.JSON(http.StatusBadRequest, defaultErrorResponse("Invalid order parameter"))
@@ -575,12 +1311,27 @@ func GetTasksByPageController(c *gin.Context) {
 		order = db.SortOrderAsc
 	}
 
+	yieldMin, err := parseOptionalNonNegativeFloat(c, "yieldMin")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+		return
+	}
+	yieldMax, err := parseOptionalNonNegativeFloat(c, "yieldMax")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+		return
+	}
+
 	paginationParams := task.PaginationParams{
-		Page:       page,
-		Limit:      limit,
-		Sort:       sort,
-		Modalities: taskModalities,
-		Order:      order,
+		Page:         page,
+		Limit:        limit,
+		Sort:         sort,
+		Modalities:   taskModalities,
+		Order:        order,
+		YieldMin:     yieldMin,
+		YieldMax:     yieldMax,
+		Cursor:       c.Query("cursor"),
+		IncludeTotal: includeTotal,
 	}
 
 	// fetching tasks by pagination
@@ -594,6 +1345,9 @@ func GetTasksByPageController(c *gin.Context) {
 			if _, ok := err.(*task.ErrInvalidTaskModality); ok {
 				isBadRequest = true
 			}
+			if errors.Is(err, task.ErrInvalidSortField) || errors.Is(err, task.ErrInvalidYieldRange) || errors.Is(err, task.ErrInvalidCursor) {
+				isBadRequest = true
+			}
 		}
 		log.Error().Interface("errors", errorDetails).Msg("Error getting tasks by pagination")
 		if isBadRequest {
@@ -614,6 +1368,58 @@ func GetTasksByPageController(c *gin.Context) {
 	c.JSON(http.StatusOK, defaultSuccessResponse(taskPagination))
 }
 
+// StreamTasksController godoc
+//
+//	@Summary		Stream task events
+//	@Description	Stream task-created, task-filled and task-expired events of the miners the worker is partnered with as server-sent events. Each event is named after its type and carries the task as JSON, a comment is sent periodically to keep the connection alive.
+//	@Tags			Tasks
+//	@Produce		text/event-stream
+//	@Param			Authorization	header		string				true	"Bearer token"
+//	@Success		200				{object}	task.TaskFeedEvent	"Stream of task events"
+//	@Failure		401				{object}	ApiResponse			"Unauthorized"
+//	@Failure		500				{object}	ApiResponse			"Internal server error"
+//	@Router			/tasks/stream [get]
+func StreamTasksController(c *gin.Context) {
+	worker := getAuthenticatedWorker(c)
+	if worker == nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, err := task.NewTaskFeedService().Subscribe(ctx, worker)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to subscribe to task events"))
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// stops reverse proxies from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAliveTicker := time.NewTicker(15 * time.Second)
+	defer keepAliveTicker.Stop()
+
+	log.Info().Str("workerId", worker.ID).Msg("Worker subscribed to task events")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		case <-keepAliveTicker.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+	log.Info().Str("workerId", worker.ID).Msg("Worker unsubscribed from task events")
+}
+
 func GetTaskResultsController(c *gin.Context) {
 	taskId := c.Param("task-id")
 	if taskId == "" {
@@ -649,6 +1455,499 @@ func GetTaskResultsController(c *gin.Context) {
 	c.JSON(http.StatusOK, defaultSuccessResponse(task.TaskResultResponse{TaskResults: formattedTaskResults}))
 }
 
+// GetTaskAggregateController godoc
+//
+//	@Summary		Retrieve aggregated task results
+//	@Description	Get per model and criteria statistics over the completed results of a task, invalid results are excluded
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			task-id	path		string											true	"Task ID"
+//	@Success		200		{object}	ApiResponse{body=task.TaskAggregateResponse}	"Successfully aggregated task results"
+//	@Failure		400		{object}	ApiResponse										"Task id is required"
+//	@Failure		404		{object}	ApiResponse										"Task not found"
+//	@Failure		500		{object}	ApiResponse										"Internal server error"
+//	@Router			/tasks/{task-id}/aggregate [get]
+func GetTaskAggregateController(c *gin.Context) {
+	taskId := c.Param("task-id")
+	if taskId == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("task id is required"))
+		return
+	}
+
+	aggregationService := task.NewAggregationService()
+	aggregate, err := aggregationService.GetTaskAggregate(c.Request.Context(), taskId)
+	if err != nil {
+		if errors.Is(err, task.ErrTaskNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
+			return
+		}
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error aggregating task results")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to aggregate task results"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(aggregate))
+}
+
+// GetTaskAgreementController godoc
+//
+//	@Summary		Retrieve inter-annotator agreement of a task
+//	@Description	Get Krippendorff's alpha over the score and multi-select criteria, and Kendall's W over the ranking criteria of a task's completed results
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			task-id	path		string									true	"Task ID"
+//	@Success		200		{object}	ApiResponse{body=task.TaskAgreement}	"Successfully computed task agreement"
+//	@Failure		400		{object}	ApiResponse								"Task id is required"
+//	@Failure		404		{object}	ApiResponse								"Task not found"
+//	@Failure		500		{object}	ApiResponse								"Internal server error"
+//	@Router			/tasks/{task-id}/agreement [get]
+func GetTaskAgreementController(c *gin.Context) {
+	taskId := c.Param("task-id")
+	if taskId == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("task id is required"))
+		return
+	}
+
+	agreementService := task.NewAgreementService()
+	agreement, err := agreementService.GetTaskAgreement(c.Request.Context(), taskId)
+	if err != nil {
+		if errors.Is(err, task.ErrTaskNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
+			return
+		}
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error computing task agreement")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to compute task agreement"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(agreement))
+}
+
+// GetTaskCommitmentController godoc
+//
+//	@Summary		Retrieve the commitment of a task's results
+//	@Description	Get the hash over a completed task's results, the leaf hash of every result and, once the task is in a commitment batch, the merkle root and the inclusion proof of the task
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			task-id	path		string											true	"Task ID"
+//	@Success		200		{object}	ApiResponse{body=task.TaskCommitmentResponse}	"Successfully retrieved task commitment"
+//	@Failure		400		{object}	ApiResponse										"Task id is required"
+//	@Failure		404		{object}	ApiResponse										"Task not found"
+//	@Failure		409		{object}	ApiResponse										"Task is not completed or its results are not committed yet"
+//	@Failure		500		{object}	ApiResponse										"Internal server error"
+//	@Router			/tasks/{task-id}/commitment [get]
+func GetTaskCommitmentController(c *gin.Context) {
+	taskId := c.Param("task-id")
+	if taskId == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("task id is required"))
+		return
+	}
+
+	commitment, err := task.NewCommitmentService().GetTaskCommitment(c.Request.Context(), taskId)
+	if err != nil {
+		switch {
+		case errors.Is(err, task.ErrTaskNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Task not found"))
+		case errors.Is(err, task.ErrTaskNotCommitted), errors.Is(err, task.ErrTaskCommitmentPending):
+			c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+		default:
+			log.Error().Err(err).Str("taskId", taskId).Msg("Error getting task commitment")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to get task commitment"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(commitment))
+}
+
+// GetCommitmentBatchController godoc
+//
+//	@Summary		Retrieve a commitment batch
+//	@Description	Get the merkle root over the results hashes of the tasks in a commitment batch
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			batch-id	path		string											true	"Commitment batch ID"
+//	@Success		200			{object}	ApiResponse{body=task.CommitmentBatchResponse}	"Successfully retrieved commitment batch"
+//	@Failure		404			{object}	ApiResponse										"Commitment batch not found"
+//	@Failure		500			{object}	ApiResponse										"Internal server error"
+//	@Router			/commitments/{batch-id} [get]
+func GetCommitmentBatchController(c *gin.Context) {
+	batchId := c.Param("batch-id")
+	batch, err := task.NewCommitmentService().GetCommitmentBatch(c.Request.Context(), batchId)
+	if err != nil {
+		if errors.Is(err, task.ErrCommitmentBatchNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("batchId", batchId).Msg("Error getting commitment batch")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to get commitment batch"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(batch))
+}
+
+// GetMinerAgreementController godoc
+//
+//	@Summary		Retrieve rolling inter-annotator agreement of a miner
+//	@Description	Get the mean agreement over the miner's most recently completed tasks
+//	@Tags			Miner
+//	@Produce		json
+//	@Param			x-api-key	header		string									true	"API Key for Miner Authentication"
+//	@Param			window		query		int										false	"Number of recent completed tasks to include (default is 50, max 200)"
+//	@Success		200			{object}	ApiResponse{body=task.MinerAgreement}	"Successfully computed miner agreement"
+//	@Failure		400			{object}	ApiResponse								"Invalid window parameter"
+//	@Failure		401			{object}	ApiResponse								"Unauthorized"
+//	@Failure		500			{object}	ApiResponse								"Internal server error"
+//	@Router			/miner/agreement [get]
+func GetMinerAgreementController(c *gin.Context) {
+	minerUserInterface, exists := c.Get("minerUser")
+	minerUser, _ := minerUserInterface.(*db.MinerUserModel)
+	if !exists || minerUser == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, defaultErrorResponse("Unauthorized"))
+		return
+	}
+
+	window, err := strconv.Atoi(c.DefaultQuery("window", strconv.Itoa(task.DefaultAgreementWindow)))
+	if err != nil || window < 1 || window > task.MaxAgreementWindow {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(fmt.Sprintf("window must be between 1 and %d", task.MaxAgreementWindow)))
+		return
+	}
+
+	agreementService := task.NewAgreementService()
+	agreement, err := agreementService.GetMinerAgreement(c.Request.Context(), minerUser.ID, window)
+	if err != nil {
+		log.Error().Err(err).Str("minerUserId", minerUser.ID).Msg("Error computing miner agreement")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to compute miner agreement"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(agreement))
+}
+
+// GetMinerTasksController godoc
+//
+//	@Summary		List the miner's tasks
+//	@Description	Lists the miner's tasks newest first with cursor pagination, along with the number of tasks of each status.
+//	@Description	Creation and expiry windows are inclusive of the start and exclusive of the end.
+//	@Tags			Miner
+//	@Produce		json
+//	@Param			x-api-key	header		string										true	"API Key for Miner Authentication"
+//	@Param			status		query		string										false	"Comma separated statuses, e.g. IN_PROGRESS,COMPLETED"
+//	@Param			modality	query		string										false	"Comma separated task modalities, e.g. CODE_GENERATION,IMAGE"
+//	@Param			createdFrom	query		integer										false	"Start of the creation window as Unix timestamp (seconds since epoch)"
+//	@Param			createdTo	query		integer										false	"End of the creation window as Unix timestamp (seconds since epoch)"
+//	@Param			expireFrom	query		integer										false	"Start of the expiry window as Unix timestamp (seconds since epoch)"
+//	@Param			expireTo	query		integer										false	"End of the expiry window as Unix timestamp (seconds since epoch)"
+//	@Param			cursor		query		string										false	"nextCursor of the previous page"
+//	@Param			limit		query		int											false	"Number of tasks per page (default is 20, max 100)"
+//	@Success		200			{object}	ApiResponse{body=task.MinerTaskListResponse}	"Successfully listed miner tasks"
+//	@Failure		400			{object}	ApiResponse									"Invalid parameters"
+//	@Failure		401			{object}	ApiResponse									"Unauthorized"
+//	@Failure		500			{object}	ApiResponse									"Internal server error"
+//	@Router			/miner/tasks [get]
+func GetMinerTasksController(c *gin.Context) {
+	minerUserInterface, exists := c.Get("minerUser")
+	minerUser, _ := minerUserInterface.(*db.MinerUserModel)
+	if !exists || minerUser == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, defaultErrorResponse("Unauthorized"))
+		return
+	}
+
+	var filter orm.MinerTaskFilter
+	if statusParam := c.Query("status"); statusParam != "" {
+		for _, status := range strings.Split(statusParam, ",") {
+			if !slices.Contains(task.ValidTaskStatuses, db.TaskStatus(status)) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(fmt.Sprintf("invalid status: '%s', supported statuses are %v", status, task.ValidTaskStatuses)))
+				return
+			}
+			filter.Statuses = append(filter.Statuses, db.TaskStatus(status))
+		}
+	}
+	if modalityParam := c.Query("modality"); modalityParam != "" {
+		for _, modality := range strings.Split(modalityParam, ",") {
+			if isValid, err := task.IsValidTaskModality(modality); !isValid {
+				c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+				return
+			}
+			filter.Modalities = append(filter.Modalities, db.TaskModality(modality))
+		}
+	}
+
+	var err error
+	windows := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"createdFrom", &filter.CreatedFrom},
+		{"createdTo", &filter.CreatedTo},
+		{"expireFrom", &filter.ExpireFrom},
+		{"expireTo", &filter.ExpireTo},
+	}
+	for _, window := range windows {
+		if *window.target, err = parseOptionalUnixTime(c, window.name); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(task.DefaultMinerTaskPageSize)))
+	if err != nil || limit < 1 || limit > task.MaxMinerTaskPageSize {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(fmt.Sprintf("limit must be between 1 and %d", task.MaxMinerTaskPageSize)))
+		return
+	}
+
+	taskService := task.NewTaskService()
+	minerTasks, err := taskService.GetMinerTasks(c.Request.Context(), minerUser.ID, filter, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, task.ErrInvalidCursor) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("minerUserId", minerUser.ID).Msg("Error listing miner tasks")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to list miner tasks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(minerTasks))
+}
+
+// ExportMinerResultsController godoc
+//
+//	@Summary		Export the results of a miner's tasks
+//	@Description	Streams every result of the miner's tasks submitted between dateFrom and dateTo, ordered by submission time.
+//	@Description	JSONL has one result per line, CSV and Parquet have one row per result, model and criterion.
+//	@Description	preference-pairs writes chosen/rejected pairs of the model responses of the miner's completed tasks created between dateFrom and dateTo, as JSONL.
+//	@Tags			Miner
+//	@Produce		application/x-ndjson
+//	@Produce		text/csv
+//	@Produce		application/vnd.apache.parquet
+//	@Param			x-api-key		header		string		true	"API Key for Miner Authentication"
+//	@Param			dateFrom		query		integer		true	"Start timestamp as Unix timestamp (seconds since epoch), inclusive"
+//	@Param			dateTo			query		integer		false	"End timestamp as Unix timestamp (seconds since epoch), exclusive. Defaults to now"
+//	@Param			format			query		string		false	"Export format: jsonl (default), csv, parquet or preference-pairs"
+//	@Param			ties			query		string		false	"preference-pairs only, skip (default) or keep pairs whose responses scored the same"
+//	@Param			tieMargin		query		number		false	"preference-pairs only, responses whose mean scores on [0, 1] differ by at most this are tied (default 0)"
+//	@Param			minAgreement	query		number		false	"preference-pairs only, minimum fraction of raters agreeing with a pair (default 0)"
+//	@Param			minRaters		query		integer		false	"preference-pairs only, minimum number of workers that scored both responses (default 1)"
+//	@Success		200				{file}		file		"Exported results"
+//	@Header			200				{integer}	X-Export-Skipped-Results	"Trailer, csv and parquet only, number of results left out for invalid result data or no criteria"
+//	@Failure		400				{object}	ApiResponse	"Invalid parameters"
+//	@Failure		401				{object}	ApiResponse	"Unauthorized"
+//	@Router			/miner/results/export [get]
+func ExportMinerResultsController(c *gin.Context) {
+	minerUserInterface, exists := c.Get("minerUser")
+	minerUser, _ := minerUserInterface.(*db.MinerUserModel)
+	if !exists || minerUser == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, defaultErrorResponse("Unauthorized"))
+		return
+	}
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+		return
+	}
+
+	dateFromUnix, err := strconv.ParseInt(c.Query("dateFrom"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("Invalid dateFrom format. Use Unix timestamp (seconds since epoch)"))
+		return
+	}
+	dateToUnix := time.Now().Unix()
+	if dateToStr := c.Query("dateTo"); dateToStr != "" {
+		dateToUnix, err = strconv.ParseInt(dateToStr, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("Invalid dateTo format. Use Unix timestamp (seconds since epoch)"))
+			return
+		}
+	}
+	if dateFromUnix >= dateToUnix {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("dateFrom must be before dateTo"))
+		return
+	}
+
+	var pairOptions task.PreferencePairOptions
+	if format == export.FormatPreferencePairs {
+		pairOptions, err = task.ParsePreferencePairOptions(c.Query("ties"), c.Query("tieMargin"), c.Query("minAgreement"), c.Query("minRaters"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("results_%d_%d.%s", dateFromUnix, dateToUnix, format.FileExtension())
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if format == export.FormatCSV || format == export.FormatParquet {
+		// the number of skipped results is only known once the export is streamed, so it is sent as a trailer
+		c.Header("Trailer", "X-Export-Skipped-Results")
+	}
+	c.Status(http.StatusOK)
+
+	// the status has been sent once streaming starts, so later errors leave the export truncated and are only logged
+	exportService := export.NewExportService()
+	from, to := time.Unix(dateFromUnix, 0), time.Unix(dateToUnix, 0)
+	var stats export.ExportStats
+	if format == export.FormatPreferencePairs {
+		stats.NumExported, err = exportService.ExportMinerPreferencePairs(c.Request.Context(), c.Writer, minerUser.ID, from, to, pairOptions)
+	} else {
+		stats, err = exportService.ExportMinerResults(c.Request.Context(), c.Writer, minerUser.ID, from, to, format)
+		if format != export.FormatJSONL {
+			c.Writer.Header().Set("X-Export-Skipped-Results", strconv.Itoa(stats.NumSkipped))
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("minerUserId", minerUser.ID).Int("numExported", stats.NumExported).Int("numSkipped", stats.NumSkipped).Msg("Error exporting miner results")
+		return
+	}
+	log.Info().Str("minerUserId", minerUser.ID).Str("format", string(format)).Int("numExported", stats.NumExported).Int("numSkipped", stats.NumSkipped).Msg("Miner results exported")
+}
+
+// CreateWebhookController godoc
+//
+//	@Summary		Register a webhook
+//	@Description	Register a URL with the API key to receive signed POSTs on task events. Each delivery carries an X-Dojo-Signature header,
+//	@Description	"sha256=" followed by the hex HMAC-SHA256 of "<X-Dojo-Timestamp>.<body>" keyed with the secret, which is only returned here.
+//	@Description	Failed deliveries are retried with exponential backoff before being dead lettered.
+//	@Tags			Miner
+//	@Accept			json
+//	@Produce		json
+//	@Param			x-api-key	header		string									true	"API Key for Miner Authentication"
+//	@Param			body		body		webhook.CreateWebhookRequest			true	"URL and events, any of task.completed, task.expired and task.result_submitted"
+//	@Success		201			{object}	ApiResponse{body=webhook.WebhookResponse}	"Webhook registered"
+//	@Failure		400			{object}	ApiResponse								"Invalid URL or events"
+//	@Failure		401			{object}	ApiResponse								"Unauthorized"
+//	@Failure		409			{object}	ApiResponse								"Webhook already registered, or too many webhooks"
+//	@Failure		500			{object}	ApiResponse								"Internal server error"
+//	@Router			/miner/webhooks [post]
+func CreateWebhookController(c *gin.Context) {
+	apiKey := getAuthenticatedMinerApiKey(c)
+	if apiKey == nil {
+		return
+	}
+
+	var requestBody webhook.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("Invalid request body"))
+		return
+	}
+
+	webhookService := webhook.GetWebhookService()
+	response, err := webhookService.CreateWebhook(c.Request.Context(), apiKey.ID, apiKey.MinerUserID, requestBody)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrInvalidWebhookURL), errors.Is(err, webhook.ErrInvalidEvents):
+			c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse(err.Error()))
+		case errors.Is(err, webhook.ErrWebhookExists), errors.Is(err, webhook.ErrTooManyWebhooks):
+			c.AbortWithStatusJSON(http.StatusConflict, defaultErrorResponse(err.Error()))
+		default:
+			log.Error().Err(err).Str("minerUserId", apiKey.MinerUserID).Msg("Error creating webhook")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to create webhook"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, defaultSuccessResponse(response))
+}
+
+// ListWebhooksController godoc
+//
+//	@Summary		List webhooks
+//	@Description	List the webhooks registered with the API key
+//	@Tags			Miner
+//	@Produce		json
+//	@Param			x-api-key	header		string										true	"API Key for Miner Authentication"
+//	@Success		200			{object}	ApiResponse{body=[]webhook.WebhookResponse}	"Webhooks retrieved successfully"
+//	@Failure		401			{object}	ApiResponse									"Unauthorized"
+//	@Failure		500			{object}	ApiResponse									"Internal server error"
+//	@Router			/miner/webhooks [get]
+func ListWebhooksController(c *gin.Context) {
+	apiKey := getAuthenticatedMinerApiKey(c)
+	if apiKey == nil {
+		return
+	}
+
+	webhookService := webhook.GetWebhookService()
+	webhooks, err := webhookService.ListWebhooks(c.Request.Context(), apiKey.ID)
+	if err != nil {
+		log.Error().Err(err).Str("minerUserId", apiKey.MinerUserID).Msg("Error listing webhooks")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to list webhooks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(webhooks))
+}
+
+// DeleteWebhookController godoc
+//
+//	@Summary		Delete a webhook
+//	@Description	Stop deliveries to a webhook registered with the API key, queued deliveries are dropped
+//	@Tags			Miner
+//	@Produce		json
+//	@Param			x-api-key	header		string		true	"API Key for Miner Authentication"
+//	@Param			webhook-id	path		string		true	"Webhook ID"
+//	@Success		200			{object}	ApiResponse	"Webhook deleted"
+//	@Failure		401			{object}	ApiResponse	"Unauthorized"
+//	@Failure		404			{object}	ApiResponse	"Webhook not found"
+//	@Failure		500			{object}	ApiResponse	"Internal server error"
+//	@Router			/miner/webhooks/{webhook-id} [delete]
+func DeleteWebhookController(c *gin.Context) {
+	apiKey := getAuthenticatedMinerApiKey(c)
+	if apiKey == nil {
+		return
+	}
+
+	webhookId := c.Param("webhook-id")
+	webhookService := webhook.GetWebhookService()
+	if err := webhookService.DeleteWebhook(c.Request.Context(), webhookId, apiKey.ID); err != nil {
+		if errors.Is(err, webhook.ErrWebhookNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("webhookId", webhookId).Msg("Error deleting webhook")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to delete webhook"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse("Webhook deleted"))
+}
+
+// GetWebhookDeliveriesController godoc
+//
+//	@Summary		Get the delivery log of a webhook
+//	@Description	Get the most recent delivery attempts of a webhook registered with the API key, and the deliveries that failed every attempt
+//	@Tags			Miner
+//	@Produce		json
+//	@Param			x-api-key	header		string											true	"API Key for Miner Authentication"
+//	@Param			webhook-id	path		string											true	"Webhook ID"
+//	@Success		200			{object}	ApiResponse{body=webhook.DeliveryLogResponse}	"Delivery log retrieved successfully"
+//	@Failure		401			{object}	ApiResponse										"Unauthorized"
+//	@Failure		404			{object}	ApiResponse										"Webhook not found"
+//	@Failure		500			{object}	ApiResponse										"Internal server error"
+//	@Router			/miner/webhooks/{webhook-id}/deliveries [get]
+func GetWebhookDeliveriesController(c *gin.Context) {
+	apiKey := getAuthenticatedMinerApiKey(c)
+	if apiKey == nil {
+		return
+	}
+
+	webhookId := c.Param("webhook-id")
+	webhookService := webhook.GetWebhookService()
+	deliveryLog, err := webhookService.GetDeliveryLog(c.Request.Context(), webhookId, apiKey.ID)
+	if err != nil {
+		if errors.Is(err, webhook.ErrWebhookNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("webhookId", webhookId).Msg("Error getting webhook delivery log")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("failed to get webhook delivery log"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(deliveryLog))
+}
+
 // UpdateWorkerPartnerController godoc
 //
 //	@Summary		Update worker partner details
@@ -905,6 +2204,25 @@ func GetAvgTaskCompletionTimeController(c *gin.Context) {
 	c.JSON(http.StatusOK, defaultSuccessResponse(metric.AvgTaskCompletionTimeResponse{AvgTaskCompletionTime: avgCompletionTime.AverageTaskCompletionTime}))
 }
 
+// GetTimeOnTaskMetricsController godoc
+//
+//	@Summary		Get time on task per modality
+//	@Description	Retrieves the time between workers first opening and submitting tasks, per modality, and the number of submissions flagged as too fast
+//	@Tags			Metrics
+//	@Produce		json
+//	@Success		200	{object}	ApiResponse{body=task.TimeOnTaskMetricsResponse}	"Time on task retrieved successfully"
+//	@Failure		500	{object}	ApiResponse											"Failed to get time on task"
+//	@Router			/metrics/time-on-task [get]
+func GetTimeOnTaskMetricsController(c *gin.Context) {
+	metrics, err := task.NewTimeOnTaskService().GetTimeOnTaskMetrics(c.Request.Context(), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get time on task"))
+		return
+	}
+
+	c.JSON(http.StatusOK, defaultSuccessResponse(metrics))
+}
+
 // GenerateCookieAuth godoc
 //
 //	@Summary		Generates a session given valid proof of ownership
@@ -1387,7 +2705,7 @@ func GetNextInProgressTaskController(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, defaultErrorResponse("task id is required"))
 		return
 	}
-	taskData, err := orm.NewTaskORM().GetNextInProgressTask(c, taskId, worker.ID)
+	taskData, err := task.NewTaskService().GetNextInProgressTask(c, taskId, worker.ID)
 	if err != nil {
 		if errors.Is(err, db.ErrNotFound) {
 			log.Info().Msg("No in progress tasks found")
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -31,35 +32,54 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+var (
+	errNoToken      = errors.New("unauthorized")
+	errInvalidToken = errors.New("invalid token")
+	errTokenExpired = errors.New("token expired")
+)
+
+// parseWorkerToken validates the bearer token of the request and returns its claims
+func parseWorkerToken(c *gin.Context) (*jwt.RegisteredClaims, error) {
+	token := c.GetHeader("Authorization")
+	if !strings.HasPrefix(token, "Bearer ") {
+		return nil, errNoToken
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token[7:], claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %w", errInvalidToken, err)
+	}
+	if !parsedToken.Valid {
+		return nil, errInvalidToken
+	}
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Before(time.Now()) {
+		return nil, errTokenExpired
+	}
+	return claims, nil
+}
+
 // AuthMiddleware checks if the request is authenticated
 func WorkerAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Info().Msg("Authenticating token")
 
-		jwtSecret := os.Getenv("JWT_SECRET")
-		token := c.GetHeader("Authorization")
-		if token == "" {
-			log.Error().Msg("No Authorization token provided")
-			c.JSON(http.StatusUnauthorized, defaultErrorResponse("unauthorized"))
-			c.Abort()
-			return
-		}
-		tokenString := token[7:]
-		claims := &jwt.RegisteredClaims{}
-		parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !parsedToken.Valid {
-			log.Error().Err(err).Msg("Invalid token")
-			c.JSON(http.StatusUnauthorized, defaultErrorResponse("invalid token"))
-			c.Abort()
-			return
-		}
-
-		if claims.ExpiresAt.Unix() < time.Now().Unix() {
-			log.Error().Msg("Token expired")
-			c.JSON(http.StatusUnauthorized, defaultErrorResponse("token expired"))
+		claims, err := parseWorkerToken(c)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to authenticate token")
+			switch {
+			case errors.Is(err, errTokenExpired):
+				c.JSON(http.StatusUnauthorized, defaultErrorResponse(errTokenExpired.Error()))
+			case errors.Is(err, errInvalidToken):
+				c.JSON(http.StatusUnauthorized, defaultErrorResponse(errInvalidToken.Error()))
+			default:
+				c.JSON(http.StatusUnauthorized, defaultErrorResponse(errNoToken.Error()))
+			}
 			c.Abort()
 			return
 		}
@@ -71,6 +91,17 @@ func WorkerAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OptionalWorkerAuthMiddleware sets userInfo when a valid worker token is provided, and lets the request
+// through without it otherwise, for public routes that behave differently for signed in workers
+func OptionalWorkerAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, err := parseWorkerToken(c); err == nil {
+			c.Set("userInfo", claims)
+		}
+		c.Next()
+	}
+}
+
 func WorkerLoginMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var requestBody worker.WorkerLoginRequest
@@ -271,6 +302,7 @@ func MinerAuthMiddleware() gin.HandlerFunc {
 		}
 
 		c.Set("minerUser", foundApiKey.MinerUser())
+		c.Set("minerApiKey", foundApiKey)
 		log.Info().Msg("Miner user authenticated successfully")
 
 		c.Next()
@@ -18,23 +18,44 @@ func LoginRoutes(router *gin.Engine) {
 			worker.POST("/partner", WorkerAuthMiddleware(), WorkerPartnerCreateController)
 			worker.PUT("/partner/disable", WorkerAuthMiddleware(), DisableMinerByWorkerController)
 			worker.GET("/partner/list", WorkerAuthMiddleware(), GetWorkerPartnerListController)
+			worker.GET("/rewards", WorkerAuthMiddleware(), GetWorkerRewardsController)
+			worker.GET("/balance", WorkerAuthMiddleware(), GetWorkerBalanceController)
+			worker.GET("/ledger", WorkerAuthMiddleware(), GetWorkerLedgerController)
+			worker.GET("/payouts/:batch-id/proof", WorkerAuthMiddleware(), GetPayoutProofController)
+			worker.GET("/time-on-task", WorkerAuthMiddleware(), GetWorkerTimeOnTaskController)
 		}
 		apiV1.GET("/auth/:address", GeneralRateLimiter(), GenerateNonceController)
 		apiV1.PUT("/partner/edit", GeneralRateLimiter(), WorkerAuthMiddleware(), UpdateWorkerPartnerController)
 		tasks := apiV1.Group("/tasks")
 		{
 			tasks.PUT("/submit-result/:task-id", WorkerAuthMiddleware(), SubmitTaskResultController)
+			tasks.PUT("/:task-id/result", WorkerAuthMiddleware(), ReviseTaskResultController)
 			// TODO: re-enable InMetagraphOnly(), and rate limiter in future
 			tasks.POST("/create-tasks", MinerAuthMiddleware(), CreateTasksController)
+			tasks.DELETE("/:task-id", MinerAuthMiddleware(), CancelTaskController)
+			tasks.POST("/:task-id/lease", WorkerAuthMiddleware(), ClaimTaskLeaseController)
+			tasks.DELETE("/:task-id/lease", WorkerAuthMiddleware(), ReleaseTaskLeaseController)
+			tasks.PUT("/:task-id/draft", WorkerAuthMiddleware(), SaveTaskDraftController)
+			tasks.GET("/:task-id/draft", WorkerAuthMiddleware(), GetTaskDraftController)
+			tasks.DELETE("/:task-id/draft", WorkerAuthMiddleware(), DiscardTaskDraftController)
 			tasks.GET("/task-result/:task-id", ReadTaskRateLimiter(), GetTaskResultsController)
-			tasks.GET("/:task-id", ReadTaskRateLimiter(), GetTaskByIdController)
+			tasks.GET("/:task-id/aggregate", ReadTaskRateLimiter(), GetTaskAggregateController)
+			tasks.GET("/:task-id/agreement", ReadTaskRateLimiter(), GetTaskAgreementController)
+			tasks.GET("/:task-id/commitment", ReadTaskRateLimiter(), GetTaskCommitmentController)
+			tasks.GET("/stream", ReadTaskRateLimiter(), WorkerAuthMiddleware(), StreamTasksController)
+			tasks.GET("/:task-id", ReadTaskRateLimiter(), OptionalWorkerAuthMiddleware(), GetTaskByIdController)
 			tasks.GET("/next-task/:task-id", ReadTaskRateLimiter(), WorkerAuthMiddleware(), GetNextInProgressTaskController)
 			tasks.GET("/", ReadTaskRateLimiter(), WorkerAuthMiddleware(), GetTasksByPageController)
 		}
 
+		apiV1.GET("/commitments/:batch-id", ReadTaskRateLimiter(), GetCommitmentBatchController)
+
 		miner := apiV1.Group("/miner")
 		{
 			miner.POST("/session/auth", GeneralRateLimiter(), GenerateCookieAuth)
+			miner.GET("/agreement", GeneralRateLimiter(), MinerAuthMiddleware(), GetMinerAgreementController)
+			miner.GET("/tasks", GeneralRateLimiter(), MinerAuthMiddleware(), GetMinerTasksController)
+			miner.GET("/results/export", GeneralRateLimiter(), MinerAuthMiddleware(), ExportMinerResultsController)
 
 			apiKeyGroup := miner.Group("/api-key")
 			apiKeyGroup.Use(GeneralRateLimiter())
@@ -44,6 +65,15 @@ func LoginRoutes(router *gin.Engine) {
 				apiKeyGroup.PUT("/disable", MinerCookieAuthMiddleware(), MinerApiKeyDisableController)
 			}
 
+			webhookGroup := miner.Group("/webhooks")
+			webhookGroup.Use(GeneralRateLimiter(), MinerAuthMiddleware())
+			{
+				webhookGroup.POST("", CreateWebhookController)
+				webhookGroup.GET("", ListWebhooksController)
+				webhookGroup.DELETE("/:webhook-id", DeleteWebhookController)
+				webhookGroup.GET("/:webhook-id/deliveries", GetWebhookDeliveriesController)
+			}
+
 			subScriptionKeyGroup := miner.Group("/subscription-key")
 			subScriptionKeyGroup.Use(GeneralRateLimiter())
 			{
@@ -60,6 +90,7 @@ func LoginRoutes(router *gin.Engine) {
 			metrics.GET("/task-result-count", GetTotalTasksResultsController)
 			metrics.GET("/average-task-completion-time", GetAvgTaskCompletionTimeController)
 			metrics.GET("/completed-tasks-by-interval", GetCompletedTasksCountByIntervalController)
+			metrics.GET("/time-on-task", GetTimeOnTaskMetricsController)
 		}
 	}
 }
@@ -3,9 +3,14 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,9 +19,14 @@ import (
 	"dojo-api/pkg/event"
 	"dojo-api/pkg/metric"
 	"dojo-api/pkg/miner"
+	"dojo-api/pkg/orm"
+	"dojo-api/pkg/task"
+	"dojo-api/pkg/webhook"
 	"dojo-api/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -49,6 +59,72 @@ func handleCurrentSession(c *gin.Context) (*auth.SecureCookieSession, error) {
 	return &currSession, nil
 }
 
+// getAuthenticatedMinerApiKey returns the API key set by MinerAuthMiddleware along with its miner,
+// or aborts the request and returns nil if there is none
+func getAuthenticatedMinerApiKey(c *gin.Context) *db.APIKeyModel {
+	apiKeyInterface, exists := c.Get("minerApiKey")
+	apiKey, _ := apiKeyInterface.(*db.APIKeyModel)
+	if !exists || apiKey == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, defaultErrorResponse("Unauthorized"))
+		return nil
+	}
+	return apiKey
+}
+
+// getAuthenticatedWorker returns the worker from the JWT set by WorkerAuthMiddleware,
+// or aborts the request and returns nil if there is none
+func getAuthenticatedWorker(c *gin.Context) *db.DojoWorkerModel {
+	jwtClaims, ok := c.Get("userInfo")
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, defaultErrorResponse("Unauthorized"))
+		return nil
+	}
+
+	userInfo, ok := jwtClaims.(*jwt.RegisteredClaims)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, defaultErrorResponse("Unauthorized"))
+		return nil
+	}
+
+	foundWorker, err := orm.NewDojoWorkerORM().GetDojoWorkerByWalletAddress(userInfo.Subject)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Worker not found"))
+			return nil
+		}
+		log.Error().Err(err).Msg("Failed to get worker")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, defaultErrorResponse("Failed to get worker"))
+		return nil
+	}
+
+	if foundWorker == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, defaultErrorResponse("Worker not found"))
+		return nil
+	}
+	return foundWorker
+}
+
+// verifySubmissionSignature checks the result signature against the submitted resultData bytes, which can differ
+// from re-encoding the parsed results, and returns the canonical bytes that were signed
+func verifySubmissionSignature(c *gin.Context, taskId string, signature task.ResultSignature, worker *db.DojoWorkerModel) (*task.SignedResult, error) {
+	var rawRequest struct {
+		ResultData json.RawMessage `json:"resultData"`
+	}
+	if err := c.ShouldBindBodyWith(&rawRequest, binding.JSON); err != nil {
+		return nil, err
+	}
+
+	canonicalResultData, err := task.CanonicalResultData(rawRequest.ResultData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := task.VerifyResultSignature(taskId, canonicalResultData, signature, worker.WalletAddress, worker.ChainID); err != nil {
+		return nil, err
+	}
+	return &task.SignedResult{Signature: signature, CanonicalResultData: canonicalResultData}, nil
+}
+
 func buildApiKeyResponse(apiKeys []db.APIKeyModel) miner.MinerApiKeysResponse {
 	keys := make([]string, 0)
 	for _, apiKey := range apiKeys {
@@ -145,6 +221,33 @@ func handleMetricData(currentTask *db.TaskModel, updatedTask *db.TaskModel) {
 	}()
 }
 
+// parseOptionalUnixTime reads a query parameter holding a Unix timestamp in seconds, nil if it is not set
+func parseOptionalUnixTime(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s format. Use Unix timestamp (seconds since epoch)", name)
+	}
+	t := time.Unix(seconds, 0)
+	return &t, nil
+}
+
+// parseOptionalNonNegativeFloat reads an optional non-negative number query parameter, nil when it is not set
+func parseOptionalNonNegativeFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) || number < 0 {
+		return nil, fmt.Errorf("invalid %s parameter, must be a non-negative number", name)
+	}
+	return &number, nil
+}
+
 // Get the user's IP address from the gin request headers
 func getCallerIP(c *gin.Context) string {
 	if runtimeEnv := utils.LoadDotEnv("RUNTIME_ENV"); runtimeEnv == "aws" {
@@ -233,3 +336,67 @@ func CustomGinLogger(logger *zerolog.Logger) gin.HandlerFunc {
 			Msg("")
 	}
 }
+
+// handleReputationUpdate recomputes the worker's reputation in the background after a submission
+func handleReputationUpdate(workerId string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if _, err := task.NewReputationService().UpdateReputation(ctx, workerId); err != nil {
+			log.Error().Err(err).Str("workerId", workerId).Msg("Failed to update worker reputation")
+		}
+	}()
+}
+
+// handleTaskSettlement splits the task's reward across its results in the background once it completes,
+// tasks that fail to settle here are picked up by the periodic settlement
+func handleTaskSettlement(taskId string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := task.NewSettlementService().SettleTask(ctx, taskId); err != nil {
+			log.Error().Err(err).Str("taskId", taskId).Msg("Failed to settle task")
+		}
+	}()
+}
+
+// handleWebhookEvent queues deliveries of a task event to the miner's webhooks in the background
+func handleWebhookEvent(event webhook.Event, updatedTask *db.TaskModel) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := webhook.GetWebhookService().NotifyTaskEvent(ctx, event, updatedTask); err != nil {
+			log.Error().Err(err).Str("taskId", updatedTask.ID).Str("event", string(event)).Msg("Failed to notify webhooks")
+		}
+	}()
+}
+
+// handleTaskFeedEvent publishes task events to the workers streaming the miner's tasks in the background
+func handleTaskFeedEvent(eventType task.TaskFeedEventType, tasks ...*db.TaskModel) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		feedService := task.NewTaskFeedService()
+		for _, t := range tasks {
+			if err := feedService.Publish(ctx, eventType, t); err != nil {
+				log.Error().Err(err).Str("taskId", t.ID).Str("event", string(eventType)).Msg("Failed to publish task feed event")
+			}
+		}
+	}()
+}
+
+// handleTaskOpen records in the background when a signed in worker first opens a task
+func handleTaskOpen(taskId string, workerId string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := task.NewTimeOnTaskService().RecordTaskOpen(ctx, taskId, workerId); err != nil {
+			log.Error().Err(err).Str("taskId", taskId).Str("workerId", workerId).Msg("Failed to record task open")
+		}
+	}()
+}
@@ -38,14 +38,17 @@ type CacheKey string
 // CacheKeys holds all cache key constants
 type CacheKeys struct {
 	// Task cache keys
-	TaskById      CacheKey
-	TasksByWorker CacheKey
+	TaskById           CacheKey
+	TasksByWorker      CacheKey
+	TasksCountByWorker CacheKey
 
 	// Task Result cache keys
 	TaskResultByTaskAndWorker CacheKey
 	TaskResultByWorker        CacheKey
 	TaskResultsTotal          CacheKey
 	CompletedTasksTotal       CacheKey
+	TaskAggregate             CacheKey
+	TaskAgreement             CacheKey
 	// Worker cache keys
 	WorkerByWallet CacheKey
 	WorkerCount    CacheKey
@@ -53,19 +56,35 @@ type CacheKeys struct {
 	// Subscription cache keys
 	SubByHotkey CacheKey
 	SubByKey    CacheKey
+
+	// Task lease keys
+	TaskLease      CacheKey
+	TaskLeaseIndex CacheKey
+
+	// Webhook delivery keys
+	WebhookQueue      CacheKey
+	WebhookDelivery   CacheKey
+	WebhookLog        CacheKey
+	WebhookDeadLetter CacheKey
+
+	// Task feed pub/sub channels
+	TaskFeed CacheKey
 }
 
 // Default cache keys
 var cacheKeys = CacheKeys{
 	// Task cache keys
-	TaskById:      "task",
-	TasksByWorker: "task:worker",
+	TaskById:           "task",
+	TasksByWorker:      "task:worker",
+	TasksCountByWorker: "task:worker:count",
 
 	// Task Result cache keys
 	TaskResultByTaskAndWorker: "tr:task:worker",
 	TaskResultByWorker:        "tr:worker",
 	TaskResultsTotal:          "metrics:tr:total",
 	CompletedTasksTotal:       "metrics:completed_tasks:total",
+	TaskAggregate:             "tr:aggregate",
+	TaskAgreement:             "tr:agreement",
 
 	// Worker cache keys
 	WorkerByWallet: "worker:wallet",
@@ -74,13 +93,29 @@ var cacheKeys = CacheKeys{
 	// Subscription cache keys
 	SubByHotkey: "sub:hotkey",
 	SubByKey:    "sub:key",
+
+	// Task lease keys
+	TaskLease:      "lease:task",
+	TaskLeaseIndex: "lease:tasks",
+
+	// Webhook delivery keys
+	WebhookQueue:      "webhook:queue",
+	WebhookDelivery:   "webhook:delivery",
+	WebhookLog:        "webhook:log",
+	WebhookDeadLetter: "webhook:dead",
+
+	// Task feed pub/sub channels
+	TaskFeed: "feed:miner",
 }
 
 var cacheExpirations = map[CacheKey]time.Duration{
 	cacheKeys.TaskById:                  5 * time.Minute,
 	cacheKeys.TasksByWorker:             2 * time.Minute,
+	cacheKeys.TasksCountByWorker:        1 * time.Minute,
 	cacheKeys.TaskResultByTaskAndWorker: 10 * time.Minute,
 	cacheKeys.TaskResultByWorker:        10 * time.Minute,
+	cacheKeys.TaskAggregate:             10 * time.Minute,
+	cacheKeys.TaskAgreement:             10 * time.Minute,
 	cacheKeys.WorkerByWallet:            5 * time.Minute,
 	cacheKeys.WorkerCount:               1 * time.Minute,
 	cacheKeys.SubByHotkey:               5 * time.Minute,
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+	"dojo-api/pkg/task"
+
+	"github.com/rs/zerolog/log"
+)
+
+// results and tasks are read a page at a time so that exports of any size are streamed with bounded memory
+const (
+	exportPageSize     = 500
+	exportTaskPageSize = 100
+)
+
+type ExportService struct {
+	taskORM       *orm.TaskORM
+	taskResultORM *orm.TaskResultORM
+}
+
+func NewExportService() *ExportService {
+	return &ExportService{
+		taskORM:       orm.NewTaskORM(),
+		taskResultORM: orm.NewTaskResultORM(),
+	}
+}
+
+// ExportMinerResults streams every result of the miner's tasks submitted in [from, to) to w, ordered by submission
+// time. When w is an http.Flusher it is flushed after every page. It returns how many results were exported, and
+// how many were skipped because they could not be flattened to CSV or Parquet rows.
+func (s *ExportService) ExportMinerResults(ctx context.Context, w io.Writer, minerUserId string, from, to time.Time, format Format) (ExportStats, error) {
+	var stats ExportStats
+	resultWriter, err := NewResultWriter(w, format)
+	if err != nil {
+		return stats, err
+	}
+	flusher, _ := w.(http.Flusher)
+
+	var afterCreatedAt time.Time
+	afterId := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		taskResults, err := s.taskResultORM.GetMinerTResultsAfter(ctx, minerUserId, from, to, afterCreatedAt, afterId, exportPageSize)
+		if err != nil {
+			log.Error().Err(err).Str("minerUserId", minerUserId).Int("numExported", stats.NumExported).Msg("Error fetching task results for export")
+			return stats, err
+		}
+
+		for _, taskResult := range taskResults {
+			if err := resultWriter.Write(taskResult); err != nil {
+				if errors.Is(err, errResultSkipped) {
+					log.Warn().Err(err).Str("taskResultId", taskResult.ID).Msg("Skipping task result in export")
+					stats.NumSkipped++
+					continue
+				}
+				return stats, err
+			}
+			stats.NumExported++
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(taskResults) < exportPageSize {
+			break
+		}
+		last := taskResults[len(taskResults)-1]
+		afterCreatedAt, afterId = last.CreatedAt, last.ID
+	}
+
+	if err := resultWriter.Close(); err != nil {
+		return stats, err
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return stats, nil
+}
+
+// ExportMinerPreferencePairs streams the preference pairs of the miner's COMPLETED tasks created in [from, to) to w
+// as JSONL, ordered by task creation time. When w is an http.Flusher it is flushed after every page of tasks.
+// It returns the number of pairs exported.
+func (s *ExportService) ExportMinerPreferencePairs(ctx context.Context, w io.Writer, minerUserId string, from, to time.Time, options task.PreferencePairOptions) (int, error) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	flusher, _ := w.(http.Flusher)
+
+	numExported := 0
+	var afterCreatedAt time.Time
+	afterId := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return numExported, err
+		}
+
+		tasks, err := s.taskORM.GetMinerCompletedTasksAfter(ctx, minerUserId, from, to, afterCreatedAt, afterId, exportTaskPageSize)
+		if err != nil {
+			log.Error().Err(err).Str("minerUserId", minerUserId).Int("numExported", numExported).Msg("Error fetching tasks for preference pair export")
+			return numExported, err
+		}
+		if len(tasks) == 0 {
+			break
+		}
+
+		taskIds := make([]string, 0, len(tasks))
+		for _, t := range tasks {
+			taskIds = append(taskIds, t.ID)
+		}
+		taskResults, err := s.taskResultORM.GetCompletedTResultByTaskIds(ctx, taskIds)
+		if err != nil {
+			log.Error().Err(err).Str("minerUserId", minerUserId).Int("numExported", numExported).Msg("Error fetching task results for preference pair export")
+			return numExported, err
+		}
+		resultsByTask := make(map[string][]db.TaskResultModel, len(tasks))
+		for _, taskResult := range taskResults {
+			resultsByTask[taskResult.TaskID] = append(resultsByTask[taskResult.TaskID], taskResult)
+		}
+
+		for i := range tasks {
+			pairs, err := task.BuildPreferencePairs(&tasks[i], resultsByTask[tasks[i].ID], options)
+			if err != nil {
+				log.Warn().Err(err).Str("taskId", tasks[i].ID).Msg("Skipping task in preference pair export")
+				continue
+			}
+			for _, pair := range pairs {
+				if err := encoder.Encode(pair); err != nil {
+					return numExported, err
+				}
+				numExported++
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(tasks) < exportTaskPageSize {
+			break
+		}
+		last := tasks[len(tasks)-1]
+		afterCreatedAt, afterId = last.CreatedAt, last.ID
+	}
+
+	return numExported, nil
+}
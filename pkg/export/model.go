@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"dojo-api/db"
+)
+
+type Format string
+
+const (
+	FormatJSONL   Format = "jsonl"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+	// chosen/rejected pairs of model responses for DPO and reward model training, as JSONL
+	FormatPreferencePairs Format = "preference-pairs"
+)
+
+var ErrUnsupportedFormat = errors.New("format must be one of jsonl, csv, parquet or preference-pairs")
+
+// errResultSkipped is returned by flat writers for task results without any criteria rows, they are counted instead
+// of failing the export
+var errResultSkipped = errors.New("task result has no exportable criteria")
+
+// ParseFormat returns the export format, defaulting to JSONL when empty
+func ParseFormat(format string) (Format, error) {
+	switch Format(format) {
+	case "", FormatJSONL:
+		return FormatJSONL, nil
+	case FormatCSV, FormatParquet, FormatPreferencePairs:
+		return Format(format), nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func (f Format) FileExtension() string {
+	if f == FormatPreferencePairs {
+		return "jsonl"
+	}
+	return string(f)
+}
+
+// ExportStats counts the task results of an export, skipped results have invalid result data or no criteria and
+// are left out of flattened CSV and Parquet exports
+type ExportStats struct {
+	NumExported int
+	NumSkipped  int
+}
+
+// ResultRecord is one line of a JSONL export
+type ResultRecord struct {
+	TaskId       string              `json:"taskId"`
+	TaskResultId string              `json:"taskResultId"`
+	WorkerId     string              `json:"workerId"`
+	Status       db.TaskResultStatus `json:"status"`
+	SubmittedAt  time.Time           `json:"submittedAt"`
+	ResultData   json.RawMessage     `json:"resultData"`
+}
+
+// flattened CSV and Parquet exports have one row per task result, model and criterion
+var flatColumns = []ParquetColumn{
+	{Name: "task_id", Kind: ParquetString},
+	{Name: "task_result_id", Kind: ParquetString},
+	{Name: "worker_id", Kind: ParquetString},
+	{Name: "status", Kind: ParquetString},
+	{Name: "submitted_at", Kind: ParquetTimestamp},
+	{Name: "model", Kind: ParquetString},
+	{Name: "criteria_id", Kind: ParquetString, Optional: true},
+	{Name: "criteria_type", Kind: ParquetString},
+	// the normalised score of score criteria, null for other criteria
+	{Name: "score", Kind: ParquetFloat64, Optional: true},
+	// the text feedback of text criteria, the score of score criteria and the JSON encoded value of other criteria
+	{Name: "value", Kind: ParquetString, Optional: true},
+}
@@ -0,0 +1,409 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// A minimal Parquet writer for flat schemas: every column is a top level REQUIRED or OPTIONAL leaf, values are
+// PLAIN encoded and uncompressed, and each row group is a single data page per column. Only rowGroupSize rows are
+// held in memory, so files of any size can be streamed. See https://github.com/apache/parquet-format.
+
+type parquetType int32
+
+// physical types from parquet.thrift
+const (
+	parquetInt64     parquetType = 2
+	parquetDouble    parquetType = 5
+	parquetByteArray parquetType = 6
+)
+
+// converted types from parquet.thrift
+const (
+	convertedUTF8            int32 = 0
+	convertedTimestampMillis int32 = 9
+)
+
+const (
+	repetitionRequired int32 = 0
+	repetitionOptional int32 = 1
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	codecUncompressed int32 = 0
+	pageTypeData      int32 = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+type ParquetColumnKind int
+
+const (
+	ParquetString ParquetColumnKind = iota
+	ParquetFloat64
+	ParquetTimestamp
+)
+
+type ParquetColumn struct {
+	Name     string
+	Kind     ParquetColumnKind
+	Optional bool
+}
+
+func (c ParquetColumn) physicalType() parquetType {
+	switch c.Kind {
+	case ParquetFloat64:
+		return parquetDouble
+	case ParquetTimestamp:
+		return parquetInt64
+	default:
+		return parquetByteArray
+	}
+}
+
+type columnBuffer struct {
+	// PLAIN encoded values of the rows that are not null
+	values bytes.Buffer
+	// whether each row has a value, only used by OPTIONAL columns
+	defined []bool
+}
+
+type columnChunk struct {
+	dataPageOffset int64
+	size           int64
+	numValues      int64
+}
+
+type rowGroup struct {
+	numRows int64
+	size    int64
+	columns []columnChunk
+}
+
+type ParquetWriter struct {
+	w            io.Writer
+	offset       int64
+	columns      []ParquetColumn
+	buffers      []columnBuffer
+	rowGroupSize int
+	numRows      int
+	totalRows    int64
+	rowGroups    []rowGroup
+}
+
+// NewParquetWriter writes the file header, rows are buffered until rowGroupSize of them can be written as a row group
+func NewParquetWriter(w io.Writer, columns []ParquetColumn, rowGroupSize int) (*ParquetWriter, error) {
+	pw := &ParquetWriter{
+		w:            w,
+		columns:      columns,
+		buffers:      make([]columnBuffer, len(columns)),
+		rowGroupSize: rowGroupSize,
+	}
+	if err := pw.write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// WriteRow takes one value per column: a string, float64 or time.Time matching the column kind, or nil for null
+func (pw *ParquetWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(pw.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(pw.columns), len(values))
+	}
+
+	for i, column := range pw.columns {
+		buffer := &pw.buffers[i]
+		if values[i] == nil {
+			if !column.Optional {
+				return fmt.Errorf("column %s is required", column.Name)
+			}
+			buffer.defined = append(buffer.defined, false)
+			continue
+		}
+
+		switch value := values[i].(type) {
+		case string:
+			if column.Kind != ParquetString {
+				return fmt.Errorf("column %s does not take a string", column.Name)
+			}
+			_ = binary.Write(&buffer.values, binary.LittleEndian, uint32(len(value)))
+			buffer.values.WriteString(value)
+		case float64:
+			if column.Kind != ParquetFloat64 {
+				return fmt.Errorf("column %s does not take a float64", column.Name)
+			}
+			_ = binary.Write(&buffer.values, binary.LittleEndian, math.Float64bits(value))
+		case time.Time:
+			if column.Kind != ParquetTimestamp {
+				return fmt.Errorf("column %s does not take a time", column.Name)
+			}
+			_ = binary.Write(&buffer.values, binary.LittleEndian, value.UnixMilli())
+		default:
+			return fmt.Errorf("unsupported value %T for column %s", values[i], column.Name)
+		}
+		if column.Optional {
+			buffer.defined = append(buffer.defined, true)
+		}
+	}
+
+	pw.numRows++
+	if pw.numRows >= pw.rowGroupSize {
+		return pw.flushRowGroup()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file footer, it does not close the underlying writer
+func (pw *ParquetWriter) Close() error {
+	if pw.numRows > 0 {
+		if err := pw.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := pw.fileMetaData()
+	if err := pw.write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(pw.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	return pw.write(parquetMagic)
+}
+
+func (pw *ParquetWriter) write(data []byte) error {
+	n, err := pw.w.Write(data)
+	pw.offset += int64(n)
+	return err
+}
+
+func (pw *ParquetWriter) flushRowGroup() error {
+	group := rowGroup{numRows: int64(pw.numRows), columns: make([]columnChunk, 0, len(pw.columns))}
+
+	for i, column := range pw.columns {
+		buffer := &pw.buffers[i]
+
+		var page bytes.Buffer
+		if column.Optional {
+			levels := encodeDefinitionLevels(buffer.defined)
+			_ = binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+			page.Write(levels)
+		}
+		page.Write(buffer.values.Bytes())
+
+		header := pageHeader(pw.numRows, page.Len())
+		chunk := columnChunk{
+			dataPageOffset: pw.offset,
+			size:           int64(len(header) + page.Len()),
+			numValues:      int64(pw.numRows),
+		}
+		if err := pw.write(header); err != nil {
+			return err
+		}
+		if err := pw.write(page.Bytes()); err != nil {
+			return err
+		}
+
+		group.columns = append(group.columns, chunk)
+		group.size += chunk.size
+		buffer.values.Reset()
+		buffer.defined = buffer.defined[:0]
+	}
+
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.totalRows += int64(pw.numRows)
+	pw.numRows = 0
+	return nil
+}
+
+// encodeDefinitionLevels encodes the levels of a column with a max definition level of 1 as RLE runs
+func encodeDefinitionLevels(defined []bool) []byte {
+	var buf bytes.Buffer
+	for start := 0; start < len(defined); {
+		end := start
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+		writeUvarint(&buf, uint64(end-start)<<1)
+		if defined[start] {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		start = end
+	}
+	return buf.Bytes()
+}
+
+func pageHeader(numValues int, pageSize int) []byte {
+	t := &thriftWriter{}
+	t.i32Field(1, pageTypeData)
+	t.i32Field(2, int32(pageSize))
+	t.i32Field(3, int32(pageSize))
+	t.structField(5, func() {
+		t.i32Field(1, int32(numValues))
+		t.i32Field(2, encodingPlain)
+		t.i32Field(3, encodingRLE)
+		t.i32Field(4, encodingRLE)
+	})
+	t.stop()
+	return t.buf.Bytes()
+}
+
+func (pw *ParquetWriter) fileMetaData() []byte {
+	t := &thriftWriter{}
+	t.i32Field(1, 1)
+
+	t.listField(2, thriftStruct, len(pw.columns)+1)
+	t.structElem(func() {
+		t.stringField(4, "schema")
+		t.i32Field(5, int32(len(pw.columns)))
+	})
+	for _, column := range pw.columns {
+		column := column
+		t.structElem(func() {
+			t.i32Field(1, int32(column.physicalType()))
+			if column.Optional {
+				t.i32Field(3, repetitionOptional)
+			} else {
+				t.i32Field(3, repetitionRequired)
+			}
+			t.stringField(4, column.Name)
+			switch column.Kind {
+			case ParquetString:
+				t.i32Field(6, convertedUTF8)
+			case ParquetTimestamp:
+				t.i32Field(6, convertedTimestampMillis)
+			}
+		})
+	}
+
+	t.i64Field(3, pw.totalRows)
+
+	t.listField(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		group := group
+		t.structElem(func() {
+			t.listField(1, thriftStruct, len(group.columns))
+			for i, chunk := range group.columns {
+				column, chunk := pw.columns[i], chunk
+				t.structElem(func() {
+					t.i64Field(2, chunk.dataPageOffset)
+					t.structField(3, func() {
+						t.i32Field(1, int32(column.physicalType()))
+						t.listField(2, thriftI32, 2)
+						t.writeVarint(zigzag64(int64(encodingPlain)))
+						t.writeVarint(zigzag64(int64(encodingRLE)))
+						t.listField(3, thriftBinary, 1)
+						t.writeBinary(column.Name)
+						t.i32Field(4, codecUncompressed)
+						t.i64Field(5, chunk.numValues)
+						t.i64Field(6, chunk.size)
+						t.i64Field(7, chunk.size)
+						t.i64Field(9, chunk.dataPageOffset)
+					})
+				})
+			}
+			t.i64Field(2, group.size)
+			t.i64Field(3, group.numRows)
+		})
+	}
+
+	t.stringField(6, "dojo-api")
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// compact protocol type IDs
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter writes the subset of the thrift compact protocol used by the parquet footer and page headers
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	parents []int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.buf.WriteByte(fieldType)
+		t.writeVarint(zigzag64(int64(id)))
+	}
+	t.lastID = id
+}
+
+func (t *thriftWriter) i32Field(id int16, value int32) {
+	t.fieldHeader(id, thriftI32)
+	t.writeVarint(zigzag64(int64(value)))
+}
+
+func (t *thriftWriter) i64Field(id int16, value int64) {
+	t.fieldHeader(id, thriftI64)
+	t.writeVarint(zigzag64(value))
+}
+
+func (t *thriftWriter) stringField(id int16, value string) {
+	t.fieldHeader(id, thriftBinary)
+	t.writeBinary(value)
+}
+
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.writeVarint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) structField(id int16, writeFields func()) {
+	t.fieldHeader(id, thriftStruct)
+	t.structElem(writeFields)
+}
+
+// structElem writes a struct, either as a list element or as the value of a struct field
+func (t *thriftWriter) structElem(writeFields func()) {
+	t.parents = append(t.parents, t.lastID)
+	t.lastID = 0
+	writeFields()
+	t.stop()
+	t.lastID = t.parents[len(t.parents)-1]
+	t.parents = t.parents[:len(t.parents)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+func (t *thriftWriter) writeBinary(value string) {
+	t.writeVarint(uint64(len(value)))
+	t.buf.WriteString(value)
+}
+
+func (t *thriftWriter) writeVarint(value uint64) {
+	writeUvarint(&t.buf, value)
+}
+
+func writeUvarint(buf *bytes.Buffer, value uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], value)
+	buf.Write(scratch[:n])
+}
+
+func zigzag64(value int64) uint64 {
+	return uint64((value << 1) ^ (value >> 63))
+}
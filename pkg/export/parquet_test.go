@@ -0,0 +1,361 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The reader below decodes files straight from the Parquet and thrift compact protocol specs, sharing no code with
+// the writer, so a round trip checks the bytes a Parquet reader sees rather than the writer against itself.
+
+type parquetFile struct {
+	schema  []map[int16]interface{}
+	numRows int64
+	rows    [][]interface{}
+}
+
+func readParquet(t *testing.T, data []byte) parquetFile {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("missing PAR1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	if footerStart < 4 {
+		t.Fatalf("footer length %d out of range", footerLen)
+	}
+	meta, n := readStruct(t, data[footerStart:])
+	if n != footerLen {
+		t.Fatalf("footer decoded %d bytes, want %d", n, footerLen)
+	}
+
+	file := parquetFile{numRows: meta[3].(int64)}
+	for _, elem := range meta[2].([]interface{}) {
+		file.schema = append(file.schema, elem.(map[int16]interface{}))
+	}
+	leaves := file.schema[1:]
+	if numChildren := file.schema[0][5].(int64); int(numChildren) != len(leaves) {
+		t.Fatalf("root has %d children, want %d", numChildren, len(leaves))
+	}
+
+	groups, _ := meta[4].([]interface{})
+	for _, g := range groups {
+		group := g.(map[int16]interface{})
+		groupRows := int(group[3].(int64))
+		chunks := group[1].([]interface{})
+		if len(chunks) != len(leaves) {
+			t.Fatalf("row group has %d column chunks, want %d", len(chunks), len(leaves))
+		}
+
+		columns := make([][]interface{}, len(leaves))
+		for i, c := range chunks {
+			chunkMeta := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if int(chunkMeta[5].(int64)) != groupRows {
+				t.Fatalf("column chunk has %d values, want %d", chunkMeta[5], groupRows)
+			}
+			offset := int(chunkMeta[9].(int64))
+			columns[i] = readDataPage(t, data[offset:], leaves[i], groupRows)
+		}
+		for r := 0; r < groupRows; r++ {
+			row := make([]interface{}, len(leaves))
+			for i := range leaves {
+				row[i] = columns[i][r]
+			}
+			file.rows = append(file.rows, row)
+		}
+	}
+	return file
+}
+
+func readDataPage(t *testing.T, data []byte, leaf map[int16]interface{}, numRows int) []interface{} {
+	t.Helper()
+	header, n := readStruct(t, data)
+	if header[1].(int64) != 0 {
+		t.Fatalf("page type %d, want a data page", header[1])
+	}
+	if header[2] != header[3] {
+		t.Fatalf("compressed size %d differs from uncompressed size %d", header[3], header[2])
+	}
+	dataPageHeader := header[5].(map[int16]interface{})
+	if int(dataPageHeader[1].(int64)) != numRows || dataPageHeader[2].(int64) != 0 {
+		t.Fatalf("data page header %v, want %d PLAIN values", dataPageHeader, numRows)
+	}
+	page := data[n : n+int(header[3].(int64))]
+
+	defined := make([]bool, numRows)
+	if leaf[3].(int64) == 1 {
+		levelsLen := int(binary.LittleEndian.Uint32(page))
+		levels := readHybridBitWidth1(t, page[4:4+levelsLen], numRows)
+		for i, level := range levels {
+			defined[i] = level == 1
+		}
+		page = page[4+levelsLen:]
+	} else {
+		for i := range defined {
+			defined[i] = true
+		}
+	}
+
+	values := make([]interface{}, numRows)
+	for i := range values {
+		if !defined[i] {
+			continue
+		}
+		switch leaf[1].(int64) {
+		case 2:
+			millis := int64(binary.LittleEndian.Uint64(page))
+			values[i] = time.UnixMilli(millis).UTC()
+			page = page[8:]
+		case 5:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case 6:
+			length := int(binary.LittleEndian.Uint32(page))
+			values[i] = string(page[4 : 4+length])
+			page = page[4+length:]
+		default:
+			t.Fatalf("unexpected physical type %d", leaf[1])
+		}
+	}
+	if len(page) != 0 {
+		t.Fatalf("%d bytes left over in the page of column %s", len(page), leaf[4])
+	}
+	return values
+}
+
+// readHybridBitWidth1 decodes the RLE / bit-packed hybrid encoding of levels with a bit width of 1
+func readHybridBitWidth1(t *testing.T, data []byte, count int) []int {
+	t.Helper()
+	var levels []int
+	for len(data) > 0 {
+		header, n := binary.Uvarint(data)
+		data = data[n:]
+		if header&1 == 0 {
+			for i := uint64(0); i < header>>1; i++ {
+				levels = append(levels, int(data[0]))
+			}
+			data = data[1:]
+		} else {
+			numBytes := int(header >> 1)
+			for _, b := range data[:numBytes] {
+				for bit := 0; bit < 8; bit++ {
+					levels = append(levels, int(b>>bit&1))
+				}
+			}
+			data = data[numBytes:]
+		}
+	}
+	if len(levels) < count {
+		t.Fatalf("decoded %d levels, want %d", len(levels), count)
+	}
+	return levels[:count]
+}
+
+// readStruct decodes a thrift compact protocol struct into its fields by ID, returning the bytes read
+func readStruct(t *testing.T, data []byte) (map[int16]interface{}, int) {
+	t.Helper()
+	fields := map[int16]interface{}{}
+	pos := 0
+	var lastID int16
+	for {
+		b := data[pos]
+		pos++
+		if b == 0 {
+			return fields, pos
+		}
+		fieldType := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			lastID += delta
+		} else {
+			id, n := binary.Varint(data[pos:])
+			pos += n
+			lastID = int16(id)
+		}
+		value, n := readValue(t, data[pos:], fieldType)
+		pos += n
+		fields[lastID] = value
+	}
+}
+
+func readValue(t *testing.T, data []byte, valueType byte) (interface{}, int) {
+	t.Helper()
+	switch valueType {
+	case 5, 6:
+		value, n := binary.Varint(data)
+		return value, n
+	case 8:
+		length, n := binary.Uvarint(data)
+		return string(data[n : n+int(length)]), n + int(length)
+	case 9:
+		size, elemType, pos := int(data[0]>>4), data[0]&0x0f, 1
+		if size == 15 {
+			longSize, n := binary.Uvarint(data[1:])
+			size, pos = int(longSize), 1+n
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			value, n := readValue(t, data[pos:], elemType)
+			pos += n
+			list = append(list, value)
+		}
+		return list, pos
+	case 12:
+		return readStruct(t, data)
+	default:
+		t.Fatalf("unexpected thrift type %d", valueType)
+		return nil, 0
+	}
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	columns := []ParquetColumn{
+		{Name: "id", Kind: ParquetString},
+		{Name: "submitted_at", Kind: ParquetTimestamp},
+		{Name: "score", Kind: ParquetFloat64, Optional: true},
+		{Name: "value", Kind: ParquetString, Optional: true},
+	}
+	submittedAt := time.Date(2024, 5, 17, 8, 30, 15, 123_000_000, time.UTC)
+	rows := [][]interface{}{
+		{"a", submittedAt, 0.75, "0.75"},
+		{"b", submittedAt.Add(time.Second), nil, "great answer"},
+		{"", submittedAt.Add(-time.Hour), -1.5, nil},
+		{"ünïcödé ✓", time.UnixMilli(0).UTC(), nil, nil},
+		{"e", submittedAt, 1e-300, `{"1":"model-a"}`},
+		{"f", submittedAt, nil, ""},
+		{"g", submittedAt, math.MaxFloat64, "x"},
+	}
+
+	for _, rowGroupSize := range []int{1, 3, 7, 100} {
+		t.Run("row group size "+strconv.Itoa(rowGroupSize), func(t *testing.T) {
+			var buf bytes.Buffer
+			pw, err := NewParquetWriter(&buf, columns, rowGroupSize)
+			if err != nil {
+				t.Fatalf("NewParquetWriter() error = %v", err)
+			}
+			for _, row := range rows {
+				if err := pw.WriteRow(row); err != nil {
+					t.Fatalf("WriteRow(%v) error = %v", row, err)
+				}
+			}
+			if err := pw.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			file := readParquet(t, buf.Bytes())
+			if file.numRows != int64(len(rows)) {
+				t.Errorf("num_rows = %d, want %d", file.numRows, len(rows))
+			}
+			if !reflect.DeepEqual(file.rows, rows) {
+				t.Errorf("rows = %v, want %v", file.rows, rows)
+			}
+
+			wantSchema := []struct {
+				name       string
+				physical   int64
+				repetition int64
+				converted  interface{}
+			}{
+				{"id", 6, 0, int64(0)},
+				{"submitted_at", 2, 0, int64(9)},
+				{"score", 5, 1, nil},
+				{"value", 6, 1, int64(0)},
+			}
+			for i, want := range wantSchema {
+				leaf := file.schema[i+1]
+				if leaf[4] != want.name || leaf[1] != want.physical || leaf[3] != want.repetition || leaf[6] != want.converted {
+					t.Errorf("schema element %d = %v, want %+v", i+1, leaf, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParquetRoundTripManyColumns(t *testing.T) {
+	// more than 14 list elements switches the thrift list header to the long form
+	columns := make([]ParquetColumn, 20)
+	row := make([]interface{}, len(columns))
+	for i := range columns {
+		columns[i] = ParquetColumn{Name: fmt.Sprintf("column_%d", i), Kind: ParquetString, Optional: i%2 == 1}
+		if i%4 != 1 {
+			row[i] = fmt.Sprintf("value %d", i)
+		}
+	}
+
+	var buf bytes.Buffer
+	pw, err := NewParquetWriter(&buf, columns, 10)
+	if err != nil {
+		t.Fatalf("NewParquetWriter() error = %v", err)
+	}
+	for i := 0; i < 25; i++ {
+		if err := pw.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file := readParquet(t, buf.Bytes())
+	if len(file.rows) != 25 {
+		t.Fatalf("read %d rows, want 25", len(file.rows))
+	}
+	for i, got := range file.rows {
+		if !reflect.DeepEqual(got, row) {
+			t.Fatalf("row %d = %v, want %v", i, got, row)
+		}
+	}
+}
+
+func TestParquetEmptyFile(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := NewParquetWriter(&buf, flatColumns, parquetRowGroupSize)
+	if err != nil {
+		t.Fatalf("NewParquetWriter() error = %v", err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file := readParquet(t, buf.Bytes())
+	if file.numRows != 0 || len(file.rows) != 0 {
+		t.Errorf("read %d rows with num_rows %d, want none", len(file.rows), file.numRows)
+	}
+	if len(file.schema) != len(flatColumns)+1 {
+		t.Errorf("schema has %d elements, want %d", len(file.schema), len(flatColumns)+1)
+	}
+}
+
+func TestParquetWriterRejectsInvalidRows(t *testing.T) {
+	columns := []ParquetColumn{
+		{Name: "id", Kind: ParquetString},
+		{Name: "score", Kind: ParquetFloat64, Optional: true},
+	}
+	tests := []struct {
+		name string
+		row  []interface{}
+	}{
+		{"too few values", []interface{}{"a"}},
+		{"too many values", []interface{}{"a", 1.0, "b"}},
+		{"null required value", []interface{}{nil, 1.0}},
+		{"string for float column", []interface{}{"a", "1"}},
+		{"float for string column", []interface{}{1.0, 1.0}},
+		{"unsupported type", []interface{}{"a", 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pw, err := NewParquetWriter(&bytes.Buffer{}, columns, 10)
+			if err != nil {
+				t.Fatalf("NewParquetWriter() error = %v", err)
+			}
+			if err := pw.WriteRow(tt.row); err == nil {
+				t.Errorf("WriteRow(%v) succeeded, want an error", tt.row)
+			}
+		})
+	}
+}
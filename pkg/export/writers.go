@@ -0,0 +1,185 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/task"
+)
+
+const parquetRowGroupSize = 10000
+
+// ResultWriter writes task results to an export as they are read, Close must be called to complete the export.
+// Flattened writers return errResultSkipped for results that have no rows.
+type ResultWriter interface {
+	Write(taskResult db.TaskResultModel) error
+	Close() error
+}
+
+func NewResultWriter(w io.Writer, format Format) (ResultWriter, error) {
+	switch format {
+	case FormatCSV:
+		csvWriter := csv.NewWriter(w)
+		header := make([]string, 0, len(flatColumns))
+		for _, column := range flatColumns {
+			header = append(header, column.Name)
+		}
+		if err := csvWriter.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvResultWriter{w: csvWriter}, nil
+	case FormatParquet:
+		parquetWriter, err := NewParquetWriter(w, flatColumns, parquetRowGroupSize)
+		if err != nil {
+			return nil, err
+		}
+		return &parquetResultWriter{w: parquetWriter}, nil
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return &jsonlResultWriter{encoder: encoder}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type jsonlResultWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlResultWriter) Write(taskResult db.TaskResultModel) error {
+	return w.encoder.Encode(ResultRecord{
+		TaskId:       taskResult.TaskID,
+		TaskResultId: taskResult.ID,
+		WorkerId:     taskResult.WorkerID,
+		Status:       taskResult.Status,
+		SubmittedAt:  taskResult.CreatedAt,
+		ResultData:   json.RawMessage(taskResult.ResultData),
+	})
+}
+
+func (w *jsonlResultWriter) Close() error {
+	return nil
+}
+
+type csvResultWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvResultWriter) Write(taskResult db.TaskResultModel) error {
+	rows, err := flattenResult(taskResult)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, 0, len(row))
+		for _, value := range row {
+			switch value := value.(type) {
+			case nil:
+				record = append(record, "")
+			case string:
+				record = append(record, value)
+			case float64:
+				record = append(record, strconv.FormatFloat(value, 'f', -1, 64))
+			case time.Time:
+				record = append(record, value.UTC().Format(time.RFC3339Nano))
+			}
+		}
+		if err := w.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.w.Error()
+}
+
+func (w *csvResultWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type parquetResultWriter struct {
+	w *ParquetWriter
+}
+
+func (w *parquetResultWriter) Write(taskResult db.TaskResultModel) error {
+	rows, err := flattenResult(taskResult)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := w.w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *parquetResultWriter) Close() error {
+	return w.w.Close()
+}
+
+// flattenResult returns a row of flatColumns values per model and criterion of the task result, or errResultSkipped
+// when its result data is invalid or has no criteria
+func flattenResult(taskResult db.TaskResultModel) ([][]interface{}, error) {
+	var results []task.Result
+	if err := json.Unmarshal(taskResult.ResultData, &results); err != nil {
+		return nil, fmt.Errorf("%w: invalid result data: %v", errResultSkipped, err)
+	}
+
+	var rows [][]interface{}
+	for _, result := range results {
+		for _, criteria := range result.Criteria {
+			var criteriaId interface{}
+			if id := criteria.GetId(); id != "" {
+				criteriaId = id
+			}
+			score, value := criteriaValue(criteria)
+			rows = append(rows, []interface{}{
+				taskResult.TaskID,
+				taskResult.ID,
+				taskResult.WorkerID,
+				string(taskResult.Status),
+				taskResult.CreatedAt,
+				result.Model,
+				criteriaId,
+				string(criteria.GetType()),
+				score,
+				value,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errResultSkipped
+	}
+	return rows, nil
+}
+
+// criteriaValue returns the score column, set only for score criteria, and the value column of a criterion
+func criteriaValue(criteria task.Criteria) (interface{}, interface{}) {
+	var value interface{}
+	switch c := criteria.(type) {
+	case task.ScoreCriteria:
+		return c.MinerScore, strconv.FormatFloat(c.MinerScore, 'f', -1, 64)
+	case task.TextCriteria:
+		return nil, c.TextFeedback
+	case task.RankingCriteria:
+		value = c.Value
+	case task.MultiScoreCriteria:
+		value = c.Value
+	case task.MultiSelectCriteria:
+		value = c.Value
+	default:
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil || string(encoded) == "null" {
+		return nil, nil
+	}
+	return nil, string(encoded)
+}
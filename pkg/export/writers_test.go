@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"testing"
+	"time"
+
+	"dojo-api/db"
+)
+
+func testTaskResult(resultData string) db.TaskResultModel {
+	return db.TaskResultModel{
+		InnerTaskResult: db.InnerTaskResult{
+			ID:         "result-1",
+			TaskID:     "task-1",
+			WorkerID:   "worker-1",
+			Status:     db.TaskResultStatusCompleted,
+			ResultData: db.JSON(resultData),
+			CreatedAt:  time.Date(2024, 5, 17, 8, 30, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestFlatWritersSkipResultsWithoutRows(t *testing.T) {
+	tests := []struct {
+		name       string
+		resultData string
+		wantRows   int
+		wantSkip   bool
+	}{
+		{"score and text criteria", `[{"model":"a","criteria":[{"type":"score","value":0.5},{"type":"text","text_feedback":"ok"}]}]`, 2, false},
+		{"invalid result data", `{"model":"a"}`, 0, true},
+		{"unknown criteria type", `[{"model":"a","criteria":[{"type":"unknown"}]}]`, 0, true},
+		{"no criteria", `[{"model":"a","criteria":[]}]`, 0, true},
+		{"no models", `[]`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, format := range []Format{FormatCSV, FormatParquet} {
+				var buf bytes.Buffer
+				resultWriter, err := NewResultWriter(&buf, format)
+				if err != nil {
+					t.Fatalf("NewResultWriter(%s) error = %v", format, err)
+				}
+				err = resultWriter.Write(testTaskResult(tt.resultData))
+				if skipped := errors.Is(err, errResultSkipped); skipped != tt.wantSkip || (err != nil && !skipped) {
+					t.Errorf("%s Write() error = %v, want skipped %v", format, err, tt.wantSkip)
+				}
+				if err := resultWriter.Close(); err != nil {
+					t.Fatalf("%s Close() error = %v", format, err)
+				}
+
+				if format == FormatCSV {
+					records, err := csv.NewReader(&buf).ReadAll()
+					if err != nil {
+						t.Fatalf("reading CSV: %v", err)
+					}
+					if got := len(records) - 1; got != tt.wantRows {
+						t.Errorf("CSV has %d rows, want %d", got, tt.wantRows)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package testutil holds the fixtures shared by tests that run against the database and redis of the environment
+package testutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+
+	"github.com/google/uuid"
+)
+
+// NewClient connects to the database and redis of the environment, tests needing them are skipped without one
+func NewClient(t *testing.T) *db.PrismaClient {
+	t.Helper()
+	for _, name := range []string{"DB_HOST", "DB_NAME", "REDIS_HOST", "REDIS_PORT"} {
+		if os.Getenv(name) == "" {
+			t.Skipf("%s not set, skipping database test", name)
+		}
+	}
+	if os.Getenv("RUNTIME_ENV") == "" {
+		t.Setenv("RUNTIME_ENV", "local")
+	}
+
+	clientWrapper := orm.GetPrismaClient()
+	if clientWrapper == nil {
+		t.Fatal("failed to connect to the database")
+	}
+	return clientWrapper.Client
+}
+
+// CreateTask creates a miner and an IN_PROGRESS task accepting maxResults results
+func CreateTask(t *testing.T, client *db.PrismaClient, maxResults int) *db.TaskModel {
+	t.Helper()
+	ctx := context.Background()
+
+	miner, err := client.MinerUser.CreateOne(
+		db.MinerUser.Hotkey.Set("test-" + uuid.NewString()),
+	).Exec(ctx)
+	if err != nil {
+		t.Fatalf("creating miner: %v", err)
+	}
+
+	task, err := client.Task.CreateOne(
+		db.Task.ExpireAt.Set(time.Now().Add(time.Hour)),
+		db.Task.Title.Set("test task"),
+		db.Task.Body.Set("test task"),
+		db.Task.Modality.Set(db.TaskModalityCodeGeneration),
+		db.Task.TaskData.Set(db.JSON(`{"task_data":[]}`)),
+		db.Task.Status.Set(db.TaskStatusInProgress),
+		db.Task.MaxResults.Set(maxResults),
+		db.Task.NumResults.Set(0),
+		db.Task.MinerUser.Link(db.MinerUser.ID.Equals(miner.ID)),
+	).Exec(ctx)
+	if err != nil {
+		t.Fatalf("creating task: %v", err)
+	}
+	return task
+}
+
+// CreateWorker creates a worker with a random wallet address
+func CreateWorker(t *testing.T, client *db.PrismaClient) *db.DojoWorkerModel {
+	t.Helper()
+	worker, err := client.DojoWorker.CreateOne(
+		db.DojoWorker.WalletAddress.Set("0x"+uuid.NewString()),
+		db.DojoWorker.ChainID.Set("1"),
+	).Exec(context.Background())
+	if err != nil {
+		t.Fatalf("creating worker: %v", err)
+	}
+	return worker
+}
@@ -0,0 +1,88 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// HashPair hashes two nodes in sorted order so that proofs do not need to record which side a sibling is on
+func HashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a.Bytes(), b.Bytes()) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a.Bytes(), b.Bytes())
+}
+
+// Tree has the layout of OpenZeppelin's StandardMerkleTree, so its root and proofs match the ones the
+// @openzeppelin/merkle-tree library produces for the same leaves. The sorted leaves fill the end of a flat array in
+// reverse order and every other node is the hash of its two children.
+type Tree struct {
+	nodes []common.Hash
+	// index in nodes of every leaf
+	leafIndex map[common.Hash]int
+}
+
+func NewTree(leaves []common.Hash) (*Tree, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("merkle tree needs at least one leaf")
+	}
+
+	sorted := make([]common.Hash, len(leaves))
+	copy(sorted, leaves)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].Bytes(), sorted[j].Bytes()) < 0 })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return nil, fmt.Errorf("duplicate leaf %s", sorted[i].Hex())
+		}
+	}
+
+	nodes := make([]common.Hash, 2*len(sorted)-1)
+	leafIndex := make(map[common.Hash]int, len(sorted))
+	for i, leaf := range sorted {
+		index := len(nodes) - 1 - i
+		nodes[index] = leaf
+		leafIndex[leaf] = index
+	}
+	for i := len(nodes) - 1 - len(sorted); i >= 0; i-- {
+		nodes[i] = HashPair(nodes[2*i+1], nodes[2*i+2])
+	}
+
+	return &Tree{nodes: nodes, leafIndex: leafIndex}, nil
+}
+
+func (t *Tree) Root() common.Hash {
+	return t.nodes[0]
+}
+
+// Proof returns the sibling hashes from the leaf up to the root
+func (t *Tree) Proof(leaf common.Hash) ([]common.Hash, error) {
+	index, ok := t.leafIndex[leaf]
+	if !ok {
+		return nil, fmt.Errorf("leaf %s is not in the tree", leaf.Hex())
+	}
+
+	proof := make([]common.Hash, 0)
+	for index > 0 {
+		// left children have odd indexes
+		sibling := index - 1
+		if index%2 == 1 {
+			sibling = index + 1
+		}
+		proof = append(proof, t.nodes[sibling])
+		index = (index - 1) / 2
+	}
+	return proof, nil
+}
+
+// VerifyProof checks the proof the same way as OpenZeppelin's MerkleProof.verify
+func VerifyProof(root common.Hash, leaf common.Hash, proof []common.Hash) bool {
+	computed := leaf
+	for _, sibling := range proof {
+		computed = HashPair(computed, sibling)
+	}
+	return computed == root
+}
@@ -0,0 +1,269 @@
+package merkle
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testLeaves returns keccak256(abi.encode(uint256(i))) for i from 1 to n, in that order
+func testLeaves(n int) []common.Hash {
+	leaves := make([]common.Hash, n)
+	for i := range leaves {
+		leaves[i] = crypto.Keccak256Hash(common.LeftPadBytes(big.NewInt(int64(i+1)).Bytes(), common.HashLength))
+	}
+	return leaves
+}
+
+func TestTreeOpenZeppelinReadme(t *testing.T) {
+	// the example of the @openzeppelin/merkle-tree README, StandardMerkleTree.of(values, ["address", "uint256"])
+	values := []struct {
+		address common.Address
+		amount  string
+	}{
+		{common.HexToAddress("0x1111111111111111111111111111111111111111"), "5000000000000000000"},
+		{common.HexToAddress("0x2222222222222222222222222222222222222222"), "2500000000000000000"},
+	}
+	leaves := make([]common.Hash, 0, len(values))
+	for _, value := range values {
+		amount, _ := new(big.Int).SetString(value.amount, 10)
+		encoded := append(common.LeftPadBytes(value.address.Bytes(), common.HashLength), common.LeftPadBytes(amount.Bytes(), common.HashLength)...)
+		leaves = append(leaves, crypto.Keccak256Hash(crypto.Keccak256(encoded)))
+	}
+
+	tree, err := NewTree(leaves)
+	if err != nil {
+		t.Fatalf("NewTree() error = %v", err)
+	}
+	if want := common.HexToHash("0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"); tree.Root() != want {
+		t.Errorf("Root() = %s, want %s", tree.Root().Hex(), want.Hex())
+	}
+}
+
+// roots and proofs StandardMerkleTree produces for testLeaves, proofs are listed in the order of testLeaves
+func TestTreeKnownAnswers(t *testing.T) {
+	tests := []struct {
+		numLeaves int
+		root      string
+		proofs    [][]string
+	}{
+		{
+			numLeaves: 1,
+			root:      "0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6",
+			proofs: [][]string{
+				{},
+			},
+		},
+		{
+			numLeaves: 2,
+			root:      "0x2a171b5bcd1449348c3e09a5424946b5e6d6f5471221941d585131d673952ee4",
+			proofs: [][]string{
+				{
+					"0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5ace",
+				},
+				{
+					"0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6",
+				},
+			},
+		},
+		{
+			numLeaves: 3,
+			root:      "0x4cdbcd942bd29b80bbd5eb9929ec8d0ea9c97d2690f9d2f8318390505ec1a769",
+			proofs: [][]string{
+				{
+					"0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5ace",
+					"0xc2575a0e9e593c00f959f8c92f12db2869c3395a3b0502d05e2516446f71f85b",
+				},
+				{
+					"0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6",
+					"0xc2575a0e9e593c00f959f8c92f12db2869c3395a3b0502d05e2516446f71f85b",
+				},
+				{
+					"0x2a171b5bcd1449348c3e09a5424946b5e6d6f5471221941d585131d673952ee4",
+				},
+			},
+		},
+		{
+			numLeaves: 5,
+			root:      "0xab5d3eff7882d82f9a07a24f2c47d4f86e6ff6d60fca52b4882ed78340a49f23",
+			proofs: [][]string{
+				{
+					"0x8a35acfbc15ff81a39ae7d344fd709f28e8600b4aa8c65c6b64bfe7fe36bd19b",
+					"0x7372ad8ae79e0780fb79401f727fad913e36df18a44331df069c0707274c2e71",
+				},
+				{
+					"0x036b6384b5eca791c62761152d0c79bb0604c104a5fb6f4eb0703f3154bb3db0",
+					"0xc2575a0e9e593c00f959f8c92f12db2869c3395a3b0502d05e2516446f71f85b",
+					"0xb4ac32458d01ec09d972c820893c530c5aca86752a8c02e2499f60b968613ded",
+				},
+				{
+					"0x7b7da98f67ab775ab79cf3e36984e2490fb6897ba0fc1ef566df8d39adedd92f",
+					"0xb4ac32458d01ec09d972c820893c530c5aca86752a8c02e2499f60b968613ded",
+				},
+				{
+					"0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6",
+					"0x7372ad8ae79e0780fb79401f727fad913e36df18a44331df069c0707274c2e71",
+				},
+				{
+					"0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5ace",
+					"0xc2575a0e9e593c00f959f8c92f12db2869c3395a3b0502d05e2516446f71f85b",
+					"0xb4ac32458d01ec09d972c820893c530c5aca86752a8c02e2499f60b968613ded",
+				},
+			},
+		},
+		{
+			numLeaves: 7,
+			root:      "0x76eaf5111ef8f39874a2c68fc8d9dfb167d4c09160f79105eef590fde2c52ace",
+			proofs: [][]string{
+				{
+					"0xc2575a0e9e593c00f959f8c92f12db2869c3395a3b0502d05e2516446f71f85b",
+					"0xc6ac427fb8aaa2487d27a03718825d2ab79856b97c34a6ee73de2ebe3178be0a",
+					"0x7e5d06e94050d228c85b4fc55229be36d1ab545ab56335ee4129b8c91a27a01b",
+				},
+				{
+					"0x036b6384b5eca791c62761152d0c79bb0604c104a5fb6f4eb0703f3154bb3db0",
+					"0xf652222313e28459528d920b65115c16c04f3efc82aaedc97be59f3f377c0d3f",
+					"0x7f7819f69ce525f1b994f0ef767c1d1db1d370e6f56a74ebacf120298f046290",
+				},
+				{
+					"0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6",
+					"0xc6ac427fb8aaa2487d27a03718825d2ab79856b97c34a6ee73de2ebe3178be0a",
+					"0x7e5d06e94050d228c85b4fc55229be36d1ab545ab56335ee4129b8c91a27a01b",
+				},
+				{
+					"0xa66cc928b5edb82af9bd49922954155ab7b0942694bea4ce44661d9a8736c688",
+					"0xa8d08734ea7322e06e0a776297d6f37272e6f5a616160a77c77841e0759bf0ca",
+					"0x7e5d06e94050d228c85b4fc55229be36d1ab545ab56335ee4129b8c91a27a01b",
+				},
+				{
+					"0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5ace",
+					"0xf652222313e28459528d920b65115c16c04f3efc82aaedc97be59f3f377c0d3f",
+					"0x7f7819f69ce525f1b994f0ef767c1d1db1d370e6f56a74ebacf120298f046290",
+				},
+				{
+					"0x7b7da98f67ab775ab79cf3e36984e2490fb6897ba0fc1ef566df8d39adedd92f",
+					"0x7f7819f69ce525f1b994f0ef767c1d1db1d370e6f56a74ebacf120298f046290",
+				},
+				{
+					"0x8a35acfbc15ff81a39ae7d344fd709f28e8600b4aa8c65c6b64bfe7fe36bd19b",
+					"0xa8d08734ea7322e06e0a776297d6f37272e6f5a616160a77c77841e0759bf0ca",
+					"0x7e5d06e94050d228c85b4fc55229be36d1ab545ab56335ee4129b8c91a27a01b",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		leaves := testLeaves(tt.numLeaves)
+		tree, err := NewTree(leaves)
+		if err != nil {
+			t.Fatalf("NewTree(%d leaves) error = %v", tt.numLeaves, err)
+		}
+
+		root := common.HexToHash(tt.root)
+		if tree.Root() != root {
+			t.Errorf("%d leaves: Root() = %s, want %s", tt.numLeaves, tree.Root().Hex(), root.Hex())
+		}
+
+		for i, leaf := range leaves {
+			proof, err := tree.Proof(leaf)
+			if err != nil {
+				t.Fatalf("%d leaves: Proof(leaf %d) error = %v", tt.numLeaves, i+1, err)
+			}
+			if len(proof) != len(tt.proofs[i]) {
+				t.Errorf("%d leaves: Proof(leaf %d) has %d hashes, want %d", tt.numLeaves, i+1, len(proof), len(tt.proofs[i]))
+				continue
+			}
+			for j, hash := range proof {
+				if want := common.HexToHash(tt.proofs[i][j]); hash != want {
+					t.Errorf("%d leaves: Proof(leaf %d)[%d] = %s, want %s", tt.numLeaves, i+1, j, hash.Hex(), want.Hex())
+				}
+			}
+			if !VerifyProof(root, leaf, proof) {
+				t.Errorf("%d leaves: proof of leaf %d does not verify", tt.numLeaves, i+1)
+			}
+		}
+	}
+}
+
+func TestTreeVerifiesEveryLeaf(t *testing.T) {
+	for numLeaves := 1; numLeaves <= 33; numLeaves++ {
+		leaves := testLeaves(numLeaves)
+		tree, err := NewTree(leaves)
+		if err != nil {
+			t.Fatalf("NewTree(%d leaves) error = %v", numLeaves, err)
+		}
+
+		// the root does not depend on the order of the leaves
+		shuffled := append([]common.Hash(nil), leaves...)
+		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		if shuffledTree, err := NewTree(shuffled); err != nil || shuffledTree.Root() != tree.Root() {
+			t.Errorf("%d leaves: root changed with the order of the leaves", numLeaves)
+		}
+
+		for i, leaf := range leaves {
+			proof, err := tree.Proof(leaf)
+			if err != nil {
+				t.Fatalf("%d leaves: Proof(leaf %d) error = %v", numLeaves, i+1, err)
+			}
+			if !VerifyProof(tree.Root(), leaf, proof) {
+				t.Errorf("%d leaves: proof of leaf %d does not verify", numLeaves, i+1)
+			}
+			if numLeaves > 1 && VerifyProof(tree.Root(), leaves[(i+1)%numLeaves], proof) {
+				t.Errorf("%d leaves: proof of leaf %d verifies another leaf", numLeaves, i+1)
+			}
+		}
+	}
+}
+
+func TestTreeSingleLeaf(t *testing.T) {
+	leaf := testLeaves(1)[0]
+	tree, err := NewTree([]common.Hash{leaf})
+	if err != nil {
+		t.Fatalf("NewTree() error = %v", err)
+	}
+	if tree.Root() != leaf {
+		t.Errorf("Root() = %s, want the leaf %s", tree.Root().Hex(), leaf.Hex())
+	}
+	proof, err := tree.Proof(leaf)
+	if err != nil {
+		t.Fatalf("Proof() error = %v", err)
+	}
+	if proof == nil || len(proof) != 0 {
+		t.Errorf("Proof() = %v, want an empty proof", proof)
+	}
+}
+
+func TestNewTreeErrors(t *testing.T) {
+	if _, err := NewTree(nil); err == nil {
+		t.Error("NewTree() without leaves succeeded, want an error")
+	}
+
+	leaves := testLeaves(3)
+	if _, err := NewTree(append(leaves, leaves[1])); err == nil {
+		t.Error("NewTree() with a duplicate leaf succeeded, want an error")
+	}
+
+	tree, err := NewTree(leaves)
+	if err != nil {
+		t.Fatalf("NewTree() error = %v", err)
+	}
+	if _, err := tree.Proof(testLeaves(4)[3]); err == nil {
+		t.Error("Proof() of a leaf not in the tree succeeded, want an error")
+	}
+}
+
+func TestHashPairIsSorted(t *testing.T) {
+	leaves := testLeaves(2)
+	a, b := leaves[0], leaves[1]
+	if HashPair(a, b) != HashPair(b, a) {
+		t.Error("HashPair() depends on the order of its arguments")
+	}
+	// the smaller hash comes first, leaf 2 sorts before leaf 1
+	if want := crypto.Keccak256Hash(b.Bytes(), a.Bytes()); HashPair(a, b) != want {
+		t.Errorf("HashPair() = %s, want %s", HashPair(a, b).Hex(), want.Hex())
+	}
+}
@@ -0,0 +1,88 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dojo-api/db"
+	"dojo-api/pkg/cache"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type CommitmentBatchORM struct {
+	dbClient      *db.PrismaClient
+	clientWrapper *PrismaClientWrapper
+}
+
+func NewCommitmentBatchORM() *CommitmentBatchORM {
+	clientWrapper := GetPrismaClient()
+	return &CommitmentBatchORM{dbClient: clientWrapper.Client, clientWrapper: clientWrapper}
+}
+
+// TaskCommitment is a task's leaf in a commitment batch and its merkle proof
+type TaskCommitment struct {
+	TaskId      string
+	ResultsHash string
+	Proof       db.JSON
+}
+
+// CreateCommitmentBatch stores the merkle root and assigns every task to the batch in one transaction. The batch is
+// rolled back if any task was already batched or its results hash changed since the tree was built.
+func (o *CommitmentBatchORM) CreateCommitmentBatch(ctx context.Context, merkleRoot string, taskCommitments []TaskCommitment) (*db.CommitmentBatchModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	batchId := uuid.NewString()
+
+	params := []interface{}{batchId}
+	values := make([]string, 0, len(taskCommitments))
+	for _, taskCommitment := range taskCommitments {
+		n := len(params)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d::jsonb)", n+1, n+2, n+3))
+		params = append(params, taskCommitment.TaskId, taskCommitment.ResultsHash, string(taskCommitment.Proof))
+	}
+
+	createBatchQuery := `INSERT INTO "CommitmentBatch" (id, merkle_root, num_tasks) VALUES ($1, $2, $3);`
+	assignTasksQuery := fmt.Sprintf(`
+UPDATE "Task" AS t
+SET commitment_batch_id = $1, commitment_proof = v.proof
+FROM (VALUES %s) AS v(id, results_hash, proof)
+WHERE t.id = v.id AND t.results_hash = v.results_hash AND t.commitment_batch_id IS NULL;`, strings.Join(values, ", "))
+	// batchId is generated above and the count is an int so both are safe to inline, DO blocks cannot take parameters
+	guardQuery := fmt.Sprintf(`
+DO $$
+BEGIN
+  IF (SELECT COUNT(*) FROM "Task" WHERE commitment_batch_id = '%s') <> %d THEN
+    RAISE EXCEPTION 'commitment batch does not match the committed tasks';
+  END IF;
+END $$;`, batchId, len(taskCommitments))
+
+	err := o.dbClient.Prisma.Transaction(
+		o.dbClient.Prisma.ExecuteRaw(createBatchQuery, batchId, merkleRoot, len(taskCommitments)).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(assignTasksQuery, params...).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(guardQuery).Tx(),
+	).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating commitment batch")
+		return nil, err
+	}
+
+	cache := cache.GetCacheInstance()
+	for _, taskCommitment := range taskCommitments {
+		if err := cache.DeleteWithSuffix(cache.Keys.TaskById, taskCommitment.TaskId); err != nil {
+			log.Warn().Err(err).Str("taskId", taskCommitment.TaskId).Msg("Failed to delete task cache")
+		}
+	}
+
+	return o.dbClient.CommitmentBatch.FindUnique(db.CommitmentBatch.ID.Equals(batchId)).Exec(ctx)
+}
+
+func (o *CommitmentBatchORM) GetBatchById(ctx context.Context, batchId string) (*db.CommitmentBatchModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.CommitmentBatch.FindUnique(db.CommitmentBatch.ID.Equals(batchId)).Exec(ctx)
+}
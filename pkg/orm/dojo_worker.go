@@ -111,3 +111,54 @@ func (s *DojoWorkerORM) GetDojoWorkers() (int, error) {
 
 	return count, nil
 }
+
+// FlagDojoWorker marks the worker for review, e.g. after repeatedly failing gold tasks
+func (s *DojoWorkerORM) FlagDojoWorker(ctx context.Context, workerId string) (*db.DojoWorkerModel, error) {
+	s.clientWrapper.BeforeQuery()
+	defer s.clientWrapper.AfterQuery()
+
+	worker, err := s.dbClient.DojoWorker.FindUnique(
+		db.DojoWorker.ID.Equals(workerId),
+	).Update(
+		db.DojoWorker.IsFlagged.Set(true),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := cache.GetCacheInstance()
+	if err := cache.DeleteWithSuffix(cache.Keys.WorkerByWallet, worker.WalletAddress); err != nil {
+		log.Warn().Err(err).Msg("Failed to clear worker cache")
+	}
+	return worker, nil
+}
+
+// UpdateReputation sets the worker's reputation score
+func (s *DojoWorkerORM) UpdateReputation(ctx context.Context, workerId string, reputation float64) (*db.DojoWorkerModel, error) {
+	s.clientWrapper.BeforeQuery()
+	defer s.clientWrapper.AfterQuery()
+
+	worker, err := s.dbClient.DojoWorker.FindUnique(
+		db.DojoWorker.ID.Equals(workerId),
+	).Update(
+		db.DojoWorker.Reputation.Set(reputation),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := cache.GetCacheInstance()
+	if err := cache.DeleteWithSuffix(cache.Keys.WorkerByWallet, worker.WalletAddress); err != nil {
+		log.Warn().Err(err).Msg("Failed to clear worker cache")
+	}
+	return worker, nil
+}
+
+func (s *DojoWorkerORM) GetDojoWorkersByIds(ctx context.Context, workerIds []string) ([]db.DojoWorkerModel, error) {
+	s.clientWrapper.BeforeQuery()
+	defer s.clientWrapper.AfterQuery()
+
+	return s.dbClient.DojoWorker.FindMany(
+		db.DojoWorker.ID.In(workerIds),
+	).Exec(ctx)
+}
@@ -0,0 +1,43 @@
+package orm
+
+import (
+	"context"
+
+	"dojo-api/db"
+)
+
+type GoldTaskGradeORM struct {
+	dbClient      *db.PrismaClient
+	clientWrapper *PrismaClientWrapper
+}
+
+func NewGoldTaskGradeORM() *GoldTaskGradeORM {
+	clientWrapper := GetPrismaClient()
+	return &GoldTaskGradeORM{dbClient: clientWrapper.Client, clientWrapper: clientWrapper}
+}
+
+// CreateGrade records whether a worker's result on a gold task matched the expected answers
+func (o *GoldTaskGradeORM) CreateGrade(ctx context.Context, taskId string, workerId string, taskResultId string, passed bool, details db.JSON) (*db.GoldTaskGradeModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.GoldTaskGrade.CreateOne(
+		db.GoldTaskGrade.Task.Link(db.Task.ID.Equals(taskId)),
+		db.GoldTaskGrade.DojoWorker.Link(db.DojoWorker.ID.Equals(workerId)),
+		db.GoldTaskGrade.TaskResult.Link(db.TaskResult.ID.Equals(taskResultId)),
+		db.GoldTaskGrade.Passed.Set(passed),
+		db.GoldTaskGrade.Details.Set(details),
+	).Exec(ctx)
+}
+
+// GetRecentGradesByWorker returns the worker's latest gold task grades, newest first
+func (o *GoldTaskGradeORM) GetRecentGradesByWorker(ctx context.Context, workerId string, limit int) ([]db.GoldTaskGradeModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.GoldTaskGrade.FindMany(
+		db.GoldTaskGrade.WorkerID.Equals(workerId),
+	).OrderBy(db.GoldTaskGrade.CreatedAt.Order(db.SortOrderDesc)).
+		Take(limit).
+		Exec(ctx)
+}
@@ -0,0 +1,253 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"dojo-api/db"
+	"dojo-api/pkg/cache"
+
+	"github.com/rs/zerolog/log"
+)
+
+// accounts that make up a worker's balance, DojoWorker.current_stake_amount is reconciled against their sum
+const workerAccounts = `('WORKER_AVAILABLE'::"LedgerAccount", 'WORKER_PENDING_PAYOUT'::"LedgerAccount")`
+
+type LedgerORM struct {
+	dbClient      *db.PrismaClient
+	clientWrapper *PrismaClientWrapper
+}
+
+func NewLedgerORM() *LedgerORM {
+	clientWrapper := GetPrismaClient()
+	return &LedgerORM{dbClient: clientWrapper.Client, clientWrapper: clientWrapper}
+}
+
+// WorkerBalance is derived from the worker's ledger entries
+type WorkerBalance struct {
+	Available     db.Decimal `json:"available"`
+	PendingPayout db.Decimal `json:"pendingPayout"`
+	PaidOut       db.Decimal `json:"paidOut"`
+}
+
+// settledResultEntriesQuery posts a transaction per settled task result with a positive finalised reward or loss,
+// results that already have the transaction are skipped. The float rewards are rounded to the ledger's 18 decimals.
+func settledResultEntriesQuery(entryType db.LedgerEntryType, amountColumn string, filter string) string {
+	transactionPrefix := "reward:"
+	workerSign, poolSign := "", "-"
+	if entryType == db.LedgerEntryTypePenalty {
+		transactionPrefix = "penalty:"
+		workerSign, poolSign = "-", ""
+	}
+
+	return fmt.Sprintf(`
+INSERT INTO "LedgerEntry" (id, transaction_id, type, account, amount, worker_id, task_id, task_result_id)
+SELECT gen_random_uuid()::text, '%s' || tr.id, '%s'::"LedgerEntryType", leg.account, leg.amount, tr.worker_id, tr.task_id, tr.id
+FROM "TaskResult" AS tr
+JOIN "Task" AS t ON t.id = tr.task_id
+CROSS JOIN LATERAL (VALUES
+  ('WORKER_AVAILABLE'::"LedgerAccount", %sROUND(tr.%s::numeric, 18)),
+  ('REWARD_POOL'::"LedgerAccount", %sROUND(tr.%s::numeric, 18))
+) AS leg(account, amount)
+WHERE t.settled_at IS NOT NULL AND tr.%s > 0 %s
+ON CONFLICT (transaction_id, account) DO NOTHING;`,
+		transactionPrefix, entryType, workerSign, amountColumn, poolSign, amountColumn, amountColumn, filter)
+}
+
+// reconcileStakeQuery sets current_stake_amount to the ledger balance of the selected workers where they differ,
+// the stake column is a float so the exact balance is only kept by the ledger
+func reconcileStakeQuery(workerFilter string) string {
+	return fmt.Sprintf(`
+UPDATE "DojoWorker" AS w
+SET current_stake_amount = b.balance, updated_at = NOW()
+FROM (
+  SELECT le.worker_id, SUM(le.amount)::float8 AS balance
+  FROM "LedgerEntry" AS le
+  WHERE le.account IN %s AND le.worker_id IN (%s)
+  GROUP BY le.worker_id
+) AS b
+WHERE w.id = b.worker_id AND w.current_stake_amount IS DISTINCT FROM b.balance;`, workerAccounts, workerFilter)
+}
+
+// AccrueSettledTask posts the rewards and penalties of a settled task to the ledger and reconciles the stakes
+// of its workers. Safe to call more than once, results are only posted once.
+func (o *LedgerORM) AccrueSettledTask(ctx context.Context, taskId string) error {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	filter := "AND tr.task_id = $1"
+	err := o.dbClient.Prisma.Transaction(
+		o.dbClient.Prisma.ExecuteRaw(settledResultEntriesQuery(db.LedgerEntryTypeRewardAccrued, "finalised_reward", filter), taskId).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(settledResultEntriesQuery(db.LedgerEntryTypePenalty, "finalised_loss", filter), taskId).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(reconcileStakeQuery(`SELECT worker_id FROM "TaskResult" WHERE task_id = $1`), taskId).Tx(),
+	).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error posting settled task to ledger")
+		return err
+	}
+
+	workers, err := o.dbClient.DojoWorker.FindMany(
+		db.DojoWorker.TaskResults.Some(db.TaskResult.TaskID.Equals(taskId)),
+	).Exec(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to fetch workers to clear cache")
+		return nil
+	}
+	clearWorkerCaches(workers...)
+	return nil
+}
+
+// AccrueMissingSettlements posts every settled task result that is missing from the ledger, e.g. when the process
+// stopped between settling a task and posting it, then reconciles every worker's stake against the ledger.
+// Returns the number of workers whose stake had to be corrected.
+func (o *LedgerORM) AccrueMissingSettlements(ctx context.Context) (int, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	if _, err := o.dbClient.Prisma.ExecuteRaw(settledResultEntriesQuery(db.LedgerEntryTypeRewardAccrued, "finalised_reward", "")).Exec(ctx); err != nil {
+		log.Error().Err(err).Msg("Error posting missing rewards to ledger")
+		return 0, err
+	}
+	if _, err := o.dbClient.Prisma.ExecuteRaw(settledResultEntriesQuery(db.LedgerEntryTypePenalty, "finalised_loss", "")).Exec(ctx); err != nil {
+		log.Error().Err(err).Msg("Error posting missing penalties to ledger")
+		return 0, err
+	}
+
+	result, err := o.dbClient.Prisma.ExecuteRaw(reconcileStakeQuery(`SELECT DISTINCT worker_id FROM "LedgerEntry"`)).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Error reconciling worker stakes")
+		return 0, err
+	}
+
+	if result.Count > 0 {
+		cache := cache.GetCacheInstance()
+		if err := cache.DeleteByPattern(string(cache.Keys.WorkerByWallet) + ":*"); err != nil {
+			log.Warn().Err(err).Msg("Failed to clear worker cache")
+		}
+	}
+	return result.Count, nil
+}
+
+// SettlePayoutBatch records that every payout of a READY batch was paid, moving each leaf's amount from pending
+// payout to PAID_OUT under the transaction payout-settled:<leaf id>, and marks the batch SETTLED. Settling the same
+// batch again does nothing.
+func (o *LedgerORM) SettlePayoutBatch(ctx context.Context, batchId string) error {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	settlePayoutsQuery := `
+INSERT INTO "LedgerEntry" (id, transaction_id, type, account, amount, worker_id)
+SELECT gen_random_uuid()::text, 'payout-settled:' || l.id, 'PAYOUT_SETTLED'::"LedgerEntryType", leg.account, leg.amount, l.worker_id
+FROM "PayoutBatchLeaf" AS l
+JOIN "PayoutBatch" AS b ON b.id = l.batch_id
+CROSS JOIN LATERAL (VALUES
+  ('WORKER_PENDING_PAYOUT'::"LedgerAccount", -l.amount),
+  ('PAID_OUT'::"LedgerAccount", l.amount)
+) AS leg(account, amount)
+WHERE l.batch_id = $1 AND b.status = 'READY'::"PayoutBatchStatus"
+ON CONFLICT (transaction_id, account) DO NOTHING;`
+	settleBatchQuery := `
+UPDATE "PayoutBatch" SET status = 'SETTLED'::"PayoutBatchStatus", updated_at = NOW()
+WHERE id = $1 AND status = 'READY'::"PayoutBatchStatus";`
+
+	err := o.dbClient.Prisma.Transaction(
+		o.dbClient.Prisma.ExecuteRaw(settlePayoutsQuery, batchId).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(settleBatchQuery, batchId).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(reconcileStakeQuery(`SELECT worker_id FROM "PayoutBatchLeaf" WHERE batch_id = $1`), batchId).Tx(),
+	).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("batchId", batchId).Msg("Error settling payout batch")
+		return err
+	}
+
+	workers, err := o.dbClient.DojoWorker.FindMany(
+		db.DojoWorker.PayoutLeaves.Some(db.PayoutBatchLeaf.BatchID.Equals(batchId)),
+	).Exec(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("batchId", batchId).Msg("Failed to fetch workers to clear cache")
+		return nil
+	}
+	clearWorkerCaches(workers...)
+	return nil
+}
+
+// GetWorkerBalance sums the worker's ledger entries per account
+func (o *LedgerORM) GetWorkerBalance(ctx context.Context, workerId string) (*WorkerBalance, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	var result []struct {
+		Available     db.RawDecimal `json:"available"`
+		PendingPayout db.RawDecimal `json:"pending_payout"`
+		PaidOut       db.RawDecimal `json:"paid_out"`
+	}
+
+	// paid out entries are posted against the worker but belong to the PAID_OUT account
+	query := `
+SELECT
+  COALESCE(SUM(amount) FILTER (WHERE account = 'WORKER_AVAILABLE'::"LedgerAccount"), 0) AS available,
+  COALESCE(SUM(amount) FILTER (WHERE account = 'WORKER_PENDING_PAYOUT'::"LedgerAccount"), 0) AS pending_payout,
+  COALESCE(SUM(amount) FILTER (WHERE account = 'PAID_OUT'::"LedgerAccount"), 0) AS paid_out
+FROM "LedgerEntry"
+WHERE worker_id = $1;`
+	if err := o.dbClient.Prisma.QueryRaw(query, workerId).Exec(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return &WorkerBalance{}, nil
+	}
+	return &WorkerBalance{
+		Available:     result[0].Available.Decimal,
+		PendingPayout: result[0].PendingPayout.Decimal,
+		PaidOut:       result[0].PaidOut.Decimal,
+	}, nil
+}
+
+// GetWorkerEntries returns a page of the entries on the worker's own accounts, newest first, and the total number of them
+func (o *LedgerORM) GetWorkerEntries(ctx context.Context, workerId string, page int, limit int) ([]db.LedgerEntryModel, int, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	filters := []db.LedgerEntryWhereParam{
+		db.LedgerEntry.WorkerID.Equals(workerId),
+		db.LedgerEntry.Account.In([]db.LedgerAccount{db.LedgerAccountWorkerAvailable, db.LedgerAccountWorkerPendingPayout}),
+	}
+
+	entries, err := o.dbClient.LedgerEntry.FindMany(filters...).
+		OrderBy(db.LedgerEntry.CreatedAt.Order(db.SortOrderDesc)).
+		Skip((page - 1) * limit).
+		Take(limit).
+		Exec(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result []struct {
+		Count db.RawString `json:"count"`
+	}
+	query := fmt.Sprintf(`SELECT COUNT(*) AS count FROM "LedgerEntry" WHERE worker_id = $1 AND account IN %s;`, workerAccounts)
+	if err := o.dbClient.Prisma.QueryRaw(query, workerId).Exec(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+	if len(result) == 0 {
+		return entries, 0, nil
+	}
+
+	totalEntries, err := strconv.Atoi(string(result[0].Count))
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, totalEntries, nil
+}
+
+// clearWorkerCaches drops cached workers so that their reconciled stake is visible
+func clearWorkerCaches(workers ...db.DojoWorkerModel) {
+	cache := cache.GetCacheInstance()
+	for _, worker := range workers {
+		if err := cache.DeleteWithSuffix(cache.Keys.WorkerByWallet, worker.WalletAddress); err != nil {
+			log.Warn().Err(err).Str("workerId", worker.ID).Msg("Failed to clear worker cache")
+		}
+	}
+}
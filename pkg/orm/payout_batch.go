@@ -0,0 +1,169 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+
+	"dojo-api/db"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type PayoutBatchORM struct {
+	dbClient      *db.PrismaClient
+	clientWrapper *PrismaClientWrapper
+}
+
+func NewPayoutBatchORM() *PayoutBatchORM {
+	clientWrapper := GetPrismaClient()
+	return &PayoutBatchORM{dbClient: clientWrapper.Client, clientWrapper: clientWrapper}
+}
+
+// PayoutLeafProof is the merkle data of a leaf, set when the batch is finalised
+type PayoutLeafProof struct {
+	LeafId      string
+	AmountUnits string
+	LeafHash    string
+	Proof       db.JSON
+}
+
+// CreatePayoutBatch adds a leaf for every unflagged worker with a positive available balance and moves that balance
+// to pending payout, all in one transaction. Leaf amounts are rounded down to tokenDecimals, the amount paid on chain,
+// so the remainder stays available for a later batch. The batch is PENDING until its merkle tree is built.
+func (o *PayoutBatchORM) CreatePayoutBatch(ctx context.Context, tokenDecimals int) (*db.PayoutBatchModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	batchId := uuid.NewString()
+
+	availableBalances := `
+SELECT worker_id, SUM(amount) AS balance
+FROM "LedgerEntry"
+WHERE account = 'WORKER_AVAILABLE'::"LedgerAccount"
+GROUP BY worker_id
+HAVING SUM(amount) > 0`
+
+	// lock the workers first so that the balances read below cannot be spent concurrently
+	lockWorkersQuery := fmt.Sprintf(`UPDATE "DojoWorker" SET updated_at = updated_at WHERE id IN (SELECT worker_id FROM (%s) AS b);`, availableBalances)
+	createBatchQuery := `INSERT INTO "PayoutBatch" (id, updated_at, status, token_decimals, total_amount) VALUES ($1, NOW(), 'PENDING'::"PayoutBatchStatus", $2, 0);`
+	createLeavesQuery := fmt.Sprintf(`
+INSERT INTO "PayoutBatchLeaf" (id, updated_at, batch_id, worker_id, wallet_address, chain_id, amount)
+SELECT gen_random_uuid()::text, NOW(), $1, w.id, w.wallet_address, w.chain_id, TRUNC(b.balance, $2)
+FROM (%s) AS b
+JOIN "DojoWorker" AS w ON w.id = b.worker_id
+WHERE NOT w.is_flagged AND TRUNC(b.balance, $2) > 0;`, availableBalances)
+	requestPayoutsQuery := `
+INSERT INTO "LedgerEntry" (id, transaction_id, type, account, amount, worker_id)
+SELECT gen_random_uuid()::text, 'payout-requested:' || l.id, 'PAYOUT_REQUESTED'::"LedgerEntryType", leg.account, leg.amount, l.worker_id
+FROM "PayoutBatchLeaf" AS l
+CROSS JOIN LATERAL (VALUES
+  ('WORKER_AVAILABLE'::"LedgerAccount", -l.amount),
+  ('WORKER_PENDING_PAYOUT'::"LedgerAccount", l.amount)
+) AS leg(account, amount)
+WHERE l.batch_id = $1;`
+	updateTotalQuery := `
+UPDATE "PayoutBatch"
+SET total_amount = (SELECT COALESCE(SUM(amount), 0) FROM "PayoutBatchLeaf" WHERE batch_id = $1)
+WHERE id = $1;`
+	// workers that became payable after the lock were not locked, abort rather than overdraw them.
+	// batchId is generated above so it is safe to inline, DO blocks cannot take parameters.
+	overdrawGuardQuery := fmt.Sprintf(`
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1 FROM "LedgerEntry"
+    WHERE account = 'WORKER_AVAILABLE'::"LedgerAccount"
+      AND worker_id IN (SELECT worker_id FROM "PayoutBatchLeaf" WHERE batch_id = '%s')
+    GROUP BY worker_id
+    HAVING SUM(amount) < 0
+  ) THEN
+    RAISE EXCEPTION 'payout batch would overdraw a worker balance';
+  END IF;
+END $$;`, batchId)
+
+	err := o.dbClient.Prisma.Transaction(
+		o.dbClient.Prisma.ExecuteRaw(lockWorkersQuery).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(createBatchQuery, batchId, tokenDecimals).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(createLeavesQuery, batchId, tokenDecimals).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(requestPayoutsQuery, batchId).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(updateTotalQuery, batchId).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(overdrawGuardQuery).Tx(),
+		o.dbClient.Prisma.ExecuteRaw(reconcileStakeQuery(`SELECT worker_id FROM "PayoutBatchLeaf" WHERE batch_id = $1`), batchId).Tx(),
+	).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating payout batch")
+		return nil, err
+	}
+
+	return o.dbClient.PayoutBatch.FindUnique(db.PayoutBatch.ID.Equals(batchId)).Exec(ctx)
+}
+
+// DeleteEmptyBatch removes a batch that has no leaves, batches with leaves have ledger entries and are kept
+func (o *PayoutBatchORM) DeleteEmptyBatch(ctx context.Context, batchId string) error {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	_, err := o.dbClient.Prisma.ExecuteRaw(
+		`DELETE FROM "PayoutBatch" WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM "PayoutBatchLeaf" WHERE batch_id = $1);`,
+		batchId,
+	).Exec(ctx)
+	return err
+}
+
+func (o *PayoutBatchORM) GetBatchById(ctx context.Context, batchId string) (*db.PayoutBatchModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.PayoutBatch.FindUnique(db.PayoutBatch.ID.Equals(batchId)).Exec(ctx)
+}
+
+func (o *PayoutBatchORM) GetBatchLeaves(ctx context.Context, batchId string) ([]db.PayoutBatchLeafModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.PayoutBatchLeaf.FindMany(
+		db.PayoutBatchLeaf.BatchID.Equals(batchId),
+	).Exec(ctx)
+}
+
+func (o *PayoutBatchORM) GetWorkerLeaf(ctx context.Context, batchId string, workerId string) (*db.PayoutBatchLeafModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.PayoutBatchLeaf.FindUnique(
+		db.PayoutBatchLeaf.BatchIDWorkerID(
+			db.PayoutBatchLeaf.BatchID.Equals(batchId),
+			db.PayoutBatchLeaf.WorkerID.Equals(workerId),
+		),
+	).Exec(ctx)
+}
+
+// FinaliseBatch stores the merkle root and every leaf's proof, then marks the batch READY
+func (o *PayoutBatchORM) FinaliseBatch(ctx context.Context, batchId string, merkleRoot string, leafProofs []PayoutLeafProof) error {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	txns := make([]db.PrismaTransaction, 0, len(leafProofs)+1)
+	for _, leafProof := range leafProofs {
+		txns = append(txns, o.dbClient.PayoutBatchLeaf.FindUnique(
+			db.PayoutBatchLeaf.ID.Equals(leafProof.LeafId),
+		).Update(
+			db.PayoutBatchLeaf.AmountUnits.Set(leafProof.AmountUnits),
+			db.PayoutBatchLeaf.LeafHash.Set(leafProof.LeafHash),
+			db.PayoutBatchLeaf.Proof.Set(leafProof.Proof),
+		).Tx())
+	}
+	txns = append(txns, o.dbClient.PayoutBatch.FindUnique(
+		db.PayoutBatch.ID.Equals(batchId),
+	).Update(
+		db.PayoutBatch.MerkleRoot.Set(merkleRoot),
+		db.PayoutBatch.Status.Set(db.PayoutBatchStatusReady),
+	).Tx())
+
+	if err := o.dbClient.Prisma.Transaction(txns...).Exec(ctx); err != nil {
+		log.Error().Err(err).Str("batchId", batchId).Msg("Error finalising payout batch")
+		return err
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package orm
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -45,6 +48,10 @@ func (o *TaskORM) CreateTask(ctx context.Context, task db.InnerTask, minerUserId
 		db.Task.MinerUser.Link(
 			db.MinerUser.ID.Equals(minerUserId),
 		),
+		db.Task.IsGold.Set(task.IsGold),
+		db.Task.GoldAnswers.SetIfPresent(task.GoldAnswers),
+		db.Task.MinReputation.SetIfPresent(task.MinReputation),
+		db.Task.TotalReward.SetIfPresent(task.TotalReward),
 	).Exec(ctx)
 	return createdTask, err
 }
@@ -79,86 +86,321 @@ func (o *TaskORM) GetById(ctx context.Context, taskId string) (*db.TaskModel, er
 	return task, nil
 }
 
-// Modified GetTasksByWorkerSubscription with caching
-func (o *TaskORM) GetTasksByWorkerSubscription(ctx context.Context, workerId string, offset, limit int, sortQuery db.TaskOrderByParam, taskModalities []db.TaskModality) ([]db.TaskModel, int, error) {
-	var tasks []db.TaskModel
-
-	// Cache miss, proceed with database query
+// CancelTask marks an in-progress task owned by the miner as CANCELLED.
+// Returns the number of rows updated, which is 0 if the task was not in progress or not owned by the miner.
+func (o *TaskORM) CancelTask(ctx context.Context, taskId string, minerUserId string) (int, error) {
 	o.clientWrapper.BeforeQuery()
 	defer o.clientWrapper.AfterQuery()
 
-	// Rest of the existing implementation...
-	partners, err := o.dbClient.WorkerPartner.FindMany(
-		db.WorkerPartner.WorkerID.Equals(workerId),
-		db.WorkerPartner.IsDeleteByMiner.Equals(false),
-		db.WorkerPartner.IsDeleteByWorker.Equals(false),
+	result, err := o.dbClient.Task.FindMany(
+		db.Task.ID.Equals(taskId),
+		db.Task.MinerUserID.Equals(minerUserId),
+		db.Task.Status.Equals(db.TaskStatusInProgress),
+	).Update(
+		db.Task.Status.Set(db.TaskStatusCancelled),
+		db.Task.UpdatedAt.Set(time.Now()),
 	).Exec(ctx)
 	if err != nil {
-		log.Error().Err(err).Msgf("Error fetching WorkerPartner by WorkerID for worker ID %v", workerId)
-		return nil, 0, err
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error cancelling task")
+		return 0, err
 	}
 
-	var subscriptionKeys []string
-	for _, partner := range partners {
-		subscriptionKeys = append(subscriptionKeys, partner.MinerSubscriptionKey)
+	// Remove stale task from cache so submissions see the new status
+	cache := cache.GetCacheInstance()
+	if err := cache.DeleteWithSuffix(cache.Keys.TaskById, taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to delete task cache")
 	}
 
-	if len(subscriptionKeys) == 0 {
-		log.Error().Msgf("No subscription keys found for worker ID %v", workerId)
-		return nil, 0, err
-	}
+	return result.Count, nil
+}
 
-	filterParams := []db.TaskWhereParam{
-		db.Task.MinerUser.Where(
-			db.MinerUser.SubscriptionKeys.Some(
-				db.SubscriptionKey.Key.In(subscriptionKeys),
-			),
-		),
-	}
+// GetByIds fetches multiple tasks by their IDs, bypassing the cache
+func (o *TaskORM) GetByIds(ctx context.Context, taskIds []string) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
 
-	if len(taskModalities) > 0 {
-		filterParams = append(filterParams, db.Task.Modality.In(taskModalities))
+	return o.dbClient.Task.FindMany(
+		db.Task.ID.In(taskIds),
+	).Exec(ctx)
+}
+
+// GetRecentCompletedTasksByMiner returns the miner's most recently completed tasks, newest first
+func (o *TaskORM) GetRecentCompletedTasksByMiner(ctx context.Context, minerUserId string, limit int) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Task.FindMany(
+		db.Task.MinerUserID.Equals(minerUserId),
+		db.Task.Status.Equals(db.TaskStatusCompleted),
+	).OrderBy(db.Task.UpdatedAt.Order(db.SortOrderDesc)).
+		Take(limit).
+		Exec(ctx)
+}
+
+// GetMinerCompletedTasksAfter returns up to limit of the miner's COMPLETED tasks created in [from, to), ordered by
+// created_at then id, that come after the (afterCreatedAt, afterId) cursor. An empty afterId starts from the beginning.
+func (o *TaskORM) GetMinerCompletedTasksAfter(ctx context.Context, minerUserId string, from, to time.Time, afterCreatedAt time.Time, afterId string, limit int) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	filters := []db.TaskWhereParam{
+		db.Task.MinerUserID.Equals(minerUserId),
+		db.Task.Status.Equals(db.TaskStatusCompleted),
+		db.Task.CreatedAt.Gte(from),
+		db.Task.CreatedAt.Lt(to),
+	}
+	if afterId != "" {
+		filters = append(filters, db.Task.Or(
+			db.Task.CreatedAt.Gt(afterCreatedAt),
+			db.Task.And(
+				db.Task.CreatedAt.Equals(afterCreatedAt),
+				db.Task.ID.Gt(afterId),
+			),
+		))
 	}
 
-	tasks, err = o.dbClient.Task.FindMany(
-		filterParams...,
-	).OrderBy(sortQuery).
-		Skip(offset).
+	return o.dbClient.Task.FindMany(filters...).
+		OrderBy(
+			db.Task.CreatedAt.Order(db.SortOrderAsc),
+			db.Task.ID.Order(db.SortOrderAsc),
+		).
 		Take(limit).
 		Exec(ctx)
+}
+
+// WorkerTaskFilter narrows the tasks shown to a worker, empty fields are not filtered on and yield bounds are inclusive
+type WorkerTaskFilter struct {
+	Modalities []db.TaskModality
+	// omitted from both the page and the total count, e.g. tasks fully reserved by other workers' leases
+	ExcludeTaskIds []string
+	YieldMin       *float64
+	YieldMax       *float64
+}
+
+// yield is computed rather than stored so it can never disagree with total_reward or max_results,
+// tasks without a reward yield 0
+const taskYieldExpr = "COALESCE(total_reward, 0) / GREATEST(max_results, 1)"
+
+type WorkerTaskSortKey string
+
+const (
+	WorkerTaskSortCreatedAt  WorkerTaskSortKey = "created_at"
+	WorkerTaskSortNumResults WorkerTaskSortKey = "num_results"
+	WorkerTaskSortYield      WorkerTaskSortKey = "yield"
+)
+
+func (k WorkerTaskSortKey) sqlExpr() string {
+	if k == WorkerTaskSortYield {
+		return taskYieldExpr
+	}
+	return string(k)
+}
+
+// WorkerTaskKeyset is the position of the last task of the previous page. Value holds its num_results or yield when
+// tasks are sorted by either of them, ties in the sort key are broken by ID.
+type WorkerTaskKeyset struct {
+	CreatedAt time.Time
+	Value     float64
+	Id        string
+}
+
+// GetTasksByWorkerSubscription returns a page of the tasks of the miners the worker is partnered with, starting after
+// the keyset when it is set and otherwise skipping offset tasks. Offsets are only kept for clients that predate
+// keysets, as they get slower the further they go and shift while tasks are created. The page is selected with a raw
+// query as the prisma go client cannot order by a computed yield, ties in the sort key are broken by ID so pages never
+// overlap.
+// Tasks requiring a higher reputation than the worker's are omitted.
+func (o *TaskORM) GetTasksByWorkerSubscription(ctx context.Context, workerId string, filter WorkerTaskFilter, sortKey WorkerTaskSortKey, order db.SortOrder, after *WorkerTaskKeyset, offset, limit int) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	subscriptionKeys, worker, err := o.getWorkerSubscription(ctx, workerId)
+	if err != nil || len(subscriptionKeys) == 0 {
+		return nil, err
+	}
+
+	where, err := workerTasksWhere(subscriptionKeys, worker.Reputation, filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Error building worker task filters")
+		return nil, err
+	}
+
+	direction, comparison := "DESC", "<"
+	if order == db.SortOrderAsc {
+		direction, comparison = "ASC", ">"
+	}
+	if after != nil {
+		// row comparison so tasks tied on the sort key continue from the keyset's ID
+		var value interface{} = after.Value
+		switch sortKey {
+		case WorkerTaskSortCreatedAt:
+			value = sq.Expr("?::timestamp", after.CreatedAt.UTC())
+		case WorkerTaskSortNumResults:
+			value = int(after.Value)
+		}
+		where = append(where, sq.Expr(fmt.Sprintf("(%s, id) %s (?, ?)", sortKey.sqlExpr(), comparison), value, after.Id))
+		offset = 0
+	}
+
+	query := sq.Select("id").
+		From("\"Task\"").
+		Where(where).
+		OrderBy(sortKey.sqlExpr()+" "+direction, "id "+direction).
+		Offset(uint64(offset)).
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	tasks, err := o.getTasksByIdQuery(ctx, query)
 	if err != nil {
 		log.Error().Err(err).Msgf("Error fetching tasks for worker ID %v", workerId)
-		return nil, 0, err
+		return nil, err
 	}
+	return tasks, nil
+}
+
+// CountTasksByWorkerSubscription counts the tasks GetTasksByWorkerSubscription pages through. Counting is the slowest
+// part of listing tasks, so counts are cached briefly per worker and filter. Excluded tasks change with every request,
+// so they are left out of the cache key and subtracted from the cached count instead.
+func (o *TaskORM) CountTasksByWorkerSubscription(ctx context.Context, workerId string, filter WorkerTaskFilter) (int, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
 
-	totalTasks, err := o.countTasksByWorkerSubscription(ctx, taskModalities, subscriptionKeys)
+	subscriptionKeys, worker, err := o.getWorkerSubscription(ctx, workerId)
+	if err != nil || len(subscriptionKeys) == 0 {
+		return 0, err
+	}
+
+	excludeTaskIds := filter.ExcludeTaskIds
+	filter.ExcludeTaskIds = nil
+	where, err := workerTasksWhere(subscriptionKeys, worker.Reputation, filter)
 	if err != nil {
-		log.Error().Err(err).Msgf("Error fetching total tasks for worker ID %v", workerId)
-		return nil, 0, err
+		log.Error().Err(err).Msg("Error building worker task filters")
+		return 0, err
 	}
 
-	log.Info().Int("totalTasks", totalTasks).Msgf("Successfully fetched total tasks fetched for worker ID %v", workerId)
+	cache := cache.GetCacheInstance()
+	cacheKey := cache.BuildCacheKey(cache.Keys.TasksCountByWorker, workerId, hashWorkerTaskFilter(filter))
+
+	totalTasks := -1
+	if cached, err := cache.Get(cacheKey); err == nil {
+		if count, err := strconv.Atoi(cached); err == nil {
+			totalTasks = count
+		}
+	}
+	if totalTasks < 0 {
+		totalTasks, err = o.countTasksByWorkerSubscription(ctx, where)
+		if err != nil {
+			log.Error().Err(err).Msgf("Error fetching total tasks for worker ID %v", workerId)
+			return 0, err
+		}
+		if err := cache.SetWithExpire(cacheKey, strconv.Itoa(totalTasks), cache.GetCacheExpiration(cache.Keys.TasksCountByWorker)); err != nil {
+			log.Warn().Err(err).Msg("Failed to set worker task count cache")
+		}
+	}
 
-	return tasks, totalTasks, nil
+	if len(excludeTaskIds) > 0 {
+		excludedTasks, err := o.countTasksByWorkerSubscription(ctx, append(where, sq.Eq{"id": excludeTaskIds}))
+		if err != nil {
+			log.Error().Err(err).Msgf("Error fetching excluded tasks for worker ID %v", workerId)
+			return 0, err
+		}
+		totalTasks = max(totalTasks-excludedTasks, 0)
+	}
+	return totalTasks, nil
 }
 
-// This function uses raw queries to calculate count(*) since this functionality is missing from the prisma go client
-// and using findMany with the filter params and then len(tasks) is facing performance issues
-func (o *TaskORM) countTasksByWorkerSubscription(ctx context.Context, taskModalities []db.TaskModality, subscriptionKeys []string) (int, error) {
-	var taskModalitiesParam []string
-	for _, taskModality := range taskModalities {
-		taskModalitiesParam = append(taskModalitiesParam, string(taskModality))
+// hashWorkerTaskFilter identifies a filter in cache keys regardless of the order of its modalities
+func hashWorkerTaskFilter(filter WorkerTaskFilter) string {
+	modalities := make([]string, 0, len(filter.Modalities))
+	for _, modality := range filter.Modalities {
+		modalities = append(modalities, string(modality))
 	}
+	slices.Sort(modalities)
 
-	validSubscriptionKeys := make([]string, 0)
-	for _, key := range subscriptionKeys {
-		if !strings.HasPrefix(key, "sk-") || len(key[3:]) != 32 {
-			continue
+	bound := func(value *float64) string {
+		if value == nil {
+			return ""
 		}
-		// nolint:staticcheck
-		validSubscriptionKeys = append(validSubscriptionKeys, key)
+		return strconv.FormatFloat(*value, 'g', -1, 64)
+	}
+	key := strings.Join(modalities, ",") + "|" + bound(filter.YieldMin) + "|" + bound(filter.YieldMax)
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:16])
+}
+
+// getTasksByIdQuery runs a raw query selecting task IDs and returns the tasks in the order of the query
+func (o *TaskORM) getTasksByIdQuery(ctx context.Context, query sq.SelectBuilder) ([]db.TaskModel, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID db.RawString `json:"id"`
+	}
+	if err := o.clientWrapper.Client.Prisma.QueryRaw(sql, args...).Exec(ctx, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []db.TaskModel{}, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, string(row.ID))
+	}
+	found, err := o.dbClient.Task.FindMany(db.Task.ID.In(ids)).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	foundById := make(map[string]db.TaskModel, len(found))
+	for _, task := range found {
+		foundById[task.ID] = task
+	}
+	tasks := make([]db.TaskModel, 0, len(ids))
+	for _, id := range ids {
+		if task, ok := foundById[id]; ok {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// getWorkerSubscription returns the subscription keys of the worker's active partners along with the worker,
+// no keys and no error when the worker has no partners
+func (o *TaskORM) getWorkerSubscription(ctx context.Context, workerId string) ([]string, *db.DojoWorkerModel, error) {
+	partners, err := o.dbClient.WorkerPartner.FindMany(
+		db.WorkerPartner.WorkerID.Equals(workerId),
+		db.WorkerPartner.IsDeleteByMiner.Equals(false),
+		db.WorkerPartner.IsDeleteByWorker.Equals(false),
+	).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error fetching WorkerPartner by WorkerID for worker ID %v", workerId)
+		return nil, nil, err
+	}
+
+	var subscriptionKeys []string
+	for _, partner := range partners {
+		subscriptionKeys = append(subscriptionKeys, partner.MinerSubscriptionKey)
+	}
+
+	if len(subscriptionKeys) == 0 {
+		log.Error().Msgf("No subscription keys found for worker ID %v", workerId)
+		return nil, nil, nil
+	}
+
+	worker, err := o.dbClient.DojoWorker.FindUnique(db.DojoWorker.ID.Equals(workerId)).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error fetching worker for worker ID %v", workerId)
+		return nil, nil, err
 	}
+	return subscriptionKeys, worker, nil
+}
 
+// workerTasksWhere matches the tasks shown to a worker, the tasks of the miners behind its subscription keys that are
+// not cancelled and do not require a higher reputation, narrowed by the filter
+func workerTasksWhere(subscriptionKeys []string, workerReputation float64, filter WorkerTaskFilter) (sq.And, error) {
 	// need to set subquery to use "$?" and let the main query use dollar to resolve placeholders
 	subQuery, subQueryArgs, err := sq.Select("miner_user_id").
 		From("\"SubscriptionKey\"").
@@ -166,18 +408,47 @@ func (o *TaskORM) countTasksByWorkerSubscription(ctx context.Context, taskModali
 		PlaceholderFormat(sq.Question).
 		ToSql()
 	if err != nil {
-		log.Error().Err(err).Msg("Error building subquery")
-		return 0, err
+		return nil, err
 	}
 
-	mainQuery := sq.Select("count(*) as total_tasks").
-		From("\"Task\"").
-		Where(sq.Expr(fmt.Sprintf("miner_user_id IN (%s)", subQuery), subQueryArgs...)).
-		// need to do this since TaskModality is a custom prisma enum type
-		Where(sq.Expr(fmt.Sprintf("modality in ('%s')", strings.Join(taskModalitiesParam, "', '")))).
-		PlaceholderFormat(sq.Dollar)
+	where := sq.And{
+		sq.Expr(fmt.Sprintf("miner_user_id IN (%s)", subQuery), subQueryArgs...),
+		// compare as text since TaskStatus and TaskModality are custom prisma enum types
+		sq.NotEq{"status::text": string(db.TaskStatusCancelled)},
+		sq.Or{sq.Eq{"min_reputation": nil}, sq.LtOrEq{"min_reputation": workerReputation}},
+	}
 
-	sql, args, err := mainQuery.ToSql()
+	if len(filter.Modalities) > 0 {
+		modalities := make([]string, 0, len(filter.Modalities))
+		for _, modality := range filter.Modalities {
+			modalities = append(modalities, string(modality))
+		}
+		where = append(where, sq.Eq{"modality::text": modalities})
+	}
+
+	if len(filter.ExcludeTaskIds) > 0 {
+		where = append(where, sq.NotEq{"id": filter.ExcludeTaskIds})
+	}
+
+	if filter.YieldMin != nil {
+		where = append(where, sq.Expr(taskYieldExpr+" >= ?", *filter.YieldMin))
+	}
+
+	if filter.YieldMax != nil {
+		where = append(where, sq.Expr(taskYieldExpr+" <= ?", *filter.YieldMax))
+	}
+
+	return where, nil
+}
+
+// This function uses raw queries to calculate count(*) since this functionality is missing from the prisma go client
+// and using findMany with the filter params and then len(tasks) is facing performance issues
+func (o *TaskORM) countTasksByWorkerSubscription(ctx context.Context, where sq.Sqlizer) (int, error) {
+	sql, args, err := sq.Select("count(*) as total_tasks").
+		From("\"Task\"").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
 	if err != nil {
 		log.Error().Err(err).Msg("Error building full SQL query")
 		return 0, err
@@ -214,8 +485,126 @@ func (o *TaskORM) countTasksByWorkerSubscription(ctx context.Context, taskModali
 	return totalTasks, nil
 }
 
-// Check every 10 mins for expired tasks
-func (o *TaskORM) UpdateExpiredTasks(ctx context.Context) {
+// MinerTaskFilter narrows a miner's task listing, empty fields are not filtered on and windows are [from, to)
+type MinerTaskFilter struct {
+	Statuses    []db.TaskStatus
+	Modalities  []db.TaskModality
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	ExpireFrom  *time.Time
+	ExpireTo    *time.Time
+}
+
+// GetMinerTasksBefore returns up to limit of the miner's tasks matching the filter, newest first, that come after
+// the (beforeCreatedAt, beforeId) cursor in that order. An empty beforeId starts from the newest task.
+func (o *TaskORM) GetMinerTasksBefore(ctx context.Context, minerUserId string, filter MinerTaskFilter, beforeCreatedAt time.Time, beforeId string, limit int) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	filters := []db.TaskWhereParam{db.Task.MinerUserID.Equals(minerUserId)}
+	if len(filter.Statuses) > 0 {
+		filters = append(filters, db.Task.Status.In(filter.Statuses))
+	}
+	if len(filter.Modalities) > 0 {
+		filters = append(filters, db.Task.Modality.In(filter.Modalities))
+	}
+	if filter.CreatedFrom != nil {
+		filters = append(filters, db.Task.CreatedAt.Gte(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		filters = append(filters, db.Task.CreatedAt.Lt(*filter.CreatedTo))
+	}
+	if filter.ExpireFrom != nil {
+		filters = append(filters, db.Task.ExpireAt.Gte(*filter.ExpireFrom))
+	}
+	if filter.ExpireTo != nil {
+		filters = append(filters, db.Task.ExpireAt.Lt(*filter.ExpireTo))
+	}
+	if beforeId != "" {
+		filters = append(filters, db.Task.Or(
+			db.Task.CreatedAt.Lt(beforeCreatedAt),
+			db.Task.And(
+				db.Task.CreatedAt.Equals(beforeCreatedAt),
+				db.Task.ID.Lt(beforeId),
+			),
+		))
+	}
+
+	return o.dbClient.Task.FindMany(filters...).
+		OrderBy(
+			db.Task.CreatedAt.Order(db.SortOrderDesc),
+			db.Task.ID.Order(db.SortOrderDesc),
+		).
+		Take(limit).
+		Exec(ctx)
+}
+
+// CountMinerTasksByStatus counts the miner's tasks matching every filter but the status filter, per status, so a
+// dashboard can show the count of each status while one of them is selected. Statuses without tasks are omitted.
+// Like countTasksByWorkerSubscription it uses a raw query, as count(*) is missing from the prisma go client.
+func (o *TaskORM) CountMinerTasksByStatus(ctx context.Context, minerUserId string, filter MinerTaskFilter) (map[db.TaskStatus]int, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	query := sq.Select("status::text AS status", "count(*) AS total_tasks").
+		From("\"Task\"").
+		Where(sq.Eq{"miner_user_id": minerUserId}).
+		GroupBy("status").
+		PlaceholderFormat(sq.Dollar)
+
+	if len(filter.Modalities) > 0 {
+		modalities := make([]string, 0, len(filter.Modalities))
+		for _, modality := range filter.Modalities {
+			modalities = append(modalities, string(modality))
+		}
+		// compare as text since TaskModality is a custom prisma enum type
+		query = query.Where(sq.Eq{"modality::text": modalities})
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where(sq.Expr("created_at >= ?::timestamp", filter.CreatedFrom.UTC()))
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where(sq.Expr("created_at < ?::timestamp", filter.CreatedTo.UTC()))
+	}
+	if filter.ExpireFrom != nil {
+		query = query.Where(sq.Expr("expire_at >= ?::timestamp", filter.ExpireFrom.UTC()))
+	}
+	if filter.ExpireTo != nil {
+		query = query.Where(sq.Expr("expire_at < ?::timestamp", filter.ExpireTo.UTC()))
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		log.Error().Err(err).Msg("Error building miner task count query")
+		return nil, err
+	}
+
+	var res []struct {
+		Status     db.RawString `json:"status"`
+		TotalTasks db.RawString `json:"total_tasks"`
+	}
+	if err := o.clientWrapper.Client.Prisma.QueryRaw(sql, args...).Exec(ctx, &res); err != nil {
+		log.Error().Err(err).Str("minerUserId", minerUserId).Msg("Error executing raw query for miner task counts")
+		return nil, err
+	}
+
+	counts := make(map[db.TaskStatus]int, len(res))
+	for _, row := range res {
+		count, err := strconv.Atoi(string(row.TotalTasks))
+		if err != nil {
+			log.Error().Err(err).Msg("Error converting miner task count to integer")
+			return nil, err
+		}
+		counts[db.TaskStatus(row.Status)] = count
+	}
+	return counts, nil
+}
+
+// ExpiredTasksHook is called with every batch of tasks marked as EXPIRED, with their updated status
+type ExpiredTasksHook func(ctx context.Context, tasks []db.TaskModel)
+
+// Check every 10 mins for expired tasks, onExpired is called with every batch of tasks marked as EXPIRED unless nil
+func (o *TaskORM) UpdateExpiredTasks(ctx context.Context, onExpired ExpiredTasksHook) {
 	for range time.Tick(10 * time.Minute) {
 		log.Info().Msg("Checking for expired tasks")
 		o.clientWrapper.BeforeQuery()
@@ -262,6 +651,13 @@ func (o *TaskORM) UpdateExpiredTasks(ctx context.Context) {
 			}
 
 			log.Info().Msgf("Updated %v expired tasks in batch %d", len(taskIDs), batchNumber)
+
+			for i := range expiredTasks {
+				expiredTasks[i].Status = db.TaskStatusExpired
+			}
+			if onExpired != nil {
+				onExpired(ctx, expiredTasks)
+			}
 		}
 
 		updateDuration := time.Since(startTime)
@@ -297,7 +693,9 @@ func (o *TaskORM) GetCompletedTaskCount(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (o *TaskORM) GetNextInProgressTask(ctx context.Context, taskId string, workerId string) (*db.TaskModel, error) {
+// GetNextInProgressTask finds the next in-progress task after taskId that the worker has not completed,
+// skipping any excludeTaskIds, e.g. tasks fully reserved by other workers' leases
+func (o *TaskORM) GetNextInProgressTask(ctx context.Context, taskId string, workerId string, excludeTaskIds []string) (*db.TaskModel, error) {
 	o.clientWrapper.BeforeQuery()
 	defer o.clientWrapper.AfterQuery()
 
@@ -343,9 +741,22 @@ func (o *TaskORM) GetNextInProgressTask(ctx context.Context, taskId string, work
 		),
 	)
 
+	// Define a filter to exclude tasks that have no slots left for the worker
+	excludedTaskFilter := db.Task.ID.NotIn(excludeTaskIds)
+
+	worker, err := o.dbClient.DojoWorker.FindUnique(db.DojoWorker.ID.Equals(workerId)).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Define a filter to exclude tasks requiring a higher reputation than the worker's
+	reputationFilter := minReputationFilter(worker.Reputation)
+
 	filterParams := []db.TaskWhereParam{
 		noCompletedTaskResults,
 		subscriptionKeyFilter,
+		excludedTaskFilter,
+		reputationFilter,
 		db.Task.CreatedAt.Gt(currentTask.CreatedAt), // Fetch task created after the current task
 		db.Task.Status.Equals(db.TaskStatusInProgress),
 	}
@@ -360,6 +771,8 @@ func (o *TaskORM) GetNextInProgressTask(ctx context.Context, taskId string, work
 			nextTask, err = o.dbClient.Task.FindFirst(
 				noCompletedTaskResults,
 				subscriptionKeyFilter,
+				excludedTaskFilter,
+				reputationFilter,
 				db.Task.Status.Equals(db.TaskStatusInProgress),
 			).OrderBy(db.Task.CreatedAt.Order(db.SortOrderAsc)).Exec(ctx) // Fetch task with the earliest CreatedAt timestamp
 			if err != nil {
@@ -373,6 +786,74 @@ func (o *TaskORM) GetNextInProgressTask(ctx context.Context, taskId string, work
 	return nextTask, nil
 }
 
+// GetUnsettledTasks returns tasks that have finished, either COMPLETED or EXPIRED, but have not been settled yet
+func (o *TaskORM) GetUnsettledTasks(ctx context.Context, limit int) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Task.FindMany(
+		db.Task.Status.In([]db.TaskStatus{db.TaskStatusCompleted, db.TaskStatusExpired}),
+		db.Task.SettledAt.IsNull(),
+	).OrderBy(db.Task.UpdatedAt.Order(db.SortOrderAsc)).
+		Take(limit).
+		Exec(ctx)
+}
+
+// SetResultsHash records the hash over a COMPLETED task's results, a hash that is already set is never replaced
+func (o *TaskORM) SetResultsHash(ctx context.Context, taskId string, resultsHash string) (bool, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	query := `
+UPDATE "Task"
+SET results_hash = $2
+WHERE id = $1 AND status = 'COMPLETED'::"TaskStatus" AND results_hash IS NULL;`
+	result, err := o.dbClient.Prisma.ExecuteRaw(query, taskId, resultsHash).Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	cache := cache.GetCacheInstance()
+	if err := cache.DeleteWithSuffix(cache.Keys.TaskById, taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to delete task cache")
+	}
+	return result.Count > 0, nil
+}
+
+// GetUnhashedCompletedTasks returns COMPLETED tasks whose results have not been hashed yet, oldest first
+func (o *TaskORM) GetUnhashedCompletedTasks(ctx context.Context, limit int) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Task.FindMany(
+		db.Task.Status.Equals(db.TaskStatusCompleted),
+		db.Task.ResultsHash.IsNull(),
+	).OrderBy(db.Task.UpdatedAt.Order(db.SortOrderAsc)).
+		Take(limit).
+		Exec(ctx)
+}
+
+// GetUnbatchedHashedTasks returns tasks with a results hash that are not in a commitment batch yet, oldest first
+func (o *TaskORM) GetUnbatchedHashedTasks(ctx context.Context, limit int) ([]db.TaskModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Task.FindMany(
+		db.Task.Not(db.Task.ResultsHash.IsNull()),
+		db.Task.CommitmentBatchID.IsNull(),
+	).OrderBy(db.Task.UpdatedAt.Order(db.SortOrderAsc)).
+		Take(limit).
+		Exec(ctx)
+}
+
+// minReputationFilter matches tasks without a minimum reputation, or one the worker's reputation meets
+func minReputationFilter(workerReputation float64) db.TaskWhereParam {
+	return db.Task.Or(
+		db.Task.MinReputation.IsNull(),
+		db.Task.MinReputation.Lte(workerReputation),
+	)
+}
+
 // GetCompletedTasksCountByIntervals efficiently fetches task counts for multiple intervals in a single query
 func (o *TaskORM) GetCompletedTasksCountByIntervals(ctx context.Context, fromUnix, toUnix int64, intervalDays int) ([]struct {
 	IntervalEnd int64 `json:"interval_end"`
@@ -0,0 +1,82 @@
+package orm
+
+import (
+	"context"
+	"time"
+
+	"dojo-api/db"
+)
+
+type TaskDraftORM struct {
+	dbClient      *db.PrismaClient
+	clientWrapper *PrismaClientWrapper
+}
+
+func NewTaskDraftORM() *TaskDraftORM {
+	clientWrapper := GetPrismaClient()
+	return &TaskDraftORM{dbClient: clientWrapper.Client, clientWrapper: clientWrapper}
+}
+
+// SaveDraft creates or replaces the worker's draft of a task
+func (o *TaskDraftORM) SaveDraft(ctx context.Context, taskId string, workerId string, resultData db.JSON, expireAt time.Time) (*db.TaskDraftModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.TaskDraft.UpsertOne(
+		db.TaskDraft.TaskIDWorkerID(
+			db.TaskDraft.TaskID.Equals(taskId),
+			db.TaskDraft.WorkerID.Equals(workerId),
+		),
+	).Create(
+		db.TaskDraft.Task.Link(db.Task.ID.Equals(taskId)),
+		db.TaskDraft.DojoWorker.Link(db.DojoWorker.ID.Equals(workerId)),
+		db.TaskDraft.ResultData.Set(resultData),
+		db.TaskDraft.ExpireAt.Set(expireAt),
+	).Update(
+		db.TaskDraft.ResultData.Set(resultData),
+		db.TaskDraft.ExpireAt.Set(expireAt),
+	).Exec(ctx)
+}
+
+// GetDraft returns the worker's draft of a task, expired drafts are treated as not found
+func (o *TaskDraftORM) GetDraft(ctx context.Context, taskId string, workerId string) (*db.TaskDraftModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.TaskDraft.FindFirst(
+		db.TaskDraft.TaskID.Equals(taskId),
+		db.TaskDraft.WorkerID.Equals(workerId),
+		db.TaskDraft.ExpireAt.Gt(time.Now()),
+	).Exec(ctx)
+}
+
+// DeleteDraft removes the worker's draft of a task, returns false if there was none
+func (o *TaskDraftORM) DeleteDraft(ctx context.Context, taskId string, workerId string) (bool, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	result, err := o.dbClient.TaskDraft.FindMany(
+		db.TaskDraft.TaskID.Equals(taskId),
+		db.TaskDraft.WorkerID.Equals(workerId),
+	).Delete().Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	return result.Count > 0, nil
+}
+
+// DeleteStaleDrafts removes drafts that have expired or whose task is no longer in progress
+func (o *TaskDraftORM) DeleteStaleDrafts(ctx context.Context) (int, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	query := `
+DELETE FROM "TaskDraft" AS d
+USING "Task" AS t
+WHERE t.id = d.task_id AND (d.expire_at <= NOW() OR t.expire_at <= NOW() OR t.status <> 'IN_PROGRESS'::"TaskStatus");`
+	result, err := o.dbClient.Prisma.ExecuteRaw(query).Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
@@ -0,0 +1,128 @@
+package orm
+
+import (
+	"context"
+	"strconv"
+
+	"dojo-api/db"
+)
+
+type TaskOpenORM struct {
+	dbClient      *db.PrismaClient
+	clientWrapper *PrismaClientWrapper
+}
+
+func NewTaskOpenORM() *TaskOpenORM {
+	clientWrapper := GetPrismaClient()
+	return &TaskOpenORM{dbClient: clientWrapper.Client, clientWrapper: clientWrapper}
+}
+
+// RecordOpen stores when the worker first opened the task, later opens are ignored
+func (o *TaskOpenORM) RecordOpen(ctx context.Context, taskId string, workerId string) error {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	query := `
+INSERT INTO "TaskOpen" (id, task_id, worker_id)
+VALUES (gen_random_uuid()::text, $1, $2)
+ON CONFLICT (task_id, worker_id) DO NOTHING;`
+	_, err := o.dbClient.Prisma.ExecuteRaw(query, taskId, workerId).Exec(ctx)
+	return err
+}
+
+func (o *TaskOpenORM) GetOpen(ctx context.Context, taskId string, workerId string) (*db.TaskOpenModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.TaskOpen.FindUnique(
+		db.TaskOpen.TaskIDWorkerID(
+			db.TaskOpen.TaskID.Equals(taskId),
+			db.TaskOpen.WorkerID.Equals(workerId),
+		),
+	).Exec(ctx)
+}
+
+// TimeOnTaskStats summarises the time on task of COMPLETED results of one modality
+type TimeOnTaskStats struct {
+	Modality           db.TaskModality
+	NumResults         int
+	AverageSeconds     float64
+	MedianSeconds      float64
+	P90Seconds         float64
+	NumFastSubmissions int
+	// results submitted without the task ever being opened or claimed, they count as fast submissions
+	NumUntimedResults int
+}
+
+// GetTimeOnTaskStats returns the time on task of COMPLETED results per modality, of every worker if workerId is empty
+func (o *TaskOpenORM) GetTimeOnTaskStats(ctx context.Context, workerId string) ([]TimeOnTaskStats, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	var result []struct {
+		Modality           db.RawString `json:"modality"`
+		NumResults         db.RawString `json:"num_results"`
+		AverageSeconds     db.RawString `json:"average_seconds"`
+		MedianSeconds      db.RawString `json:"median_seconds"`
+		P90Seconds         db.RawString `json:"p90_seconds"`
+		NumFastSubmissions db.RawString `json:"num_fast_submissions"`
+		NumUntimedResults  db.RawString `json:"num_untimed_results"`
+	}
+
+	query := `
+SELECT
+  t.modality::text AS modality,
+  COUNT(tr.time_on_task_seconds)::text AS num_results,
+  COALESCE(AVG(tr.time_on_task_seconds), 0)::text AS average_seconds,
+  COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY tr.time_on_task_seconds), 0)::text AS median_seconds,
+  COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY tr.time_on_task_seconds), 0)::text AS p90_seconds,
+  COUNT(*) FILTER (WHERE tr.is_fast_submission)::text AS num_fast_submissions,
+  COUNT(*) FILTER (WHERE tr.time_on_task_seconds IS NULL)::text AS num_untimed_results
+FROM "TaskResult" AS tr
+JOIN "Task" AS t ON t.id = tr.task_id
+WHERE tr.status = 'COMPLETED'::"TaskResultStatus"
+  AND ($1 = '' OR tr.worker_id = $1)
+GROUP BY t.modality
+ORDER BY t.modality;`
+	if err := o.dbClient.Prisma.QueryRaw(query, workerId).Exec(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	stats := make([]TimeOnTaskStats, 0, len(result))
+	for _, row := range result {
+		numResults, err := strconv.Atoi(string(row.NumResults))
+		if err != nil {
+			return nil, err
+		}
+		numFast, err := strconv.Atoi(string(row.NumFastSubmissions))
+		if err != nil {
+			return nil, err
+		}
+		numUntimed, err := strconv.Atoi(string(row.NumUntimedResults))
+		if err != nil {
+			return nil, err
+		}
+		average, err := strconv.ParseFloat(string(row.AverageSeconds), 64)
+		if err != nil {
+			return nil, err
+		}
+		median, err := strconv.ParseFloat(string(row.MedianSeconds), 64)
+		if err != nil {
+			return nil, err
+		}
+		p90, err := strconv.ParseFloat(string(row.P90Seconds), 64)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, TimeOnTaskStats{
+			Modality:           db.TaskModality(row.Modality),
+			NumResults:         numResults,
+			AverageSeconds:     average,
+			MedianSeconds:      median,
+			P90Seconds:         p90,
+			NumFastSubmissions: numFast,
+			NumUntimedResults:  numUntimed,
+		})
+	}
+	return stats, nil
+}
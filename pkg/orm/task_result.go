@@ -2,16 +2,34 @@ package orm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"dojo-api/db"
 	"dojo-api/pkg/cache"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
+// ErrMaxResultsReached is returned when a completed result cannot be recorded
+// because the task is already full or no longer in progress
+var ErrMaxResultsReached = errors.New("task has reached max results")
+
+// ErrSlotsReserved is returned when the task's remaining slots are reserved by other workers' leases
+var ErrSlotsReserved = errors.New("remaining task slots are reserved by leases")
+
+// ErrTaskResultNotRevisable is returned when the worker has no COMPLETED result to revise
+// or the task is no longer in progress
+var ErrTaskResultNotRevisable = errors.New("task result can only be revised while the task is in progress")
+
+// ErrTaskResultRevisionConflict is returned when another revision of the same result took the revision number
+var ErrTaskResultRevisionConflict = errors.New("task result is being revised by another request")
+
 type TaskResultORM struct {
 	client        *db.PrismaClient
 	clientWrapper *PrismaClientWrapper
@@ -25,13 +43,14 @@ func NewTaskResultORM() *TaskResultORM {
 	}
 }
 
-// In a transaction creates the TaskResult and updates the Task.NumResults
-func (t *TaskResultORM) CreateTaskResult(ctx context.Context, taskResult *db.InnerTaskResult) (*db.TaskResultModel, error) {
+// In a transaction creates the TaskResult and updates the Task.NumResults, leaseHolders are the other workers
+// holding an active lease on the task
+func (t *TaskResultORM) CreateTaskResult(ctx context.Context, taskResult *db.InnerTaskResult, leaseHolders []string) (*db.TaskResultModel, error) {
 	switch taskResult.Status {
 	case db.TaskResultStatusInvalid:
 		return t.CreateTaskResultWithInvalid(ctx, taskResult)
 	case db.TaskResultStatusCompleted:
-		return t.CreateTaskResultWithCompleted(ctx, taskResult)
+		return t.CreateTaskResultWithCompleted(ctx, taskResult, leaseHolders)
 	default:
 		return nil, fmt.Errorf("unsupported status: %v", taskResult.Status)
 	}
@@ -60,6 +79,38 @@ func (t *TaskResultORM) GetCompletedTResultByTaskAndWorker(ctx context.Context,
 	return results, nil
 }
 
+func (t *TaskResultORM) GetCompletedTResultByTaskId(ctx context.Context, taskId string) ([]db.TaskResultModel, error) {
+	t.clientWrapper.BeforeQuery()
+	defer t.clientWrapper.AfterQuery()
+
+	return t.client.TaskResult.FindMany(
+		db.TaskResult.TaskID.Equals(taskId),
+		db.TaskResult.Status.Equals(db.TaskResultStatusCompleted),
+	).Exec(ctx)
+}
+
+func (t *TaskResultORM) GetCompletedTResultByTaskIds(ctx context.Context, taskIds []string) ([]db.TaskResultModel, error) {
+	t.clientWrapper.BeforeQuery()
+	defer t.clientWrapper.AfterQuery()
+
+	return t.client.TaskResult.FindMany(
+		db.TaskResult.TaskID.In(taskIds),
+		db.TaskResult.Status.Equals(db.TaskResultStatusCompleted),
+	).Exec(ctx)
+}
+
+// GetRecentTResultsByWorker returns the worker's latest task results of any status, newest first
+func (t *TaskResultORM) GetRecentTResultsByWorker(ctx context.Context, workerId string, limit int) ([]db.TaskResultModel, error) {
+	t.clientWrapper.BeforeQuery()
+	defer t.clientWrapper.AfterQuery()
+
+	return t.client.TaskResult.FindMany(
+		db.TaskResult.WorkerID.Equals(workerId),
+	).OrderBy(db.TaskResult.CreatedAt.Order(db.SortOrderDesc)).
+		Take(limit).
+		Exec(ctx)
+}
+
 func (t *TaskResultORM) GetCompletedTResultByWorker(ctx context.Context, workerId string) ([]db.TaskResultModel, error) {
 	var results []db.TaskResultModel
 	cache := cache.GetCacheInstance()
@@ -103,6 +154,12 @@ func (t *TaskResultORM) CreateTaskResultWithInvalid(ctx context.Context, taskRes
 		db.TaskResult.DojoWorker.Link(
 			db.DojoWorker.ID.Equals(taskResult.WorkerID),
 		),
+		db.TaskResult.Signature.SetIfPresent(taskResult.Signature),
+		db.TaskResult.SignatureScheme.SetIfPresent(taskResult.SignatureScheme),
+		db.TaskResult.SignedResultData.SetIfPresent(taskResult.SignedResultData),
+		db.TaskResult.OpenedAt.SetIfPresent(taskResult.OpenedAt),
+		db.TaskResult.TimeOnTaskSeconds.SetIfPresent(taskResult.TimeOnTaskSeconds),
+		db.TaskResult.IsFastSubmission.Set(taskResult.IsFastSubmission),
 	).With(
 		db.TaskResult.Task.Fetch(),
 	).Exec(ctx)
@@ -112,45 +169,272 @@ func (t *TaskResultORM) CreateTaskResultWithInvalid(ctx context.Context, taskRes
 	return createdTaskResult, nil
 }
 
-func (t *TaskResultORM) CreateTaskResultWithCompleted(ctx context.Context, taskResult *db.InnerTaskResult) (*db.TaskResultModel, error) {
+// CreateTaskResultWithCompleted creates a COMPLETED TaskResult and increments Task.NumResults.
+// The increment is guarded by an IN_PROGRESS status and `num_results + reserved < max_results` within a single
+// statement, where reserved is the number of leaseHolders, the other workers holding an active lease on the task,
+// that have not submitted a result yet. The task row is locked by a first statement, so the insert reads the results
+// committed by concurrent submissions and can never overshoot max_results, skip the COMPLETED transition or take
+// a slot reserved by a lease.
+// Returns ErrSlotsReserved if the remaining slots are leased by other workers, and ErrMaxResultsReached if the task
+// was already full or no longer in progress.
+func (t *TaskResultORM) CreateTaskResultWithCompleted(ctx context.Context, taskResult *db.InnerTaskResult, leaseHolders []string) (*db.TaskResultModel, error) {
 	t.clientWrapper.BeforeQuery()
 	defer t.clientWrapper.AfterQuery()
 
-	// Retrieve the task object from the appropriate source
-	task, err := t.client.Task.FindUnique(db.Task.ID.Equals(taskResult.TaskID)).Exec(ctx)
+	taskResultId := uuid.New().String()
+	leaseHoldersJSON, err := json.Marshal(leaseHolders)
+	if err != nil {
+		return nil, err
+	}
+
+	lockTaskQuery := `UPDATE "Task" SET updated_at = updated_at WHERE id = $1;`
+	// TODO add web3 integration fields when the time comes
+	query := `
+WITH reserved AS (
+  SELECT COUNT(*) AS num_slots
+  FROM jsonb_array_elements_text($11::jsonb) AS holder(worker_id)
+  WHERE NOT EXISTS (
+    SELECT 1 FROM "TaskResult" AS tr
+    WHERE tr.task_id = $1 AND tr.worker_id = holder.worker_id AND tr.status = 'COMPLETED'::"TaskResultStatus"
+  )
+),
+updated_task AS (
+  UPDATE "Task"
+  SET
+    num_results = num_results + 1,
+    status = CASE WHEN num_results + 1 >= max_results THEN 'COMPLETED'::"TaskStatus" ELSE status END,
+    completed_at = CASE WHEN num_results + 1 >= max_results THEN NOW() ELSE completed_at END,
+    updated_at = NOW()
+  WHERE id = $1 AND status = 'IN_PROGRESS'::"TaskStatus"
+    AND num_results + (SELECT num_slots FROM reserved) < max_results
+  RETURNING id
+)
+INSERT INTO "TaskResult" (id, created_at, updated_at, status, result_data, task_id, worker_id, signature, signature_scheme, signed_result_data, opened_at, time_on_task_seconds, is_fast_submission)
+SELECT $2, NOW(), NOW(), 'COMPLETED'::"TaskResultStatus", $3::jsonb, updated_task.id, $4, $5, $6, $7, $8, $9, $10
+FROM updated_task;
+`
+	err = t.client.Prisma.Transaction(
+		t.client.Prisma.ExecuteRaw(lockTaskQuery, taskResult.TaskID).Tx(),
+		t.client.Prisma.ExecuteRaw(
+			query,
+			taskResult.TaskID,
+			taskResultId,
+			string(taskResult.ResultData),
+			taskResult.WorkerID,
+			taskResult.Signature,
+			taskResult.SignatureScheme,
+			taskResult.SignedResultData,
+			taskResult.OpenedAt,
+			taskResult.TimeOnTaskSeconds,
+			taskResult.IsFastSubmission,
+			string(leaseHoldersJSON),
+		).Tx(),
+	).Exec(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	updateTaskParams := []db.TaskSetParam{
-		db.Task.NumResults.Increment(1),
-		db.Task.UpdatedAt.Set(time.Now()),
+	createdTaskResult, err := t.client.TaskResult.FindUnique(
+		db.TaskResult.ID.Equals(taskResultId),
+	).With(
+		db.TaskResult.Task.Fetch(),
+	).Exec(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, t.rejectedResultError(ctx, taskResult)
 	}
-	// Check if num_results equals max_results before updating the task status
-	if task.NumResults+1 == task.MaxResults && task.Status != db.TaskStatusCompleted {
-		updateTaskParams = append(updateTaskParams, db.Task.Status.Set(db.TaskStatusCompleted))
+	if err != nil {
+		return nil, err
 	}
 
-	// TODO add web3 integration fields when the time comes
-	updateTaskTx := t.client.Task.FindUnique(db.Task.ID.Equals(taskResult.TaskID)).Update(updateTaskParams...).Tx()
+	// Invalidate cached task so the new num_results and status are visible
+	cache := cache.GetCacheInstance()
+	if err := cache.DeleteWithSuffix(cache.Keys.TaskById, taskResult.TaskID); err != nil {
+		log.Warn().Err(err).Str("taskId", taskResult.TaskID).Msg("Failed to delete task cache")
+	}
+	return createdTaskResult, nil
+}
 
-	createResultTx := t.client.TaskResult.CreateOne(
-		db.TaskResult.Status.Set(db.TaskResultStatusCompleted),
-		db.TaskResult.ResultData.Set(taskResult.ResultData),
-		db.TaskResult.Task.Link(
-			db.Task.ID.Equals(taskResult.TaskID),
-		),
-		db.TaskResult.DojoWorker.Link(
-			db.DojoWorker.ID.Equals(taskResult.WorkerID),
-		),
+// rejectedResultError tells apart a task that is full from one whose remaining slots are leased
+func (t *TaskResultORM) rejectedResultError(ctx context.Context, taskResult *db.InnerTaskResult) error {
+	task, err := t.client.Task.FindUnique(db.Task.ID.Equals(taskResult.TaskID)).Exec(ctx)
+	if err == nil && task.Status == db.TaskStatusInProgress && task.NumResults < task.MaxResults {
+		log.Info().Str("taskId", taskResult.TaskID).Str("workerId", taskResult.WorkerID).Msg("Task result rejected, remaining slots are leased")
+		return ErrSlotsReserved
+	}
+	log.Info().Str("taskId", taskResult.TaskID).Str("workerId", taskResult.WorkerID).Msg("Task result rejected, task has reached max results")
+	return ErrMaxResultsReached
+}
+
+// ReviseTaskResult replaces the result data and signature of the worker's COMPLETED result and saves the previous
+// version as a TaskResultRevision. The result row is locked by a first statement, so concurrent revisions of the same
+// result run one after the other and each reads the revision numbers committed before it. The task row is share locked
+// so it cannot complete while the result is revised, and num_results is left untouched.
+// Returns ErrTaskResultNotRevisable if there is no COMPLETED result or the task is no longer in progress, and
+// ErrTaskResultRevisionConflict if the revision number was taken regardless.
+func (t *TaskResultORM) ReviseTaskResult(ctx context.Context, taskResult *db.InnerTaskResult) (*db.TaskResultModel, error) {
+	t.clientWrapper.BeforeQuery()
+	defer t.clientWrapper.AfterQuery()
+
+	lockResultQuery := `
+UPDATE "TaskResult" SET updated_at = updated_at
+WHERE task_id = $1 AND worker_id = $2 AND status = 'COMPLETED'::"TaskResultStatus";
+`
+	query := `
+WITH open_task AS (
+  SELECT id FROM "Task"
+  WHERE id = $1 AND status = 'IN_PROGRESS'::"TaskStatus" AND expire_at > NOW()
+  FOR SHARE
+),
+current_result AS (
+  SELECT tr.* FROM "TaskResult" AS tr
+  JOIN open_task ON open_task.id = tr.task_id
+  WHERE tr.worker_id = $2 AND tr.status = 'COMPLETED'::"TaskResultStatus"
+  FOR UPDATE OF tr
+),
+revision AS (
+  INSERT INTO "TaskResultRevision" (id, task_result_id, revision, submitted_at, result_data, signature, signature_scheme, signed_result_data)
+  SELECT gen_random_uuid()::text, c.id,
+    (SELECT COALESCE(MAX(revision), 0) + 1 FROM "TaskResultRevision" WHERE task_result_id = c.id),
+    c.updated_at, c.result_data, c.signature, c.signature_scheme, c.signed_result_data
+  FROM current_result AS c
+  RETURNING task_result_id
+)
+UPDATE "TaskResult" AS tr
+SET result_data = $3::jsonb, signature = $4, signature_scheme = $5, signed_result_data = $6, updated_at = NOW()
+FROM revision
+WHERE tr.id = revision.task_result_id;
+`
+	lockResult := t.client.Prisma.ExecuteRaw(lockResultQuery, taskResult.TaskID, taskResult.WorkerID).Tx()
+	reviseResult := t.client.Prisma.ExecuteRaw(
+		query,
+		taskResult.TaskID,
+		taskResult.WorkerID,
+		string(taskResult.ResultData),
+		taskResult.Signature,
+		taskResult.SignatureScheme,
+		taskResult.SignedResultData,
+	).Tx()
+	if err := t.client.Prisma.Transaction(lockResult, reviseResult).Exec(ctx); err != nil {
+		if isUniqueViolation(err) {
+			log.Info().Str("taskId", taskResult.TaskID).Str("workerId", taskResult.WorkerID).Msg("Task result revision conflicted with another revision")
+			return nil, ErrTaskResultRevisionConflict
+		}
+		return nil, err
+	}
+
+	if reviseResult.Result().Count == 0 {
+		log.Info().Str("taskId", taskResult.TaskID).Str("workerId", taskResult.WorkerID).Msg("Task result revision rejected")
+		return nil, ErrTaskResultNotRevisable
+	}
+
+	return t.client.TaskResult.FindFirst(
+		db.TaskResult.TaskID.Equals(taskResult.TaskID),
+		db.TaskResult.WorkerID.Equals(taskResult.WorkerID),
+		db.TaskResult.Status.Equals(db.TaskResultStatusCompleted),
 	).With(
 		db.TaskResult.Task.Fetch(),
-	).Tx()
+	).Exec(ctx)
+}
 
-	if err := t.client.Prisma.Transaction(updateTaskTx, createResultTx).Exec(ctx); err != nil {
-		return nil, err
+// TaskResultSettlement is the final reward and loss of a single task result
+type TaskResultSettlement struct {
+	TaskResultId    string
+	FinalisedReward float64
+	FinalisedLoss   float64
+}
+
+// SettleTask marks a finished task as settled and writes the finalised reward and loss of its results
+// in a single statement, so a task is never partially settled. Returns false if the task was already settled
+// or has not finished yet.
+func (t *TaskResultORM) SettleTask(ctx context.Context, taskId string, settlements []TaskResultSettlement) (bool, error) {
+	t.clientWrapper.BeforeQuery()
+	defer t.clientWrapper.AfterQuery()
+
+	claimTaskQuery := `
+  UPDATE "Task"
+  SET settled_at = NOW(), updated_at = NOW()
+  WHERE id = $1 AND settled_at IS NULL AND status IN ('COMPLETED'::"TaskStatus", 'EXPIRED'::"TaskStatus")
+  RETURNING id`
+
+	var query string
+	args := []interface{}{taskId}
+	if len(settlements) == 0 {
+		query = claimTaskQuery + ";"
+	} else {
+		values := make([]string, 0, len(settlements))
+		for _, settlement := range settlements {
+			n := len(args)
+			values = append(values, fmt.Sprintf("($%d, $%d::float8, $%d::float8)", n+1, n+2, n+3))
+			args = append(args, settlement.TaskResultId, settlement.FinalisedReward, settlement.FinalisedLoss)
+		}
+
+		// the CTE always runs, and only lets the results be updated if this call claimed the task
+		query = fmt.Sprintf(`
+WITH settled_task AS (%s
+)
+UPDATE "TaskResult" AS tr
+SET finalised_reward = v.reward, finalised_loss = v.loss, updated_at = NOW()
+FROM (VALUES %s) AS v(id, reward, loss), settled_task
+WHERE tr.id = v.id AND tr.task_id = settled_task.id;
+`, claimTaskQuery, strings.Join(values, ", "))
+	}
+
+	result, err := t.client.Prisma.ExecuteRaw(query, args...).Exec(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error settling task")
+		return false, err
+	}
+
+	// Invalidate cached task so settled_at is visible
+	cache := cache.GetCacheInstance()
+	if err := cache.DeleteWithSuffix(cache.Keys.TaskById, taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to delete task cache")
+	}
+
+	return result.Count > 0, nil
+}
+
+// GetSettledTResultsByWorker returns the worker's task results that have a finalised reward, newest first
+func (t *TaskResultORM) GetSettledTResultsByWorker(ctx context.Context, workerId string, limit int) ([]db.TaskResultModel, error) {
+	t.clientWrapper.BeforeQuery()
+	defer t.clientWrapper.AfterQuery()
+
+	return t.client.TaskResult.FindMany(
+		db.TaskResult.WorkerID.Equals(workerId),
+		db.TaskResult.Not(db.TaskResult.FinalisedReward.IsNull()),
+	).OrderBy(db.TaskResult.UpdatedAt.Order(db.SortOrderDesc)).
+		Take(limit).
+		Exec(ctx)
+}
+
+// GetMinerTResultsAfter returns up to limit results of the miner's tasks submitted in [from, to), ordered by
+// created_at then id, that come after the (afterCreatedAt, afterId) cursor. An empty afterId starts from the beginning.
+func (t *TaskResultORM) GetMinerTResultsAfter(ctx context.Context, minerUserId string, from, to time.Time, afterCreatedAt time.Time, afterId string, limit int) ([]db.TaskResultModel, error) {
+	t.clientWrapper.BeforeQuery()
+	defer t.clientWrapper.AfterQuery()
+
+	filters := []db.TaskResultWhereParam{
+		db.TaskResult.Task.Where(db.Task.MinerUserID.Equals(minerUserId)),
+		db.TaskResult.CreatedAt.Gte(from),
+		db.TaskResult.CreatedAt.Lt(to),
+	}
+	if afterId != "" {
+		filters = append(filters, db.TaskResult.Or(
+			db.TaskResult.CreatedAt.Gt(afterCreatedAt),
+			db.TaskResult.And(
+				db.TaskResult.CreatedAt.Equals(afterCreatedAt),
+				db.TaskResult.ID.Gt(afterId),
+			),
+		))
 	}
-	return createResultTx.Result(), nil
+
+	return t.client.TaskResult.FindMany(filters...).
+		OrderBy(
+			db.TaskResult.CreatedAt.Order(db.SortOrderAsc),
+			db.TaskResult.ID.Order(db.SortOrderAsc),
+		).
+		Take(limit).
+		Exec(ctx)
 }
 
 func (t *TaskResultORM) GetCompletedTResultCount(ctx context.Context) (int, error) {
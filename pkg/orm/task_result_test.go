@@ -0,0 +1,176 @@
+package orm_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"dojo-api/db"
+	"dojo-api/pkg/internal/testutil"
+	"dojo-api/pkg/orm"
+)
+
+func TestCreateTaskResultWithCompletedConcurrent(t *testing.T) {
+	client := testutil.NewClient(t)
+	ctx := context.Background()
+
+	const submissions, maxResults = 20, 3
+	task := testutil.CreateTask(t, client, maxResults)
+	workers := make([]*db.DojoWorkerModel, submissions)
+	for i := range workers {
+		workers[i] = testutil.CreateWorker(t, client)
+	}
+
+	taskResultORM := orm.NewTaskResultORM()
+	var wg sync.WaitGroup
+	errs := make(chan error, submissions)
+	start := make(chan struct{})
+	for i := 0; i < submissions; i++ {
+		wg.Add(1)
+		go func(worker *db.DojoWorkerModel) {
+			defer wg.Done()
+			<-start
+			_, err := taskResultORM.CreateTaskResultWithCompleted(ctx, &db.InnerTaskResult{
+				Status:     db.TaskResultStatusCompleted,
+				TaskID:     task.ID,
+				WorkerID:   worker.ID,
+				ResultData: db.JSON(`[]`),
+			}, nil)
+			errs <- err
+		}(workers[i])
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, orm.ErrMaxResultsReached):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != maxResults {
+		t.Errorf("%d submissions succeeded, want %d", succeeded, maxResults)
+	}
+
+	updated, err := client.Task.FindUnique(db.Task.ID.Equals(task.ID)).Exec(ctx)
+	if err != nil {
+		t.Fatalf("fetching task: %v", err)
+	}
+	if updated.NumResults != maxResults {
+		t.Errorf("NumResults = %d, want %d", updated.NumResults, maxResults)
+	}
+	if updated.Status != db.TaskStatusCompleted {
+		t.Errorf("Status = %s, want %s", updated.Status, db.TaskStatusCompleted)
+	}
+
+	results, err := client.TaskResult.FindMany(db.TaskResult.TaskID.Equals(task.ID)).Exec(ctx)
+	if err != nil {
+		t.Fatalf("fetching task results: %v", err)
+	}
+	if len(results) != maxResults {
+		t.Errorf("%d task results stored, want %d", len(results), maxResults)
+	}
+}
+
+func TestCreateTaskResultWithCompletedReservedSlots(t *testing.T) {
+	client := testutil.NewClient(t)
+	ctx := context.Background()
+
+	task := testutil.CreateTask(t, client, 2)
+	holderA, holderB, other := testutil.CreateWorker(t, client), testutil.CreateWorker(t, client), testutil.CreateWorker(t, client)
+	taskResultORM := orm.NewTaskResultORM()
+	submit := func(worker *db.DojoWorkerModel, leaseHolders []string) error {
+		_, err := taskResultORM.CreateTaskResultWithCompleted(ctx, &db.InnerTaskResult{
+			Status:     db.TaskResultStatusCompleted,
+			TaskID:     task.ID,
+			WorkerID:   worker.ID,
+			ResultData: db.JSON(`[]`),
+		}, leaseHolders)
+		return err
+	}
+
+	if err := submit(other, []string{holderA.ID, holderB.ID}); !errors.Is(err, orm.ErrSlotsReserved) {
+		t.Errorf("non holder submission error = %v, want %v", err, orm.ErrSlotsReserved)
+	}
+	if err := submit(holderA, []string{holderB.ID}); err != nil {
+		t.Fatalf("holder submission error = %v", err)
+	}
+	// holderA's result no longer reserves a slot, holderB's lease still does
+	if err := submit(other, []string{holderA.ID, holderB.ID}); !errors.Is(err, orm.ErrSlotsReserved) {
+		t.Errorf("non holder submission error = %v, want %v", err, orm.ErrSlotsReserved)
+	}
+	if err := submit(other, nil); err != nil {
+		t.Fatalf("submission after leases expired error = %v", err)
+	}
+	if err := submit(holderB, nil); !errors.Is(err, orm.ErrMaxResultsReached) {
+		t.Errorf("submission to a full task error = %v, want %v", err, orm.ErrMaxResultsReached)
+	}
+}
+
+func TestReviseTaskResultConcurrent(t *testing.T) {
+	client := testutil.NewClient(t)
+	ctx := context.Background()
+
+	const revisions = 10
+	task := testutil.CreateTask(t, client, 2)
+	worker := testutil.CreateWorker(t, client)
+	taskResultORM := orm.NewTaskResultORM()
+	taskResult, err := taskResultORM.CreateTaskResultWithCompleted(ctx, &db.InnerTaskResult{
+		Status:     db.TaskResultStatusCompleted,
+		TaskID:     task.ID,
+		WorkerID:   worker.ID,
+		ResultData: db.JSON(`[]`),
+	}, nil)
+	if err != nil {
+		t.Fatalf("creating task result: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, revisions)
+	start := make(chan struct{})
+	for i := 0; i < revisions; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := taskResultORM.ReviseTaskResult(ctx, &db.InnerTaskResult{
+				TaskID:     task.ID,
+				WorkerID:   worker.ID,
+				ResultData: db.JSON(`[]`),
+			})
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("ReviseTaskResult() error = %v", err)
+		}
+	}
+
+	stored, err := client.TaskResultRevision.FindMany(
+		db.TaskResultRevision.TaskResultID.Equals(taskResult.ID),
+	).OrderBy(
+		db.TaskResultRevision.Revision.Order(db.SortOrderAsc),
+	).Exec(ctx)
+	if err != nil {
+		t.Fatalf("fetching revisions: %v", err)
+	}
+	if len(stored) != revisions {
+		t.Fatalf("%d revisions stored, want %d", len(stored), revisions)
+	}
+	for i, revision := range stored {
+		if revision.Revision != i+1 {
+			t.Errorf("revision %d numbered %d", i+1, revision.Revision)
+		}
+	}
+}
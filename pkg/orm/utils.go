@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -225,3 +226,12 @@ func getPrismaConfig() func(*db.PrismaConfig) {
 	prismaConfig := db.WithDatasourceURL(buildPostgresConnString(secrets))
 	return prismaConfig
 }
+
+// isUniqueViolation reports whether err is a unique constraint violation, raised by a prisma query or a raw query
+func isUniqueViolation(err error) bool {
+	if _, ok := db.IsErrUniqueConstraint(err); ok {
+		return true
+	}
+	// raw queries fail with P2010 and the postgres error code in the message
+	return strings.Contains(err.Error(), "23505")
+}
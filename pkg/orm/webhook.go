@@ -0,0 +1,84 @@
+package orm
+
+import (
+	"context"
+
+	"dojo-api/db"
+)
+
+type WebhookORM struct {
+	dbClient      *db.PrismaClient
+	clientWrapper *PrismaClientWrapper
+}
+
+func NewWebhookORM() *WebhookORM {
+	clientWrapper := GetPrismaClient()
+	return &WebhookORM{dbClient: clientWrapper.Client, clientWrapper: clientWrapper}
+}
+
+func (o *WebhookORM) CreateWebhook(ctx context.Context, apiKeyId string, minerUserId string, url string, secret string, events []string) (*db.WebhookModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Webhook.CreateOne(
+		db.Webhook.URL.Set(url),
+		db.Webhook.Secret.Set(secret),
+		db.Webhook.APIKey.Link(db.APIKey.ID.Equals(apiKeyId)),
+		db.Webhook.MinerUser.Link(db.MinerUser.ID.Equals(minerUserId)),
+		db.Webhook.Events.Set(events),
+	).Exec(ctx)
+}
+
+// GetWebhooksByApiKey returns the webhooks registered with the API key that have not been deleted
+func (o *WebhookORM) GetWebhooksByApiKey(ctx context.Context, apiKeyId string) ([]db.WebhookModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Webhook.FindMany(
+		db.Webhook.APIKeyID.Equals(apiKeyId),
+		db.Webhook.IsDelete.Equals(false),
+	).OrderBy(db.Webhook.CreatedAt.Order(db.SortOrderAsc)).Exec(ctx)
+}
+
+// GetActiveWebhook returns a webhook that has not been deleted and whose API key is still enabled
+func (o *WebhookORM) GetActiveWebhook(ctx context.Context, webhookId string) (*db.WebhookModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Webhook.FindFirst(
+		db.Webhook.ID.Equals(webhookId),
+		db.Webhook.IsDelete.Equals(false),
+		db.Webhook.APIKey.Where(db.APIKey.IsDelete.Equals(false)),
+	).Exec(ctx)
+}
+
+// GetWebhooksForEvent returns the miner's active webhooks subscribed to the event
+func (o *WebhookORM) GetWebhooksForEvent(ctx context.Context, minerUserId string, event string) ([]db.WebhookModel, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	return o.dbClient.Webhook.FindMany(
+		db.Webhook.MinerUserID.Equals(minerUserId),
+		db.Webhook.Events.Has(event),
+		db.Webhook.IsDelete.Equals(false),
+		db.Webhook.APIKey.Where(db.APIKey.IsDelete.Equals(false)),
+	).Exec(ctx)
+}
+
+// DeleteWebhook soft deletes a webhook registered with the API key, returns false if there was none
+func (o *WebhookORM) DeleteWebhook(ctx context.Context, webhookId string, apiKeyId string) (bool, error) {
+	o.clientWrapper.BeforeQuery()
+	defer o.clientWrapper.AfterQuery()
+
+	result, err := o.dbClient.Webhook.FindMany(
+		db.Webhook.ID.Equals(webhookId),
+		db.Webhook.APIKeyID.Equals(apiKeyId),
+		db.Webhook.IsDelete.Equals(false),
+	).Update(
+		db.Webhook.IsDelete.Set(true),
+	).Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	return result.Count > 0, nil
+}
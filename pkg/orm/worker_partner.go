@@ -204,3 +204,35 @@ func (m *WorkerPartnerORM) GetWorkerPartnerByWorkerIdAndSubscriptionKey(workerId
 	}
 	return workerPartner, nil
 }
+
+// GetPartneredMinerUserIds returns the IDs of the miners behind the worker's active partner subscription keys
+func (m *WorkerPartnerORM) GetPartneredMinerUserIds(ctx context.Context, workerId string) ([]string, error) {
+	m.clientWrapper.BeforeQuery()
+	defer m.clientWrapper.AfterQuery()
+
+	workerPartners, err := m.dbClient.WorkerPartner.FindMany(
+		db.WorkerPartner.WorkerID.Equals(workerId),
+		db.WorkerPartner.IsDeleteByMiner.Equals(false),
+		db.WorkerPartner.IsDeleteByWorker.Equals(false),
+		db.WorkerPartner.SubscriptionKey.Where(
+			db.SubscriptionKey.IsDelete.Equals(false),
+		),
+	).With(
+		db.WorkerPartner.SubscriptionKey.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(workerPartners))
+	minerUserIds := make([]string, 0, len(workerPartners))
+	for _, workerPartner := range workerPartners {
+		minerUserId := workerPartner.SubscriptionKey().MinerUserID
+		if seen[minerUserId] {
+			continue
+		}
+		seen[minerUserId] = true
+		minerUserIds = append(minerUserIds, minerUserId)
+	}
+	return minerUserIds, nil
+}
@@ -0,0 +1,37 @@
+package payout
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+)
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// LeafHash hashes a (wallet_address, chain_id, amount) leaf the same way as OpenZeppelin's StandardMerkleTree,
+// keccak256(bytes.concat(keccak256(abi.encode(address, uint256, uint256)))), so proofs can be checked with MerkleProof.verify
+func LeafHash(walletAddress common.Address, chainId *big.Int, amount *big.Int) (common.Hash, error) {
+	for _, value := range []*big.Int{chainId, amount} {
+		if value.Sign() < 0 || value.Cmp(maxUint256) > 0 {
+			return common.Hash{}, fmt.Errorf("%s does not fit in a uint256", value)
+		}
+	}
+
+	encoded := make([]byte, 0, 3*common.HashLength)
+	encoded = append(encoded, common.LeftPadBytes(walletAddress.Bytes(), common.HashLength)...)
+	encoded = append(encoded, common.LeftPadBytes(chainId.Bytes(), common.HashLength)...)
+	encoded = append(encoded, common.LeftPadBytes(amount.Bytes(), common.HashLength)...)
+	return crypto.Keccak256Hash(crypto.Keccak256(encoded)), nil
+}
+
+// ToTokenUnits converts an amount to the token's smallest unit, truncating digits beyond its decimals. Payout leaf
+// amounts are already rounded down to the batch's decimals, so nothing is truncated for them.
+func ToTokenUnits(amount decimal.Decimal, decimals int) (*big.Int, error) {
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("invalid amount %s", amount)
+	}
+	return amount.Shift(int32(decimals)).BigInt(), nil
+}
@@ -0,0 +1,61 @@
+package payout
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+)
+
+func TestLeafHash(t *testing.T) {
+	// StandardMerkleTree.of([[address, 1, 5e18]], ["address", "uint256", "uint256"]) leaf hash
+	amount, _ := new(big.Int).SetString("5000000000000000000", 10)
+	leaf, err := LeafHash(common.HexToAddress("0x1111111111111111111111111111111111111111"), big.NewInt(1), amount)
+	if err != nil {
+		t.Fatalf("LeafHash() error = %v", err)
+	}
+	if want := common.HexToHash("0x44da5b0417700789316459d184ce4e6bf16fe395102a17511f84ab645a292efc"); leaf != want {
+		t.Errorf("LeafHash() = %s, want %s", leaf.Hex(), want.Hex())
+	}
+}
+
+func TestLeafHashRejectsValuesOutsideUint256(t *testing.T) {
+	address := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	for _, amount := range []*big.Int{big.NewInt(-1), new(big.Int).Add(maxUint256, big.NewInt(1))} {
+		if _, err := LeafHash(address, big.NewInt(1), amount); err == nil {
+			t.Errorf("LeafHash() with amount %s succeeded, want an error", amount)
+		}
+	}
+}
+
+func TestToTokenUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"0.1", 18, "100000000000000000"},
+		{"1.5", 6, "1500000"},
+		{"123456789.123456789123456789", 18, "123456789123456789123456789"},
+		// digits beyond the token's decimals are truncated
+		{"0.1234567", 6, "123456"},
+		{"0.0000009", 6, "0"},
+		{"42", 0, "42"},
+	}
+	for _, tt := range tests {
+		got, err := ToTokenUnits(decimal.RequireFromString(tt.amount), tt.decimals)
+		if err != nil {
+			t.Errorf("ToTokenUnits(%s, %d) error = %v", tt.amount, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ToTokenUnits(%s, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+
+	if _, err := ToTokenUnits(decimal.RequireFromString("-0.000001"), 18); err == nil {
+		t.Error("ToTokenUnits() of a negative amount succeeded, want an error")
+	}
+}
@@ -0,0 +1,30 @@
+package payout
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrNothingToPay        = errors.New("no worker has an available balance to pay out")
+	ErrBatchNotFound       = errors.New("payout batch not found")
+	ErrBatchNotReady       = errors.New("payout batch is not ready")
+	ErrLeafNotFound        = errors.New("worker is not part of the payout batch")
+	ErrInvalidBatchProofs  = errors.New("payout batch proofs do not match its merkle root")
+	ErrBatchAlreadyReady   = errors.New("payout batch is already ready")
+	ErrBatchAlreadySettled = errors.New("payout batch is already settled")
+	ErrInvalidPayoutWallet = errors.New("invalid payout wallet address")
+)
+
+type PayoutProofResponse struct {
+	BatchId       string          `json:"batchId"`
+	MerkleRoot    string          `json:"merkleRoot"`
+	WalletAddress string          `json:"walletAddress"`
+	ChainId       string          `json:"chainId"`
+	Amount        decimal.Decimal `json:"amount" swaggertype:"string"`
+	AmountUnits   string          `json:"amountUnits"`
+	TokenDecimals int             `json:"tokenDecimals"`
+	Leaf          string          `json:"leaf"`
+	Proof         []string        `json:"proof"`
+}
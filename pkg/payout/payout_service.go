@@ -0,0 +1,295 @@
+package payout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+
+	"dojo-api/db"
+	"dojo-api/pkg/merkle"
+	"dojo-api/pkg/orm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultTokenDecimals = 18
+
+type PayoutService struct {
+	payoutBatchORM *orm.PayoutBatchORM
+	ledgerORM      *orm.LedgerORM
+	tokenDecimals  int
+}
+
+func NewPayoutService() *PayoutService {
+	tokenDecimals := defaultTokenDecimals
+	if decimalsStr := os.Getenv("PAYOUT_TOKEN_DECIMALS"); decimalsStr != "" {
+		decimals, err := strconv.Atoi(decimalsStr)
+		if err != nil || decimals < 0 || decimals > 77 {
+			log.Warn().Str("PAYOUT_TOKEN_DECIMALS", decimalsStr).Msg("Invalid value, using default")
+		} else {
+			tokenDecimals = decimals
+		}
+	}
+
+	return &PayoutService{
+		payoutBatchORM: orm.NewPayoutBatchORM(),
+		ledgerORM:      orm.NewLedgerORM(),
+		tokenDecimals:  tokenDecimals,
+	}
+}
+
+// CreateBatch moves every available worker balance into a new payout batch and builds its merkle tree
+func (s *PayoutService) CreateBatch(ctx context.Context) (*db.PayoutBatchModel, error) {
+	batch, err := s.payoutBatchORM.CreatePayoutBatch(ctx, s.tokenDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves, err := s.payoutBatchORM.GetBatchLeaves(ctx, batch.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(leaves) == 0 {
+		if err := s.payoutBatchORM.DeleteEmptyBatch(ctx, batch.ID); err != nil {
+			log.Warn().Err(err).Str("batchId", batch.ID).Msg("Failed to delete empty payout batch")
+		}
+		return nil, ErrNothingToPay
+	}
+
+	// balances are already pending payout, a batch that fails here stays PENDING and can be finalised again
+	if err := s.FinaliseBatch(ctx, batch.ID); err != nil {
+		return nil, err
+	}
+	return s.payoutBatchORM.GetBatchById(ctx, batch.ID)
+}
+
+// FinaliseBatch builds the merkle tree of a PENDING batch and stores its root and proofs
+func (s *PayoutService) FinaliseBatch(ctx context.Context, batchId string) error {
+	batch, err := s.getBatch(ctx, batchId)
+	if err != nil {
+		return err
+	}
+	if batch.Status != db.PayoutBatchStatusPending {
+		return ErrBatchAlreadyReady
+	}
+
+	leaves, err := s.payoutBatchORM.GetBatchLeaves(ctx, batchId)
+	if err != nil {
+		return err
+	}
+
+	leafHashes := make([]common.Hash, 0, len(leaves))
+	amountUnits := make([]*big.Int, 0, len(leaves))
+	for _, leaf := range leaves {
+		leafHash, units, err := buildLeafHash(leaf, batch.TokenDecimals)
+		if err != nil {
+			log.Error().Err(err).Str("batchId", batchId).Str("leafId", leaf.ID).Msg("Invalid payout leaf")
+			return err
+		}
+		leafHashes = append(leafHashes, leafHash)
+		amountUnits = append(amountUnits, units)
+	}
+
+	tree, err := merkle.NewTree(leafHashes)
+	if err != nil {
+		return err
+	}
+
+	leafProofs := make([]orm.PayoutLeafProof, 0, len(leaves))
+	for i, leaf := range leaves {
+		proof, err := tree.Proof(leafHashes[i])
+		if err != nil {
+			return err
+		}
+		proofJSON, err := json.Marshal(hashesToHex(proof))
+		if err != nil {
+			return err
+		}
+		leafProofs = append(leafProofs, orm.PayoutLeafProof{
+			LeafId:      leaf.ID,
+			AmountUnits: amountUnits[i].String(),
+			LeafHash:    leafHashes[i].Hex(),
+			Proof:       proofJSON,
+		})
+	}
+
+	if err := s.payoutBatchORM.FinaliseBatch(ctx, batchId, tree.Root().Hex(), leafProofs); err != nil {
+		return err
+	}
+	log.Info().Str("batchId", batchId).Str("merkleRoot", tree.Root().Hex()).Int("numLeaves", len(leaves)).Msg("Payout batch finalised")
+	return nil
+}
+
+// VerifyBatch recomputes every leaf of a READY or SETTLED batch from its stored payout and checks its proof against the root
+func (s *PayoutService) VerifyBatch(ctx context.Context, batchId string) error {
+	batch, err := s.getBatch(ctx, batchId)
+	if err != nil {
+		return err
+	}
+	merkleRoot, ok := batch.MerkleRoot()
+	if batch.Status == db.PayoutBatchStatusPending || !ok {
+		return ErrBatchNotReady
+	}
+
+	leaves, err := s.payoutBatchORM.GetBatchLeaves(ctx, batchId)
+	if err != nil {
+		return err
+	}
+
+	for _, leaf := range leaves {
+		leafHash, _, err := buildLeafHash(leaf, batch.TokenDecimals)
+		if err != nil {
+			return err
+		}
+		proof, err := parseProof(leaf)
+		if err != nil {
+			return err
+		}
+		if !merkle.VerifyProof(common.HexToHash(merkleRoot), leafHash, proof) {
+			log.Error().Str("batchId", batchId).Str("leafId", leaf.ID).Msg("Payout leaf proof does not match the merkle root")
+			return ErrInvalidBatchProofs
+		}
+	}
+	return nil
+}
+
+// SettleBatch records that every payout of a READY batch was paid on chain, moving the amounts from the workers'
+// pending payout to PAID_OUT in the ledger
+func (s *PayoutService) SettleBatch(ctx context.Context, batchId string) error {
+	batch, err := s.getBatch(ctx, batchId)
+	if err != nil {
+		return err
+	}
+	switch batch.Status {
+	case db.PayoutBatchStatusPending:
+		return ErrBatchNotReady
+	case db.PayoutBatchStatusSettled:
+		return ErrBatchAlreadySettled
+	}
+
+	// the proofs are checked again so that a batch is never settled against a root that does not match its payouts
+	if err := s.VerifyBatch(ctx, batchId); err != nil {
+		return err
+	}
+	if err := s.ledgerORM.SettlePayoutBatch(ctx, batchId); err != nil {
+		return err
+	}
+	log.Info().Str("batchId", batchId).Str("totalAmount", batch.TotalAmount.String()).Msg("Payout batch settled")
+	return nil
+}
+
+// GetWorkerProof returns the worker's leaf in a READY or SETTLED batch and its proof
+func (s *PayoutService) GetWorkerProof(ctx context.Context, batchId string, workerId string) (*PayoutProofResponse, error) {
+	batch, err := s.getBatch(ctx, batchId)
+	if err != nil {
+		return nil, err
+	}
+	merkleRoot, ok := batch.MerkleRoot()
+	if batch.Status == db.PayoutBatchStatusPending || !ok {
+		return nil, ErrBatchNotReady
+	}
+
+	leaf, err := s.payoutBatchORM.GetWorkerLeaf(ctx, batchId, workerId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrLeafNotFound
+		}
+		return nil, err
+	}
+
+	proof, err := parseProof(*leaf)
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := parseChainId(leaf.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	amountUnits, _ := leaf.AmountUnits()
+	leafHash, _ := leaf.LeafHash()
+
+	return &PayoutProofResponse{
+		BatchId:       batch.ID,
+		MerkleRoot:    merkleRoot,
+		WalletAddress: common.HexToAddress(leaf.WalletAddress).Hex(),
+		ChainId:       chainId.String(),
+		Amount:        leaf.Amount,
+		AmountUnits:   amountUnits,
+		TokenDecimals: batch.TokenDecimals,
+		Leaf:          leafHash,
+		Proof:         hashesToHex(proof),
+	}, nil
+}
+
+func (s *PayoutService) getBatch(ctx context.Context, batchId string) (*db.PayoutBatchModel, error) {
+	batch, err := s.payoutBatchORM.GetBatchById(ctx, batchId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrBatchNotFound
+		}
+		return nil, err
+	}
+	return batch, nil
+}
+
+func buildLeafHash(leaf db.PayoutBatchLeafModel, tokenDecimals int) (common.Hash, *big.Int, error) {
+	if !common.IsHexAddress(leaf.WalletAddress) {
+		return common.Hash{}, nil, fmt.Errorf("%w: %s", ErrInvalidPayoutWallet, leaf.WalletAddress)
+	}
+
+	chainId, err := parseChainId(leaf.ChainID)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+
+	amountUnits, err := ToTokenUnits(leaf.Amount, tokenDecimals)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+
+	leafHash, err := LeafHash(common.HexToAddress(leaf.WalletAddress), chainId, amountUnits)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return leafHash, amountUnits, nil
+}
+
+// parseChainId accepts decimal and 0x prefixed chain IDs, as sent by wallets on login
+func parseChainId(chainIdStr string) (*big.Int, error) {
+	chainId, ok := new(big.Int).SetString(chainIdStr, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid chain id %s", chainIdStr)
+	}
+	return chainId, nil
+}
+
+func parseProof(leaf db.PayoutBatchLeafModel) ([]common.Hash, error) {
+	proofJSON, ok := leaf.Proof()
+	if !ok {
+		return nil, fmt.Errorf("leaf %s has no proof", leaf.ID)
+	}
+
+	var proofHex []string
+	if err := json.Unmarshal(proofJSON, &proofHex); err != nil {
+		return nil, err
+	}
+
+	proof := make([]common.Hash, 0, len(proofHex))
+	for _, hash := range proofHex {
+		proof = append(proof, common.HexToHash(hash))
+	}
+	return proof, nil
+}
+
+func hashesToHex(hashes []common.Hash) []string {
+	hexHashes := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		hexHashes = append(hexHashes, hash.Hex())
+	}
+	return hexHashes
+}
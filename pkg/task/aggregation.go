@@ -0,0 +1,245 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"dojo-api/db"
+	"dojo-api/pkg/cache"
+	"dojo-api/pkg/orm"
+
+	"github.com/rs/zerolog/log"
+)
+
+type AggregationService struct {
+	taskORM       *orm.TaskORM
+	taskResultORM *orm.TaskResultORM
+	cache         *cache.Cache
+}
+
+func NewAggregationService() *AggregationService {
+	return &AggregationService{
+		taskORM:       orm.NewTaskORM(),
+		taskResultORM: orm.NewTaskResultORM(),
+		cache:         cache.GetCacheInstance(),
+	}
+}
+
+// GetTaskAggregate returns the aggregated COMPLETED results of a task, from cache if available
+func (s *AggregationService) GetTaskAggregate(ctx context.Context, taskId string) (*TaskAggregateResponse, error) {
+	var aggregate *TaskAggregateResponse
+	cacheKey := s.cache.BuildCacheKey(s.cache.Keys.TaskAggregate, taskId)
+
+	// Try to get from cache first
+	if err := s.cache.GetCacheValue(cacheKey, &aggregate); err == nil {
+		return aggregate, nil
+	}
+
+	task, err := s.taskORM.GetById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	taskResults, err := s.taskResultORM.GetCompletedTResultByTaskId(ctx, taskId)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error fetching completed task results")
+		return nil, err
+	}
+
+	aggregate, err = AggregateTaskResults(task, taskResults)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store in cache
+	if err := s.cache.SetCacheValue(cacheKey, aggregate); err != nil {
+		log.Warn().Err(err).Msg("Failed to set task aggregate cache")
+	}
+
+	return aggregate, nil
+}
+
+// InvalidateTaskAggregate clears the cached aggregate, to be called whenever a task gets a new result
+func (s *AggregationService) InvalidateTaskAggregate(taskId string) error {
+	return s.cache.DeleteWithSuffix(s.cache.Keys.TaskAggregate, taskId)
+}
+
+// criteriaCollector accumulates the submitted values of a single task criteria
+type criteriaCollector struct {
+	criteria     Criteria
+	scores       []float64
+	optionScores map[string][]float64
+	optionCounts map[string]int
+	textFeedback []string
+}
+
+// AggregateTaskResults computes per model and criteria statistics over the given results.
+// Results that are not COMPLETED are ignored, and models and criteria keep the order of the task data.
+func AggregateTaskResults(task *db.TaskModel, taskResults []db.TaskResultModel) (*TaskAggregateResponse, error) {
+	var taskData TaskData
+	if err := json.Unmarshal(task.TaskData, &taskData); err != nil {
+		log.Error().Err(err).Msg("Error unmarshaling task data")
+		return nil, err
+	}
+
+	collectors := make(map[string]map[string]*criteriaCollector)
+	for _, response := range taskData.Responses {
+		collectors[response.Model] = make(map[string]*criteriaCollector)
+		for _, criteria := range response.Criteria {
+			collectors[response.Model][criteriaKey(criteria)] = &criteriaCollector{
+				criteria:     criteria,
+				optionScores: make(map[string][]float64),
+				optionCounts: make(map[string]int),
+			}
+		}
+	}
+
+	numResults := 0
+	for _, taskResult := range taskResults {
+		if taskResult.Status != db.TaskResultStatusCompleted {
+			continue
+		}
+
+		var results []Result
+		if err := json.Unmarshal(taskResult.ResultData, &results); err != nil {
+			log.Warn().Err(err).Str("taskResultId", taskResult.ID).Msg("Skipping task result with invalid result data")
+			continue
+		}
+		numResults++
+
+		for _, result := range results {
+			for _, criteria := range result.Criteria {
+				collector, ok := collectors[result.Model][criteriaKey(criteria)]
+				if !ok || collector.criteria.GetType() != criteria.GetType() {
+					continue
+				}
+				collector.add(criteria)
+			}
+		}
+	}
+
+	aggregate := &TaskAggregateResponse{
+		TaskId:     task.ID,
+		NumResults: numResults,
+		Models:     make([]ModelAggregate, 0, len(taskData.Responses)),
+	}
+	for _, response := range taskData.Responses {
+		modelAggregate := ModelAggregate{
+			Model:    response.Model,
+			Criteria: make([]CriteriaAggregate, 0, len(response.Criteria)),
+		}
+		for _, criteria := range response.Criteria {
+			collector := collectors[response.Model][criteriaKey(criteria)]
+			modelAggregate.Criteria = append(modelAggregate.Criteria, collector.aggregate())
+		}
+		aggregate.Models = append(aggregate.Models, modelAggregate)
+	}
+
+	return aggregate, nil
+}
+
+func (c *criteriaCollector) add(criteria Criteria) {
+	switch submitted := criteria.(type) {
+	case ScoreCriteria:
+		c.scores = append(c.scores, submitted.MinerScore)
+	case MultiScoreCriteria:
+		for option, score := range submitted.Value {
+			c.optionScores[option] = append(c.optionScores[option], score)
+		}
+	case RankingCriteria:
+		for rankStr, option := range submitted.Value {
+			rank, err := strconv.Atoi(rankStr)
+			if err != nil {
+				continue
+			}
+			c.optionScores[option] = append(c.optionScores[option], float64(rank))
+		}
+	case MultiSelectCriteria:
+		for _, option := range submitted.Value {
+			c.optionCounts[option]++
+		}
+	case TextCriteria:
+		if submitted.TextFeedback != "" {
+			c.textFeedback = append(c.textFeedback, submitted.TextFeedback)
+		}
+	}
+}
+
+func (c *criteriaCollector) aggregate() CriteriaAggregate {
+	aggregate := CriteriaAggregate{
+		ID:   c.criteria.GetId(),
+		Type: c.criteria.GetType(),
+	}
+
+	switch taskCriteria := c.criteria.(type) {
+	case ScoreCriteria:
+		stats := computeScoreStats(c.scores)
+		aggregate.Stats = &stats
+	case MultiScoreCriteria:
+		aggregate.OptionStats = c.optionStats(taskCriteria.Options)
+	case RankingCriteria:
+		aggregate.OptionStats = c.optionStats(taskCriteria.Options)
+	case MultiSelectCriteria:
+		aggregate.OptionCounts = make(map[string]int, len(taskCriteria.Options))
+		for _, option := range taskCriteria.Options {
+			aggregate.OptionCounts[option] = c.optionCounts[option]
+		}
+	case TextCriteria:
+		aggregate.TextFeedback = c.textFeedback
+	}
+
+	return aggregate
+}
+
+func (c *criteriaCollector) optionStats(options []string) map[string]ScoreStats {
+	optionStats := make(map[string]ScoreStats, len(options))
+	for _, option := range options {
+		optionStats[option] = computeScoreStats(c.optionScores[option])
+	}
+	return optionStats
+}
+
+// computeScoreStats returns the count, mean, median, population standard deviation, min and max of values
+func computeScoreStats(values []float64) ScoreStats {
+	if len(values) == 0 {
+		return ScoreStats{}
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(sorted))
+
+	mid := len(sorted) / 2
+	median := sorted[mid]
+	if len(sorted)%2 == 0 {
+		median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return ScoreStats{
+		Count:  len(sorted),
+		Mean:   mean,
+		Median: median,
+		StdDev: math.Sqrt(variance),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+}
@@ -0,0 +1,419 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"dojo-api/db"
+	"dojo-api/pkg/cache"
+	"dojo-api/pkg/orm"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultAgreementWindow = 50
+	MaxAgreementWindow     = 200
+)
+
+type AgreementService struct {
+	taskORM       *orm.TaskORM
+	taskResultORM *orm.TaskResultORM
+	cache         *cache.Cache
+}
+
+func NewAgreementService() *AgreementService {
+	return &AgreementService{
+		taskORM:       orm.NewTaskORM(),
+		taskResultORM: orm.NewTaskResultORM(),
+		cache:         cache.GetCacheInstance(),
+	}
+}
+
+// GetTaskAgreement returns the inter-annotator agreement over the COMPLETED results of a task
+func (s *AgreementService) GetTaskAgreement(ctx context.Context, taskId string) (*TaskAgreement, error) {
+	var agreement *TaskAgreement
+	cacheKey := s.cache.BuildCacheKey(s.cache.Keys.TaskAgreement, taskId)
+
+	// Try to get from cache first
+	if err := s.cache.GetCacheValue(cacheKey, &agreement); err == nil {
+		return agreement, nil
+	}
+
+	task, err := s.taskORM.GetById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	taskResults, err := s.taskResultORM.GetCompletedTResultByTaskId(ctx, taskId)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error fetching completed task results")
+		return nil, err
+	}
+
+	agreement, err = ComputeTaskAgreement(task, taskResults)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store in cache
+	if err := s.cache.SetCacheValue(cacheKey, agreement); err != nil {
+		log.Warn().Err(err).Msg("Failed to set task agreement cache")
+	}
+
+	return agreement, nil
+}
+
+// InvalidateTaskAgreement clears the cached agreement, to be called whenever a task gets a new result
+func (s *AgreementService) InvalidateTaskAgreement(taskId string) error {
+	return s.cache.DeleteWithSuffix(s.cache.Keys.TaskAgreement, taskId)
+}
+
+// GetMinerAgreement averages the agreement of the miner's last `window` completed tasks
+func (s *AgreementService) GetMinerAgreement(ctx context.Context, minerUserId string, window int) (*MinerAgreement, error) {
+	tasks, err := s.taskORM.GetRecentCompletedTasksByMiner(ctx, minerUserId, window)
+	if err != nil {
+		log.Error().Err(err).Str("minerUserId", minerUserId).Msg("Error fetching completed tasks for miner")
+		return nil, err
+	}
+
+	minerAgreement := &MinerAgreement{MinerUserId: minerUserId, NumTasks: len(tasks)}
+	if len(tasks) == 0 {
+		return minerAgreement, nil
+	}
+
+	taskIds := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIds = append(taskIds, task.ID)
+	}
+
+	taskResults, err := s.taskResultORM.GetCompletedTResultByTaskIds(ctx, taskIds)
+	if err != nil {
+		log.Error().Err(err).Str("minerUserId", minerUserId).Msg("Error fetching completed task results for miner")
+		return nil, err
+	}
+
+	resultsByTask := make(map[string][]db.TaskResultModel)
+	for _, taskResult := range taskResults {
+		resultsByTask[taskResult.TaskID] = append(resultsByTask[taskResult.TaskID], taskResult)
+	}
+
+	var scoreAlphas, selectionAlphas, kendallWs []float64
+	for i := range tasks {
+		agreement, err := ComputeTaskAgreement(&tasks[i], resultsByTask[tasks[i].ID])
+		if err != nil {
+			log.Warn().Err(err).Str("taskId", tasks[i].ID).Msg("Skipping task agreement")
+			continue
+		}
+
+		if agreement.ScoreAlpha != nil {
+			scoreAlphas = append(scoreAlphas, *agreement.ScoreAlpha)
+		}
+		if agreement.SelectionAlpha != nil {
+			selectionAlphas = append(selectionAlphas, *agreement.SelectionAlpha)
+		}
+		if agreement.KendallW != nil {
+			kendallWs = append(kendallWs, *agreement.KendallW)
+		}
+	}
+
+	minerAgreement.ScoreAlpha = meanOf(scoreAlphas)
+	minerAgreement.SelectionAlpha = meanOf(selectionAlphas)
+	minerAgreement.KendallW = meanOf(kendallWs)
+	return minerAgreement, nil
+}
+
+// taskRatings holds each worker's ratings of the units of a task, keyed by unit then worker ID
+type taskRatings struct {
+	taskData   TaskData
+	numResults int
+	scores     map[string]map[string]float64
+	selections map[string]map[string]float64
+	// each worker's rank of every option, in task option order
+	rankings map[string]map[string][]float64
+}
+
+// collectTaskRatings treats each worker as a rater, and each score item, multi-select option
+// and ranking criteria of the task as a unit being rated. Results that are not COMPLETED are ignored.
+func collectTaskRatings(task *db.TaskModel, taskResults []db.TaskResultModel) (*taskRatings, error) {
+	var taskData TaskData
+	if err := json.Unmarshal(task.TaskData, &taskData); err != nil {
+		log.Error().Err(err).Msg("Error unmarshaling task data")
+		return nil, err
+	}
+
+	modelCriteriaMap := make(map[string]map[string]Criteria)
+	for _, response := range taskData.Responses {
+		criteriaMap := make(map[string]Criteria)
+		for _, criteria := range response.Criteria {
+			criteriaMap[criteriaKey(criteria)] = criteria
+		}
+		modelCriteriaMap[response.Model] = criteriaMap
+	}
+
+	ratings := &taskRatings{
+		taskData:   taskData,
+		scores:     make(map[string]map[string]float64),
+		selections: make(map[string]map[string]float64),
+		rankings:   make(map[string]map[string][]float64),
+	}
+	addRating := func(units map[string]map[string]float64, unit string, workerId string, value float64) {
+		if units[unit] == nil {
+			units[unit] = make(map[string]float64)
+		}
+		units[unit][workerId] = value
+	}
+
+	for _, taskResult := range taskResults {
+		if taskResult.Status != db.TaskResultStatusCompleted {
+			continue
+		}
+
+		var results []Result
+		if err := json.Unmarshal(taskResult.ResultData, &results); err != nil {
+			log.Warn().Err(err).Str("taskResultId", taskResult.ID).Msg("Skipping task result with invalid result data")
+			continue
+		}
+		ratings.numResults++
+
+		workerId := taskResult.WorkerID
+		for _, result := range results {
+			for _, criteria := range result.Criteria {
+				taskCriteria, ok := modelCriteriaMap[result.Model][criteriaKey(criteria)]
+				if !ok || taskCriteria.GetType() != criteria.GetType() {
+					continue
+				}
+
+				unit := result.Model + ":" + criteriaKey(criteria)
+				switch submitted := criteria.(type) {
+				case ScoreCriteria:
+					tc := taskCriteria.(ScoreCriteria)
+					addRating(ratings.scores, unit, workerId,
+						fractionOfScale(submitted.MinerScore, tc.Min, tc.Max, tc.OutputMin, tc.OutputMax))
+				case MultiScoreCriteria:
+					tc := taskCriteria.(MultiScoreCriteria)
+					for option, score := range submitted.Value {
+						addRating(ratings.scores, unit+":"+option, workerId,
+							fractionOfScale(score, tc.Min, tc.Max, tc.OutputMin, tc.OutputMax))
+					}
+				case MultiSelectCriteria:
+					tc := taskCriteria.(MultiSelectCriteria)
+					selected := make(map[string]bool, len(submitted.Value))
+					for _, option := range submitted.Value {
+						selected[option] = true
+					}
+					for _, option := range tc.Options {
+						value := 0.0
+						if selected[option] {
+							value = 1
+						}
+						addRating(ratings.selections, unit+":"+option, workerId, value)
+					}
+				case RankingCriteria:
+					tc := taskCriteria.(RankingCriteria)
+					if ranks, ok := ranksByOption(submitted.Value, tc.Options); ok {
+						if ratings.rankings[unit] == nil {
+							ratings.rankings[unit] = make(map[string][]float64)
+						}
+						ratings.rankings[unit][workerId] = ranks
+					}
+				}
+			}
+		}
+	}
+
+	return ratings, nil
+}
+
+// unitValues drops the worker IDs of the ratings of each unit
+func unitValues(units map[string]map[string]float64) map[string][]float64 {
+	values := make(map[string][]float64, len(units))
+	for unit, byWorker := range units {
+		for _, value := range byWorker {
+			values[unit] = append(values[unit], value)
+		}
+	}
+	return values
+}
+
+// ComputeTaskAgreement computes Krippendorff's alpha over the score and multi-select units
+// and Kendall's W over each ranking criteria of the task
+func ComputeTaskAgreement(task *db.TaskModel, taskResults []db.TaskResultModel) (*TaskAgreement, error) {
+	ratings, err := collectTaskRatings(task, taskResults)
+	if err != nil {
+		return nil, err
+	}
+
+	agreement := &TaskAgreement{
+		TaskId:         task.ID,
+		NumResults:     ratings.numResults,
+		ScoreAlpha:     krippendorffAlpha(unitValues(ratings.scores), intervalDistance),
+		SelectionAlpha: krippendorffAlpha(unitValues(ratings.selections), nominalDistance),
+		Rankings:       make([]RankingAgreement, 0),
+	}
+
+	var kendallWs []float64
+	for _, response := range ratings.taskData.Responses {
+		for _, criteria := range response.Criteria {
+			if criteria.GetType() != CriteriaTypeRanking {
+				continue
+			}
+
+			rankingsByWorker := ratings.rankings[response.Model+":"+criteriaKey(criteria)]
+			workerRankings := make([][]float64, 0, len(rankingsByWorker))
+			for _, ranks := range rankingsByWorker {
+				workerRankings = append(workerRankings, ranks)
+			}
+
+			w := kendallW(workerRankings)
+			if w != nil {
+				kendallWs = append(kendallWs, *w)
+			}
+			agreement.Rankings = append(agreement.Rankings, RankingAgreement{
+				Model:      response.Model,
+				CriteriaId: criteria.GetId(),
+				NumRaters:  len(workerRankings),
+				KendallW:   w,
+			})
+		}
+	}
+	agreement.KendallW = meanOf(kendallWs)
+
+	return agreement, nil
+}
+
+// fractionOfScale maps a stored score onto [0, 1] so items with different scales can be compared.
+// Stored scores are on the output scale, which defaults to the input scale.
+func fractionOfScale(score, inputMin, inputMax, outputMin, outputMax float64) float64 {
+	scaleMin, scaleMax := outputMin, outputMax
+	if scaleMin == 0 && scaleMax == 0 {
+		scaleMin, scaleMax = inputMin, inputMax
+	}
+	if scaleMax <= scaleMin {
+		return score
+	}
+	return (score - scaleMin) / (scaleMax - scaleMin)
+}
+
+// ranksByOption returns the rank of each option in task option order, if the ranking covers every option
+func ranksByOption(value RankingValue, options []string) ([]float64, bool) {
+	rankOf := make(map[string]int, len(value))
+	for rankStr, option := range value {
+		rank, err := strconv.Atoi(rankStr)
+		if err != nil {
+			return nil, false
+		}
+		rankOf[option] = rank
+	}
+
+	ranks := make([]float64, 0, len(options))
+	for _, option := range options {
+		rank, ok := rankOf[option]
+		if !ok {
+			return nil, false
+		}
+		ranks = append(ranks, float64(rank))
+	}
+	return ranks, true
+}
+
+func intervalDistance(a, b float64) float64 {
+	return (a - b) * (a - b)
+}
+
+func nominalDistance(a, b float64) float64 {
+	if a == b {
+		return 0
+	}
+	return 1
+}
+
+// krippendorffAlpha computes 1 - Do/De, where Do is the observed disagreement within units and De the
+// disagreement expected by chance over all pairable values. Units rated by fewer than 2 raters are not pairable.
+func krippendorffAlpha(units map[string][]float64, distance func(a, b float64) float64) *float64 {
+	values := make([]float64, 0)
+	observed := 0.0
+	for _, unit := range units {
+		if len(unit) < 2 {
+			continue
+		}
+
+		unitDisagreement := 0.0
+		for i := range unit {
+			for j := range unit {
+				if i != j {
+					unitDisagreement += distance(unit[i], unit[j])
+				}
+			}
+		}
+		observed += unitDisagreement / float64(len(unit)-1)
+		values = append(values, unit...)
+	}
+
+	n := float64(len(values))
+	if n < 2 {
+		return nil
+	}
+	observed /= n
+
+	expected := 0.0
+	for i := range values {
+		for j := range values {
+			if i != j {
+				expected += distance(values[i], values[j])
+			}
+		}
+	}
+	expected /= n * (n - 1)
+
+	// every value is the same, alpha is undefined
+	if expected == 0 {
+		return nil
+	}
+
+	alpha := 1 - observed/expected
+	return &alpha
+}
+
+// kendallW computes Kendall's coefficient of concordance for m raters each ranking the same n options
+func kendallW(ratings [][]float64) *float64 {
+	m := float64(len(ratings))
+	if len(ratings) < 2 || len(ratings[0]) < 2 {
+		return nil
+	}
+	n := float64(len(ratings[0]))
+
+	rankSums := make([]float64, len(ratings[0]))
+	for _, ranks := range ratings {
+		for i, rank := range ranks {
+			rankSums[i] += rank
+		}
+	}
+
+	meanRankSum := m * (n + 1) / 2
+	s := 0.0
+	for _, rankSum := range rankSums {
+		s += (rankSum - meanRankSum) * (rankSum - meanRankSum)
+	}
+
+	w := 12 * s / (m * m * (n*n*n - n))
+	return &w
+}
+
+func meanOf(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	return &mean
+}
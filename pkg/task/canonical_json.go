@@ -0,0 +1,183 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"unicode/utf16"
+)
+
+var errDuplicateKey = errors.New("duplicate object key")
+
+// CanonicalResultData re-encodes the submitted resultData JSON with the JSON Canonicalization Scheme (RFC 8785), so
+// workers and third parties can reproduce the exact bytes that are signed with any JCS library: object keys sorted by
+// their UTF-16 code units, no insignificant whitespace, minimal string escaping and numbers as IEEE 754 doubles
+// formatted like ECMAScript. Objects with duplicate keys are rejected.
+func CanonicalResultData(resultData json.RawMessage) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(resultData))
+	// numbers are kept as their literal so out of range values are rejected instead of silently becoming Inf
+	decoder.UseNumber()
+
+	var buf bytes.Buffer
+	if err := writeCanonicalValue(&buf, decoder); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonicalValue(buf *bytes.Buffer, decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '[' {
+			return writeCanonicalArray(buf, decoder)
+		}
+		return writeCanonicalObject(buf, decoder)
+	case string:
+		writeCanonicalString(buf, value)
+	case json.Number:
+		number, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return fmt.Errorf("number %s cannot be canonicalised: %w", value, err)
+		}
+		buf.Write(canonicalNumber(number))
+	case bool:
+		buf.WriteString(strconv.FormatBool(value))
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+func writeCanonicalArray(buf *bytes.Buffer, decoder *json.Decoder) error {
+	buf.WriteByte('[')
+	for i := 0; decoder.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeCanonicalValue(buf, decoder); err != nil {
+			return err
+		}
+	}
+	// consume the closing ]
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func writeCanonicalObject(buf *bytes.Buffer, decoder *json.Decoder) error {
+	type member struct {
+		key   string
+		utf16 []uint16
+		value []byte
+	}
+
+	var members []member
+	seen := make(map[string]bool)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		if seen[key] {
+			return fmt.Errorf("%w %q", errDuplicateKey, key)
+		}
+		seen[key] = true
+
+		var value bytes.Buffer
+		if err := writeCanonicalValue(&value, decoder); err != nil {
+			return err
+		}
+		members = append(members, member{key: key, utf16: utf16.Encode([]rune(key)), value: value.Bytes()})
+	}
+	// consume the closing }
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	slices.SortFunc(members, func(a, b member) int {
+		return slices.Compare(a.utf16, b.utf16)
+	})
+
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeCanonicalString(buf, m.key)
+		buf.WriteByte(':')
+		buf.Write(m.value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// writeCanonicalString only escapes quotes, backslashes and control characters, using the short forms where JSON has them
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[r>>4])
+				buf.WriteByte(hex[r&0xf])
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// canonicalNumber formats a finite double like ECMAScript's Number.prototype.toString: the shortest decimal that
+// round trips, in exponent form below 1e-6 and from 1e21, and 0 for negative zero
+func canonicalNumber(f float64) []byte {
+	if f == 0 {
+		return []byte("0")
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	b := strconv.AppendFloat(nil, f, format, -1, 64)
+	if format == 'e' {
+		// ECMAScript has no leading zero in the exponent, 1e-07 -> 1e-7
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
@@ -0,0 +1,149 @@
+package task
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestCanonicalResultData(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			// RFC 8785 section 3.2.2
+			name: "rfc 8785 example",
+			input: `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			want: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			// RFC 8785 section 3.2.3, keys sorted by UTF-16 code units rather than code points or UTF-8 bytes
+			name: "rfc 8785 sorting",
+			input: `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`,
+			want: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:  "nested result data",
+			input: ` [ { "model" : "b", "criteria" : [ { "value" : 7.0, "type" : "score" } ] } ] `,
+			want:  `[{"criteria":[{"type":"score","value":7}],"model":"b"}]`,
+		},
+		{
+			name:  "no html escaping",
+			input: `{"text_feedback":"<b>a & b</b>"}`,
+			want:  `{"text_feedback":"<b>a & b</b>"}`,
+		},
+		{
+			name:  "line and paragraph separators are not escaped",
+			input: `"\u2028\u2029"`,
+			want:  "\"\u2028\u2029\"",
+		},
+		{
+			name:  "control characters",
+			input: `"\u0000\u0008\u0009\u000c\u001f\u007f"`,
+			want:  "\"\\u0000\\b\\t\\f\\u001f\u007f\"",
+		},
+		{
+			name:  "empty containers",
+			input: `{"a":{},"b":[]}`,
+			want:  `{"a":{},"b":[]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalResultData([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("CanonicalResultData() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalResultData() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalNumbers(t *testing.T) {
+	// RFC 8785 appendix B, IEEE 754 bit patterns and their ECMAScript serialization
+	tests := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, tt := range tests {
+		f := math.Float64frombits(tt.bits)
+		// the shortest exponent form parses back to the same bits
+		input := strconv.FormatFloat(f, 'e', -1, 64)
+		got, err := CanonicalResultData([]byte(input))
+		if err != nil {
+			t.Errorf("CanonicalResultData(%s) error = %v", input, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("CanonicalResultData(%s) = %s, want %s (bits %016x)", input, got, tt.want, tt.bits)
+		}
+	}
+}
+
+func TestCanonicalResultDataRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"duplicate key", `{"a":1,"a":2}`},
+		{"nested duplicate key", `[{"b":{"a":1,"a":1}}]`},
+		{"number out of range", `[1e400]`},
+		{"trailing data", `{"a":1} {}`},
+		{"truncated", `{"a":[1,2`},
+		{"invalid json", `{"a":}`},
+		{"empty", ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := CanonicalResultData([]byte(tt.input)); err == nil {
+				t.Errorf("CanonicalResultData() = %s, want an error", got)
+			}
+		})
+	}
+
+	if _, err := CanonicalResultData([]byte(`{"a":1,"a":2}`)); !errors.Is(err, errDuplicateKey) {
+		t.Errorf("CanonicalResultData() error = %v, want errDuplicateKey", err)
+	}
+}
@@ -0,0 +1,327 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/merkle"
+	"dojo-api/pkg/orm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	commitmentHashBatchSize = 100
+	// keeps the task assignment query well under the postgres limit of 65535 parameters
+	maxTasksPerCommitmentBatch = 5000
+)
+
+// resultCommitmentData is the part of a task result that is committed to, encoded as canonical JSON
+type resultCommitmentData struct {
+	ID         string          `json:"id"`
+	TaskID     string          `json:"taskId"`
+	WorkerID   string          `json:"workerId"`
+	ResultData json.RawMessage `json:"resultData"`
+}
+
+// ResultLeafHash is keccak256 of the canonical JSON {"id", "resultData", "taskId", "workerId"} of a task result
+func ResultLeafHash(taskResult db.TaskResultModel) (common.Hash, error) {
+	data, err := json.Marshal(resultCommitmentData{
+		ID:         taskResult.ID,
+		TaskID:     taskResult.TaskID,
+		WorkerID:   taskResult.WorkerID,
+		ResultData: json.RawMessage(taskResult.ResultData),
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	canonicalData, err := CanonicalResultData(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(canonicalData), nil
+}
+
+// ComputeResultsHash is keccak256 over the concatenated leaf hashes of the task's COMPLETED results ordered by
+// result ID, other results are ignored
+func ComputeResultsHash(taskResults []db.TaskResultModel) (common.Hash, []ResultCommitment, error) {
+	completedResults := make([]db.TaskResultModel, 0, len(taskResults))
+	for _, taskResult := range taskResults {
+		if taskResult.Status == db.TaskResultStatusCompleted {
+			completedResults = append(completedResults, taskResult)
+		}
+	}
+	sort.Slice(completedResults, func(i, j int) bool {
+		return completedResults[i].ID < completedResults[j].ID
+	})
+
+	leafHashes := make([]byte, 0, len(completedResults)*common.HashLength)
+	resultCommitments := make([]ResultCommitment, 0, len(completedResults))
+	for _, taskResult := range completedResults {
+		leafHash, err := ResultLeafHash(taskResult)
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+		leafHashes = append(leafHashes, leafHash.Bytes()...)
+		resultCommitments = append(resultCommitments, ResultCommitment{
+			TaskResultId: taskResult.ID,
+			WorkerId:     taskResult.WorkerID,
+			LeafHash:     leafHash.Hex(),
+		})
+	}
+	return crypto.Keccak256Hash(leafHashes), resultCommitments, nil
+}
+
+// TaskLeafHash is a task's leaf in a commitment batch, keccak256(keccak256(keccak256(taskId) || resultsHash)).
+// Hashing twice keeps leaves from being mistaken for inner nodes of the tree.
+func TaskLeafHash(taskId string, resultsHash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(crypto.Keccak256(crypto.Keccak256([]byte(taskId)), resultsHash.Bytes()))
+}
+
+type CommitmentService struct {
+	taskORM            *orm.TaskORM
+	taskResultORM      *orm.TaskResultORM
+	commitmentBatchORM *orm.CommitmentBatchORM
+}
+
+func NewCommitmentService() *CommitmentService {
+	return &CommitmentService{
+		taskORM:            orm.NewTaskORM(),
+		taskResultORM:      orm.NewTaskResultORM(),
+		commitmentBatchORM: orm.NewCommitmentBatchORM(),
+	}
+}
+
+// CommitTask stores the results hash of a COMPLETED task. Committing is idempotent, the first hash is kept.
+func (s *CommitmentService) CommitTask(ctx context.Context, taskId string) error {
+	task, err := s.taskORM.GetById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+
+	if task.Status != db.TaskStatusCompleted {
+		log.Debug().Str("taskId", taskId).Str("status", string(task.Status)).Msg("Task has not completed, skipping commitment")
+		return nil
+	}
+	if _, ok := task.ResultsHash(); ok {
+		return nil
+	}
+	return s.commitTask(ctx, taskId)
+}
+
+func (s *CommitmentService) commitTask(ctx context.Context, taskId string) error {
+	taskResults, err := s.taskResultORM.GetCompletedTResultByTaskId(ctx, taskId)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error fetching task results for commitment")
+		return err
+	}
+
+	resultsHash, _, err := ComputeResultsHash(taskResults)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error hashing task results")
+		return err
+	}
+
+	committed, err := s.taskORM.SetResultsHash(ctx, taskId, resultsHash.Hex())
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Error storing task results hash")
+		return err
+	}
+	if committed {
+		log.Info().Str("taskId", taskId).Str("resultsHash", resultsHash.Hex()).Int("numResults", len(taskResults)).Msg("Task results committed")
+	}
+	return nil
+}
+
+// CreateCommitmentBatch builds a merkle tree over every committed task that is not in a batch yet and stores its
+// root and proofs. It returns nil when there is nothing to batch.
+func (s *CommitmentService) CreateCommitmentBatch(ctx context.Context) (*db.CommitmentBatchModel, error) {
+	tasks, err := s.taskORM.GetUnbatchedHashedTasks(ctx, maxTasksPerCommitmentBatch)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	leafHashes := make([]common.Hash, 0, len(tasks))
+	for _, task := range tasks {
+		resultsHash, _ := task.ResultsHash()
+		leafHashes = append(leafHashes, TaskLeafHash(task.ID, common.HexToHash(resultsHash)))
+	}
+
+	tree, err := merkle.NewTree(leafHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	taskCommitments := make([]orm.TaskCommitment, 0, len(tasks))
+	for i, task := range tasks {
+		proof, err := tree.Proof(leafHashes[i])
+		if err != nil {
+			return nil, err
+		}
+		proofJSON, err := json.Marshal(hashesToHex(proof))
+		if err != nil {
+			return nil, err
+		}
+		resultsHash, _ := task.ResultsHash()
+		taskCommitments = append(taskCommitments, orm.TaskCommitment{
+			TaskId:      task.ID,
+			ResultsHash: resultsHash,
+			Proof:       proofJSON,
+		})
+	}
+
+	batch, err := s.commitmentBatchORM.CreateCommitmentBatch(ctx, tree.Root().Hex(), taskCommitments)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("batchId", batch.ID).Str("merkleRoot", batch.MerkleRoot).Int("numTasks", batch.NumTasks).Msg("Commitment batch created")
+	return batch, nil
+}
+
+// CommitPendingTasks periodically commits COMPLETED tasks whose commitment failed when they completed,
+// then batches every newly committed task under a new merkle root
+func (s *CommitmentService) CommitPendingTasks(ctx context.Context) {
+	for range time.Tick(10 * time.Minute) {
+		log.Info().Msg("Checking for uncommitted tasks")
+		startTime := time.Now()
+		numCommitted := 0
+
+		for {
+			tasks, err := s.taskORM.GetUnhashedCompletedTasks(ctx, commitmentHashBatchSize)
+			if err != nil {
+				log.Error().Err(err).Msg("Error finding uncommitted tasks")
+				break
+			}
+
+			numFailed := 0
+			for _, task := range tasks {
+				if err := s.commitTask(ctx, task.ID); err != nil {
+					numFailed++
+					continue
+				}
+				numCommitted++
+			}
+
+			// stop once there is nothing left, or when every task in the batch keeps failing
+			if len(tasks) < commitmentHashBatchSize || numFailed == len(tasks) {
+				break
+			}
+		}
+
+		numBatched := 0
+		for {
+			batch, err := s.CreateCommitmentBatch(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Error creating commitment batch")
+				break
+			}
+			if batch == nil {
+				break
+			}
+			numBatched += batch.NumTasks
+			if batch.NumTasks < maxTasksPerCommitmentBatch {
+				break
+			}
+		}
+
+		log.Info().Int("numCommitted", numCommitted).Int("numBatched", numBatched).Msgf("Total time taken to commit tasks: %s", time.Since(startTime))
+	}
+}
+
+// GetTaskCommitment returns the task's results hash with the leaf of every result and, once batched,
+// the merkle root and the proof of the task's leaf
+func (s *CommitmentService) GetTaskCommitment(ctx context.Context, taskId string) (*TaskCommitmentResponse, error) {
+	task, err := s.taskORM.GetById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	if task.Status != db.TaskStatusCompleted {
+		return nil, ErrTaskNotCommitted
+	}
+
+	// the commitment failed when the task completed, it is retried by the periodic commitment
+	resultsHash, ok := task.ResultsHash()
+	if !ok {
+		return nil, ErrTaskCommitmentPending
+	}
+
+	taskResults, err := s.taskResultORM.GetCompletedTResultByTaskId(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	computedHash, resultCommitments, err := ComputeResultsHash(taskResults)
+	if err != nil {
+		return nil, err
+	}
+	if resultsHash != computedHash.Hex() {
+		log.Error().Str("taskId", taskId).Str("resultsHash", resultsHash).Str("computedHash", computedHash.Hex()).Msg("Task results changed after they were committed")
+		return nil, ErrCommitmentHashMismatch
+	}
+
+	response := &TaskCommitmentResponse{
+		TaskId:       taskId,
+		ResultsHash:  resultsHash,
+		Results:      resultCommitments,
+		TaskLeafHash: TaskLeafHash(taskId, common.HexToHash(resultsHash)).Hex(),
+		Proof:        []string{},
+	}
+
+	batchId, ok := task.CommitmentBatchID()
+	if !ok {
+		return response, nil
+	}
+	batch, err := s.commitmentBatchORM.GetBatchById(ctx, batchId)
+	if err != nil {
+		return nil, err
+	}
+	if proofJSON, ok := task.CommitmentProof(); ok {
+		if err := json.Unmarshal(proofJSON, &response.Proof); err != nil {
+			return nil, err
+		}
+	}
+	response.BatchId = &batch.ID
+	response.MerkleRoot = &batch.MerkleRoot
+	response.CommittedAt = &batch.CreatedAt
+	return response, nil
+}
+
+func (s *CommitmentService) GetCommitmentBatch(ctx context.Context, batchId string) (*CommitmentBatchResponse, error) {
+	batch, err := s.commitmentBatchORM.GetBatchById(ctx, batchId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrCommitmentBatchNotFound
+		}
+		return nil, err
+	}
+
+	return &CommitmentBatchResponse{
+		BatchId:    batch.ID,
+		MerkleRoot: batch.MerkleRoot,
+		NumTasks:   batch.NumTasks,
+		CreatedAt:  batch.CreatedAt,
+	}, nil
+}
+
+func hashesToHex(hashes []common.Hash) []string {
+	hexHashes := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		hexHashes = append(hexHashes, hash.Hex())
+	}
+	return hexHashes
+}
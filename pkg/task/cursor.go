@@ -0,0 +1,52 @@
+package task
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"dojo-api/db"
+)
+
+// TaskCursor is the position of the last task of a page in a keyset paginated listing. It is handed to clients
+// as an opaque string so the ordering can change without breaking them.
+type TaskCursor struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Id        string    `json:"id"`
+	// set by listings that can be sorted by other keys, so a cursor is only used with the sort it was issued for
+	Sort  SortField    `json:"sort,omitempty"`
+	Order db.SortOrder `json:"order,omitempty"`
+	// numResults or yield of the task when sorted by either of them
+	Value float64 `json:"value,omitempty"`
+}
+
+// newWorkerTaskCursor is the cursor of the page after the task in a worker's task listing
+func newWorkerTaskCursor(task db.TaskModel, sort SortField, order db.SortOrder) TaskCursor {
+	cursor := TaskCursor{CreatedAt: task.CreatedAt, Id: task.ID, Sort: sort, Order: order}
+	switch sort {
+	case SortNumResult:
+		cursor.Value = float64(task.NumResults)
+	case SortHighestYield:
+		cursor.Value = TaskYield(task)
+	}
+	return cursor
+}
+
+func EncodeTaskCursor(cursor TaskCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTaskCursor returns ErrInvalidCursor for anything not produced by EncodeTaskCursor
+func DecodeTaskCursor(encoded string) (*TaskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor TaskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
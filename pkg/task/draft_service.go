@@ -0,0 +1,150 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// drafts hold scores and text feedback, anything larger is not a draft of a real result
+const maxDraftSize = 1 << 20
+
+type DraftService struct {
+	taskORM          *orm.TaskORM
+	taskResultORM    *orm.TaskResultORM
+	taskDraftORM     *orm.TaskDraftORM
+	workerPartnerORM *orm.WorkerPartnerORM
+}
+
+func NewDraftService() *DraftService {
+	return &DraftService{
+		taskORM:          orm.NewTaskORM(),
+		taskResultORM:    orm.NewTaskResultORM(),
+		taskDraftORM:     orm.NewTaskDraftORM(),
+		workerPartnerORM: orm.NewWorkerPartnerORM(),
+	}
+}
+
+// SaveDraft stores the worker's partial results of an IN_PROGRESS task until the task expires.
+// Drafts are not validated against the task's criteria, as they are allowed to be incomplete.
+// Once the worker has submitted a result, changes go through a revision instead.
+func (s *DraftService) SaveDraft(ctx context.Context, taskId string, worker *db.DojoWorkerModel, results []Result) (*TaskDraftResponse, error) {
+	task, err := s.getTask(ctx, taskId, worker)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != db.TaskStatusInProgress || task.ExpireAt.Before(time.Now()) {
+		return nil, ErrTaskNotDraftable
+	}
+
+	completedResults, err := s.taskResultORM.GetCompletedTResultByTaskAndWorker(ctx, taskId, worker.ID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Error().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Error checking submitted task result")
+		return nil, err
+	}
+	if len(completedResults) > 0 {
+		return nil, ErrTaskAlreadySubmitted
+	}
+
+	resultData, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	if len(resultData) > maxDraftSize {
+		return nil, ErrDraftTooLarge
+	}
+
+	draft, err := s.taskDraftORM.SaveDraft(ctx, taskId, worker.ID, resultData, task.ExpireAt)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Str("workerId", worker.ID).Msg("Error saving task draft")
+		return nil, err
+	}
+	return buildTaskDraftResponse(draft)
+}
+
+func (s *DraftService) GetDraft(ctx context.Context, taskId string, worker *db.DojoWorkerModel) (*TaskDraftResponse, error) {
+	if _, err := s.getTask(ctx, taskId, worker); err != nil {
+		return nil, err
+	}
+
+	draft, err := s.taskDraftORM.GetDraft(ctx, taskId, worker.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrDraftNotFound
+		}
+		return nil, err
+	}
+	return buildTaskDraftResponse(draft)
+}
+
+// DiscardDraft removes the worker's draft of a task, discarding a draft that does not exist is not an error
+func (s *DraftService) DiscardDraft(ctx context.Context, taskId string, workerId string) error {
+	deleted, err := s.taskDraftORM.DeleteDraft(ctx, taskId, workerId)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		log.Debug().Str("taskId", taskId).Str("workerId", workerId).Msg("Task draft discarded")
+	}
+	return nil
+}
+
+// getTask returns the task if it is listed for the worker, that is the worker is partnered with the task's miner
+// and meets the task's minimum reputation. Tasks of other miners are reported as not found.
+func (s *DraftService) getTask(ctx context.Context, taskId string, worker *db.DojoWorkerModel) (*db.TaskModel, error) {
+	task, err := s.taskORM.GetById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	minerUserIds, err := s.workerPartnerORM.GetPartneredMinerUserIds(ctx, worker.ID)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", worker.ID).Msg("Error getting partnered miners for task draft")
+		return nil, err
+	}
+	if minerUserId, ok := task.MinerUserID(); !ok || !slices.Contains(minerUserIds, minerUserId) {
+		return nil, ErrTaskNotFound
+	}
+
+	if err := CheckMinReputation(task, worker); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// DeleteStaleDrafts periodically removes drafts of tasks that expired or are no longer in progress
+func (s *DraftService) DeleteStaleDrafts(ctx context.Context) {
+	for range time.Tick(10 * time.Minute) {
+		numDeleted, err := s.taskDraftORM.DeleteStaleDrafts(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Error deleting stale task drafts")
+			continue
+		}
+		log.Info().Int("numDeleted", numDeleted).Msg("Deleted stale task drafts")
+	}
+}
+
+func buildTaskDraftResponse(draft *db.TaskDraftModel) (*TaskDraftResponse, error) {
+	var results []Result
+	if err := json.Unmarshal(draft.ResultData, &results); err != nil {
+		log.Error().Err(err).Str("taskId", draft.TaskID).Str("workerId", draft.WorkerID).Msg("Error unmarshaling task draft")
+		return nil, err
+	}
+
+	return &TaskDraftResponse{
+		TaskId:     draft.TaskID,
+		ResultData: results,
+		UpdatedAt:  draft.UpdatedAt,
+		ExpireAt:   draft.ExpireAt,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/cache"
+	"dojo-api/pkg/orm"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// how often a subscriber re-reads the worker's partnerships, so partners added or removed while connected apply
+	taskFeedRefreshInterval = 5 * time.Minute
+	// events a subscriber can fall behind by before new events are dropped
+	taskFeedBufferSize = 64
+)
+
+type TaskFeedEventType string
+
+const (
+	TaskFeedEventCreated TaskFeedEventType = "task-created"
+	TaskFeedEventFilled  TaskFeedEventType = "task-filled"
+	TaskFeedEventExpired TaskFeedEventType = "task-expired"
+)
+
+// TaskFeedEvent is sent to workers as the data of an SSE event named after its type
+type TaskFeedEvent struct {
+	Type       TaskFeedEventType `json:"type"`
+	TaskId     string            `json:"taskId"`
+	Title      string            `json:"title"`
+	Modality   db.TaskModality   `json:"modality"`
+	Status     db.TaskStatus     `json:"status"`
+	NumResults int               `json:"numResults"`
+	MaxResults int               `json:"maxResults"`
+	ExpireAt   time.Time         `json:"expireAt"`
+}
+
+// taskFeedMessage is published on the miner's channel, the minimum reputation decides which workers receive it
+type taskFeedMessage struct {
+	Event         TaskFeedEvent `json:"event"`
+	MinReputation *float64      `json:"minReputation,omitempty"`
+}
+
+// TaskFeedService fans task events out to workers through a redis pub/sub channel per miner, so a worker connected
+// to any API replica receives the events of every miner it is partnered with
+type TaskFeedService struct {
+	workerPartnerORM *orm.WorkerPartnerORM
+	cache            *cache.Cache
+}
+
+func NewTaskFeedService() *TaskFeedService {
+	return &TaskFeedService{
+		workerPartnerORM: orm.NewWorkerPartnerORM(),
+		cache:            cache.GetCacheInstance(),
+	}
+}
+
+// Publish sends a task event to the subscribers of the task's miner
+func (s *TaskFeedService) Publish(ctx context.Context, eventType TaskFeedEventType, task *db.TaskModel) error {
+	minerUserId, ok := task.MinerUserID()
+	if !ok {
+		return nil
+	}
+
+	message := taskFeedMessage{
+		Event: TaskFeedEvent{
+			Type:       eventType,
+			TaskId:     task.ID,
+			Title:      task.Title,
+			Modality:   task.Modality,
+			Status:     task.Status,
+			NumResults: task.NumResults,
+			MaxResults: task.MaxResults,
+			ExpireAt:   task.ExpireAt,
+		},
+	}
+	if minReputation, ok := task.MinReputation(); ok {
+		message.MinReputation = &minReputation
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return s.cache.Redis.Publish(ctx, s.minerChannel(minerUserId), data).Err()
+}
+
+// PublishTasksExpired is called with each batch of tasks the expiry loop marks as EXPIRED
+func (s *TaskFeedService) PublishTasksExpired(ctx context.Context, tasks []db.TaskModel) {
+	for i := range tasks {
+		if err := s.Publish(ctx, TaskFeedEventExpired, &tasks[i]); err != nil {
+			log.Error().Err(err).Str("taskId", tasks[i].ID).Msg("Failed to publish expired task to task feed")
+		}
+	}
+}
+
+// Subscribe streams the events of tasks the worker is allowed to see until ctx is done, the returned channel is
+// closed when the subscription ends
+func (s *TaskFeedService) Subscribe(ctx context.Context, worker *db.DojoWorkerModel) (<-chan TaskFeedEvent, error) {
+	minerUserIds, err := s.workerPartnerORM.GetPartneredMinerUserIds(ctx, worker.ID)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", worker.ID).Msg("Error getting partnered miners for task feed")
+		return nil, err
+	}
+
+	channels := s.minerChannels(minerUserIds)
+	channelNames := make([]string, 0, len(channels))
+	for channel := range channels {
+		channelNames = append(channelNames, channel)
+	}
+	pubsub := s.cache.Redis.Subscribe(ctx, channelNames...)
+
+	events := make(chan TaskFeedEvent, taskFeedBufferSize)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+
+		refreshTicker := time.NewTicker(taskFeedRefreshInterval)
+		defer refreshTicker.Stop()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-refreshTicker.C:
+				channels = s.refreshSubscriptions(ctx, pubsub, worker.ID, channels)
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var message taskFeedMessage
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+					log.Warn().Err(err).Str("channel", msg.Channel).Msg("Invalid task feed message")
+					continue
+				}
+				if message.MinReputation != nil && *message.MinReputation > worker.Reputation {
+					continue
+				}
+
+				select {
+				case events <- message.Event:
+				default:
+					log.Warn().Str("workerId", worker.ID).Str("taskId", message.Event.TaskId).Msg("Task feed subscriber is falling behind, dropping event")
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// refreshSubscriptions subscribes to miners the worker partnered with and unsubscribes from partnerships that were
+// removed since the last refresh, keeping the current channels if the partnerships cannot be read
+func (s *TaskFeedService) refreshSubscriptions(ctx context.Context, pubsub *redis.PubSub, workerId string, current map[string]bool) map[string]bool {
+	minerUserIds, err := s.workerPartnerORM.GetPartneredMinerUserIds(ctx, workerId)
+	if err != nil {
+		log.Warn().Err(err).Str("workerId", workerId).Msg("Error refreshing partnered miners for task feed")
+		return current
+	}
+	updated := s.minerChannels(minerUserIds)
+
+	var added, removed []string
+	for channel := range updated {
+		if !current[channel] {
+			added = append(added, channel)
+		}
+	}
+	for channel := range current {
+		if !updated[channel] {
+			removed = append(removed, channel)
+		}
+	}
+
+	// unsubscribing without channels would unsubscribe from all of them
+	if len(added) > 0 {
+		if err := pubsub.Subscribe(ctx, added...); err != nil {
+			log.Warn().Err(err).Str("workerId", workerId).Msg("Error subscribing to task feed channels")
+			return current
+		}
+	}
+	if len(removed) > 0 {
+		if err := pubsub.Unsubscribe(ctx, removed...); err != nil {
+			log.Warn().Err(err).Str("workerId", workerId).Msg("Error unsubscribing from task feed channels")
+		}
+	}
+	return updated
+}
+
+func (s *TaskFeedService) minerChannel(minerUserId string) string {
+	return s.cache.BuildCacheKey(s.cache.Keys.TaskFeed, minerUserId)
+}
+
+func (s *TaskFeedService) minerChannels(minerUserIds []string) map[string]bool {
+	channels := make(map[string]bool, len(minerUserIds))
+	for _, minerUserId := range minerUserIds {
+		channels[s.minerChannel(minerUserId)] = true
+	}
+	return channels
+}
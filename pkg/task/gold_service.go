@@ -0,0 +1,307 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultGoldFailWindow    = 10
+	defaultGoldFailThreshold = 3
+)
+
+type GoldService struct {
+	goldTaskGradeORM *orm.GoldTaskGradeORM
+	dojoWorkerORM    *orm.DojoWorkerORM
+	// a worker is flagged once failThreshold of their last failWindow gold grades are fails
+	failWindow    int
+	failThreshold int
+}
+
+func NewGoldService() *GoldService {
+	return &GoldService{
+		goldTaskGradeORM: orm.NewGoldTaskGradeORM(),
+		dojoWorkerORM:    orm.NewDojoWorkerORM(),
+		failWindow:       getPositiveIntEnv("GOLD_FAIL_WINDOW", defaultGoldFailWindow),
+		failThreshold:    getPositiveIntEnv("GOLD_FAIL_THRESHOLD", defaultGoldFailThreshold),
+	}
+}
+
+func getPositiveIntEnv(key string, defaultValue int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value <= 0 {
+		log.Warn().Str(key, valueStr).Msg("Invalid value, using default")
+		return defaultValue
+	}
+	return value
+}
+
+// GradeSubmission grades a worker's result on a gold task against its expected answers and records a pass/fail,
+// flagging the worker if they keep failing. Does nothing for tasks that are not gold.
+func (s *GoldService) GradeSubmission(ctx context.Context, task *db.TaskModel, taskResult *db.TaskResultModel, results []Result) (*db.GoldTaskGradeModel, error) {
+	goldAnswersJSON, ok := task.GoldAnswers()
+	if !task.IsGold || !ok {
+		return nil, nil
+	}
+
+	var goldAnswers GoldAnswers
+	if err := json.Unmarshal(goldAnswersJSON, &goldAnswers); err != nil {
+		log.Error().Err(err).Str("taskId", task.ID).Msg("Error unmarshaling gold answers")
+		return nil, err
+	}
+
+	passed, criteriaGrades := GradeGoldResults(goldAnswers, results)
+	details, err := json.Marshal(criteriaGrades)
+	if err != nil {
+		return nil, err
+	}
+
+	grade, err := s.goldTaskGradeORM.CreateGrade(ctx, task.ID, taskResult.WorkerID, taskResult.ID, passed, details)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", task.ID).Str("workerId", taskResult.WorkerID).Msg("Error creating gold task grade")
+		return nil, err
+	}
+	log.Info().Str("taskId", task.ID).Str("workerId", taskResult.WorkerID).Bool("passed", passed).Msg("Gold task graded")
+
+	if !passed {
+		if err := s.flagIfRepeatedlyFailing(ctx, taskResult.WorkerID); err != nil {
+			log.Error().Err(err).Str("workerId", taskResult.WorkerID).Msg("Error checking worker gold task grades")
+		}
+	}
+
+	return grade, nil
+}
+
+func (s *GoldService) flagIfRepeatedlyFailing(ctx context.Context, workerId string) error {
+	grades, err := s.goldTaskGradeORM.GetRecentGradesByWorker(ctx, workerId, s.failWindow)
+	if err != nil {
+		return err
+	}
+
+	numFails := 0
+	for _, grade := range grades {
+		if !grade.Passed {
+			numFails++
+		}
+	}
+
+	if numFails < s.failThreshold {
+		return nil
+	}
+
+	if _, err := s.dojoWorkerORM.FlagDojoWorker(ctx, workerId); err != nil {
+		return err
+	}
+	log.Warn().Str("workerId", workerId).Int("numFails", numFails).Int("window", len(grades)).Msg("Worker flagged for repeatedly failing gold tasks")
+	return nil
+}
+
+// GradeGoldResults checks every expected answer against the submitted results, the result passes only if all criteria pass
+func GradeGoldResults(goldAnswers GoldAnswers, results []Result) (bool, []GoldCriteriaGrade) {
+	submitted := make(map[string]map[string]Criteria)
+	for _, result := range results {
+		submitted[result.Model] = make(map[string]Criteria)
+		for _, criteria := range result.Criteria {
+			submitted[result.Model][criteria.GetId()] = criteria
+		}
+	}
+
+	// grade in a stable order so the recorded details are deterministic
+	models := make([]string, 0, len(goldAnswers))
+	for model := range goldAnswers {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	passed := true
+	grades := make([]GoldCriteriaGrade, 0)
+	for _, model := range models {
+		criteriaIds := make([]string, 0, len(goldAnswers[model]))
+		for criteriaId := range goldAnswers[model] {
+			criteriaIds = append(criteriaIds, criteriaId)
+		}
+		sort.Strings(criteriaIds)
+
+		for _, criteriaId := range criteriaIds {
+			grade := GoldCriteriaGrade{Model: model, CriteriaId: criteriaId, Passed: true}
+			criteria, ok := submitted[model][criteriaId]
+			if !ok {
+				grade.Passed, grade.Reason = false, "criteria not submitted"
+			} else if err := gradeGoldCriteria(goldAnswers[model][criteriaId], criteria); err != nil {
+				grade.Passed, grade.Reason = false, err.Error()
+			}
+
+			passed = passed && grade.Passed
+			grades = append(grades, grade)
+		}
+	}
+
+	return passed, grades
+}
+
+func gradeGoldCriteria(expected GoldExpectation, criteria Criteria) error {
+	switch submitted := criteria.(type) {
+	case ScoreCriteria:
+		if expected.Score == nil {
+			return fmt.Errorf("no expected score range")
+		}
+		score := submitted.MinerScore
+		if submitted.RawScore != nil {
+			score = *submitted.RawScore
+		}
+		return checkGoldRange(*expected.Score, score, "score")
+	case MultiScoreCriteria:
+		scores := submitted.RawValue
+		if scores == nil {
+			scores = submitted.Value
+		}
+		for option, expectedRange := range expected.Options {
+			if err := checkGoldRange(expectedRange, scores[option], "score of "+option); err != nil {
+				return err
+			}
+		}
+	case RankingCriteria:
+		ranks := make(map[string]float64, len(submitted.Value))
+		for rankStr, option := range submitted.Value {
+			rank, _ := strconv.Atoi(rankStr)
+			ranks[option] = float64(rank)
+		}
+		for option, expectedRange := range expected.Options {
+			if err := checkGoldRange(expectedRange, ranks[option], "rank of "+option); err != nil {
+				return err
+			}
+		}
+	case MultiSelectCriteria:
+		selected := make(map[string]bool, len(submitted.Value))
+		for _, option := range submitted.Value {
+			selected[option] = true
+		}
+		if len(selected) != len(expected.Selected) {
+			return fmt.Errorf("expected %d selected options, got %d", len(expected.Selected), len(selected))
+		}
+		for _, option := range expected.Selected {
+			if !selected[option] {
+				return fmt.Errorf("expected option %s to be selected", option)
+			}
+		}
+	default:
+		return fmt.Errorf("criteria type %s cannot be graded", criteria.GetType())
+	}
+	return nil
+}
+
+func checkGoldRange(expected GoldRange, value float64, label string) error {
+	if value < expected.Min || value > expected.Max {
+		return fmt.Errorf("%s %v is outside the expected range [%v, %v]", label, value, expected.Min, expected.Max)
+	}
+	return nil
+}
+
+// validateGoldAnswers ensures every expected answer refers to a gradable criteria of the task, by its miner provided ID
+func validateGoldAnswers(taskData TaskData) error {
+	if len(taskData.GoldAnswers) == 0 {
+		return fmt.Errorf("goldAnswers is required for gold tasks")
+	}
+
+	for model, expectations := range taskData.GoldAnswers {
+		var response *ModelResponse
+		for i := range taskData.Responses {
+			if taskData.Responses[i].Model == model {
+				response = &taskData.Responses[i]
+				break
+			}
+		}
+		if response == nil {
+			return fmt.Errorf("gold answer model %s not found in responses", model)
+		}
+
+		for criteriaId, expected := range expectations {
+			var criteria Criteria
+			for _, c := range response.Criteria {
+				if c.GetId() != "" && c.GetId() == criteriaId {
+					criteria = c
+					break
+				}
+			}
+			if criteria == nil {
+				return fmt.Errorf("gold answer criteria %s not found for model %s, gold criteria require an id", criteriaId, model)
+			}
+
+			if err := validateGoldExpectation(expected, criteria); err != nil {
+				return fmt.Errorf("invalid gold answer for criteria %s of model %s: %w", criteriaId, model, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateGoldExpectation(expected GoldExpectation, criteria Criteria) error {
+	switch c := criteria.(type) {
+	case ScoreCriteria:
+		if expected.Score == nil {
+			return fmt.Errorf("score range is required")
+		}
+		return validateGoldRange(*expected.Score, c.Min, c.Max)
+	case MultiScoreCriteria:
+		return validateGoldOptionRanges(expected.Options, c.Options, c.Min, c.Max)
+	case RankingCriteria:
+		return validateGoldOptionRanges(expected.Options, c.Options, 1, float64(len(c.Options)))
+	case MultiSelectCriteria:
+		validOptions := make(map[string]bool, len(c.Options))
+		for _, option := range c.Options {
+			validOptions[option] = true
+		}
+		for _, option := range expected.Selected {
+			if !validOptions[option] {
+				return fmt.Errorf("selected option %s is not one of the criteria options", option)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("criteria type %s cannot be graded", criteria.GetType())
+	}
+}
+
+func validateGoldOptionRanges(expected map[string]GoldRange, options []string, scaleMin, scaleMax float64) error {
+	if len(expected) == 0 {
+		return fmt.Errorf("option ranges are required")
+	}
+
+	validOptions := make(map[string]bool, len(options))
+	for _, option := range options {
+		validOptions[option] = true
+	}
+	for option, expectedRange := range expected {
+		if !validOptions[option] {
+			return fmt.Errorf("option %s is not one of the criteria options", option)
+		}
+		if err := validateGoldRange(expectedRange, scaleMin, scaleMax); err != nil {
+			return fmt.Errorf("option %s: %w", option, err)
+		}
+	}
+	return nil
+}
+
+func validateGoldRange(expected GoldRange, scaleMin, scaleMax float64) error {
+	if expected.Min > expected.Max {
+		return fmt.Errorf("range min must not be greater than max")
+	}
+	if expected.Min < scaleMin || expected.Max > scaleMax {
+		return fmt.Errorf("range [%v, %v] must be within [%v, %v]", expected.Min, expected.Max, scaleMin, scaleMax)
+	}
+	return nil
+}
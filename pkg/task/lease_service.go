@@ -0,0 +1,235 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/cache"
+	"dojo-api/pkg/orm"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultLeaseTTL = 15 * time.Minute
+
+var (
+	ErrTaskNotLeasable      = errors.New("only in progress tasks can be leased")
+	ErrNoLeaseSlotAvailable = errors.New("all result slots for this task are reserved by other workers")
+)
+
+// Each task has a sorted set of worker IDs scored by lease expiry (unix ms), and a global
+// index sorted set of task IDs scored by their latest lease expiry so saturated tasks can be found cheaply.
+//
+// KEYS[1] = task lease set, KEYS[2] = lease index
+// ARGV[1] = now, ARGV[2] = lease expiry, ARGV[3] = worker ID, ARGV[4] = remaining slots, ARGV[5] = task ID
+var claimLeaseScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if not redis.call('ZSCORE', KEYS[1], ARGV[3]) then
+	if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[4]) then
+		return 0
+	end
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+redis.call('PEXPIREAT', KEYS[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[5])
+return 1
+`)
+
+type LeaseService struct {
+	taskORM     *orm.TaskORM
+	taskOpenORM *orm.TaskOpenORM
+	cache       *cache.Cache
+	ttl         time.Duration
+}
+
+func NewLeaseService() *LeaseService {
+	ttl := defaultLeaseTTL
+	if ttlStr := os.Getenv("TASK_LEASE_TTL_SECONDS"); ttlStr != "" {
+		if seconds, err := strconv.Atoi(ttlStr); err == nil && seconds > 0 {
+			ttl = time.Duration(seconds) * time.Second
+		} else {
+			log.Warn().Str("TASK_LEASE_TTL_SECONDS", ttlStr).Msg("Invalid lease TTL, using default")
+		}
+	}
+
+	return &LeaseService{
+		taskORM:     orm.NewTaskORM(),
+		taskOpenORM: orm.NewTaskOpenORM(),
+		cache:       cache.GetCacheInstance(),
+		ttl:         ttl,
+	}
+}
+
+func (s *LeaseService) leaseKey(taskId string) string {
+	return s.cache.BuildCacheKey(s.cache.Keys.TaskLease, taskId)
+}
+
+// ClaimLease reserves one of the task's remaining result slots for the worker.
+// Claiming again while holding a lease extends it.
+func (s *LeaseService) ClaimLease(ctx context.Context, task *db.TaskModel, worker *db.DojoWorkerModel) (*TaskLease, error) {
+	if task.Status != db.TaskStatusInProgress || task.ExpireAt.Before(time.Now()) {
+		return nil, ErrTaskNotLeasable
+	}
+	if err := CheckMinReputation(task, worker); err != nil {
+		return nil, err
+	}
+	workerId := worker.ID
+
+	now := time.Now()
+	expireAt := now.Add(s.ttl)
+	// never hold a slot past the task's own expiry
+	if task.ExpireAt.Before(expireAt) {
+		expireAt = task.ExpireAt
+	}
+
+	remainingSlots := task.MaxResults - task.NumResults
+	claimed, err := claimLeaseScript.Run(ctx, &s.cache.Redis,
+		[]string{s.leaseKey(task.ID), string(s.cache.Keys.TaskLeaseIndex)},
+		now.UnixMilli(), expireAt.UnixMilli(), workerId, remainingSlots, task.ID,
+	).Int()
+	if err != nil {
+		log.Error().Err(err).Str("taskId", task.ID).Str("workerId", workerId).Msg("Failed to claim task lease")
+		return nil, err
+	}
+
+	if claimed == 0 {
+		return nil, ErrNoLeaseSlotAvailable
+	}
+
+	// claiming counts as opening the task, so time on task is measured for workers that never fetched it signed in
+	if err := s.taskOpenORM.RecordOpen(ctx, task.ID, workerId); err != nil {
+		log.Warn().Err(err).Str("taskId", task.ID).Str("workerId", workerId).Msg("Failed to record task open on lease claim")
+	}
+
+	log.Info().Str("taskId", task.ID).Str("workerId", workerId).Time("expireAt", expireAt).Msg("Task lease claimed")
+	return &TaskLease{TaskId: task.ID, WorkerId: workerId, ExpireAt: expireAt}, nil
+}
+
+// ReleaseLease gives up the worker's lease on the task, if any
+func (s *LeaseService) ReleaseLease(ctx context.Context, taskId string, workerId string) error {
+	if err := s.cache.Redis.ZRem(ctx, s.leaseKey(taskId), workerId).Err(); err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Str("workerId", workerId).Msg("Failed to release task lease")
+		return err
+	}
+	return nil
+}
+
+// ReleaseTaskLeases drops every lease on the task, for tasks that stop accepting results
+func (s *LeaseService) ReleaseTaskLeases(ctx context.Context, taskId string) error {
+	pipe := s.cache.Redis.TxPipeline()
+	pipe.Del(ctx, s.leaseKey(taskId))
+	pipe.ZRem(ctx, string(s.cache.Keys.TaskLeaseIndex), taskId)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Error().Err(err).Str("taskId", taskId).Msg("Failed to release task leases")
+		return err
+	}
+	return nil
+}
+
+// GetLeaseHolders returns the workers other than workerId holding an active lease on the task, and whether
+// workerId holds one itself
+func (s *LeaseService) GetLeaseHolders(ctx context.Context, taskId string, workerId string) ([]string, bool, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	holders, err := s.cache.Redis.ZRangeByScore(ctx, s.leaseKey(taskId), &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	otherHolders := make([]string, 0, len(holders))
+	isHolder := false
+	for _, holder := range holders {
+		if holder == workerId {
+			isHolder = true
+			continue
+		}
+		otherHolders = append(otherHolders, holder)
+	}
+	return otherHolders, isHolder, nil
+}
+
+// CanSubmit reports whether the worker may submit a result for the task. Lease holders can always submit;
+// other workers can only submit while the task still has slots not reserved by active leases.
+// The same check is enforced again when the result is inserted, see TaskResultORM.CreateTaskResultWithCompleted.
+func (s *LeaseService) CanSubmit(ctx context.Context, task *db.TaskModel, workerId string) (bool, error) {
+	otherHolders, isHolder, err := s.GetLeaseHolders(ctx, task.ID, workerId)
+	if err != nil {
+		return false, err
+	}
+
+	if isHolder {
+		return true, nil
+	}
+	return task.NumResults+len(otherHolders) < task.MaxResults, nil
+}
+
+// GetSaturatedTaskIds returns the IDs of in-progress tasks whose remaining slots are all reserved
+// by other workers' leases, so they can be hidden from the worker. The leases of every leased task
+// are read in a single pipeline.
+func (s *LeaseService) GetSaturatedTaskIds(ctx context.Context, workerId string) ([]string, error) {
+	indexKey := string(s.cache.Keys.TaskLeaseIndex)
+	nowMillis := time.Now().UnixMilli()
+	now := strconv.FormatInt(nowMillis, 10)
+
+	pipe := s.cache.Redis.Pipeline()
+	pipe.ZRemRangeByScore(ctx, indexKey, "-inf", now)
+	leasedCmd := pipe.ZRange(ctx, indexKey, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	leasedTaskIds := leasedCmd.Val()
+	if len(leasedTaskIds) == 0 {
+		return []string{}, nil
+	}
+
+	tasks, err := s.taskORM.GetByIds(ctx, leasedTaskIds)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching leased tasks")
+		return nil, err
+	}
+
+	// count each in progress task's active leases and whether the worker holds one of them
+	type taskLeases struct {
+		task     db.TaskModel
+		countCmd *redis.IntCmd
+		heldCmd  *redis.FloatCmd
+	}
+	leases := make([]taskLeases, 0, len(tasks))
+	pipe = s.cache.Redis.Pipeline()
+	for _, task := range tasks {
+		if task.Status != db.TaskStatusInProgress {
+			continue
+		}
+		key := s.leaseKey(task.ID)
+		leases = append(leases, taskLeases{
+			task:     task,
+			countCmd: pipe.ZCount(ctx, key, "("+now, "+inf"),
+			heldCmd:  pipe.ZScore(ctx, key, workerId),
+		})
+	}
+	if len(leases) == 0 {
+		return []string{}, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	saturatedTaskIds := make([]string, 0)
+	for _, lease := range leases {
+		numLeases := int(lease.countCmd.Val())
+		if heldUntil, err := lease.heldCmd.Result(); err == nil && heldUntil > float64(nowMillis) {
+			continue
+		}
+		if lease.task.NumResults+numLeases >= lease.task.MaxResults {
+			saturatedTaskIds = append(saturatedTaskIds, lease.task.ID)
+		}
+	}
+
+	log.Debug().Interface("saturatedTaskIds", saturatedTaskIds).Str("workerId", workerId).Msg("Tasks saturated by leases")
+	return saturatedTaskIds, nil
+}
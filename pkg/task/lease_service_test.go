@@ -0,0 +1,151 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/internal/testutil"
+)
+
+func TestLeaseSaturation(t *testing.T) {
+	client := testutil.NewClient(t)
+	ctx := context.Background()
+
+	task := testutil.CreateTask(t, client, 2)
+	holderA, holderB, other := testutil.CreateWorker(t, client), testutil.CreateWorker(t, client), testutil.CreateWorker(t, client)
+	leaseService := NewLeaseService()
+
+	if _, err := leaseService.ClaimLease(ctx, task, holderA); err != nil {
+		t.Fatalf("ClaimLease() error = %v", err)
+	}
+	// claiming again extends the lease instead of taking another slot
+	if _, err := leaseService.ClaimLease(ctx, task, holderA); err != nil {
+		t.Fatalf("ClaimLease() again error = %v", err)
+	}
+	if _, err := leaseService.ClaimLease(ctx, task, holderB); err != nil {
+		t.Fatalf("ClaimLease() error = %v", err)
+	}
+	if _, err := leaseService.ClaimLease(ctx, task, other); !errors.Is(err, ErrNoLeaseSlotAvailable) {
+		t.Errorf("ClaimLease() on a saturated task error = %v, want %v", err, ErrNoLeaseSlotAvailable)
+	}
+
+	tests := []struct {
+		worker        *db.DojoWorkerModel
+		wantSubmit    bool
+		wantSaturated bool
+	}{
+		{holderA, true, false},
+		{holderB, true, false},
+		{other, false, true},
+	}
+	for _, tt := range tests {
+		canSubmit, err := leaseService.CanSubmit(ctx, task, tt.worker.ID)
+		if err != nil {
+			t.Fatalf("CanSubmit() error = %v", err)
+		}
+		if canSubmit != tt.wantSubmit {
+			t.Errorf("CanSubmit(%s) = %v, want %v", tt.worker.ID, canSubmit, tt.wantSubmit)
+		}
+
+		saturatedTaskIds, err := leaseService.GetSaturatedTaskIds(ctx, tt.worker.ID)
+		if err != nil {
+			t.Fatalf("GetSaturatedTaskIds() error = %v", err)
+		}
+		if saturated := slices.Contains(saturatedTaskIds, task.ID); saturated != tt.wantSaturated {
+			t.Errorf("task saturated for %s = %v, want %v", tt.worker.ID, saturated, tt.wantSaturated)
+		}
+	}
+
+	if err := leaseService.ReleaseLease(ctx, task.ID, holderB.ID); err != nil {
+		t.Fatalf("ReleaseLease() error = %v", err)
+	}
+	canSubmit, err := leaseService.CanSubmit(ctx, task, other.ID)
+	if err != nil {
+		t.Fatalf("CanSubmit() error = %v", err)
+	}
+	if !canSubmit {
+		t.Error("CanSubmit() = false after a lease was released, want true")
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	client := testutil.NewClient(t)
+	ctx := context.Background()
+	t.Setenv("TASK_LEASE_TTL_SECONDS", "1")
+
+	task := testutil.CreateTask(t, client, 1)
+	holder, other := testutil.CreateWorker(t, client), testutil.CreateWorker(t, client)
+	leaseService := NewLeaseService()
+
+	lease, err := leaseService.ClaimLease(ctx, task, holder)
+	if err != nil {
+		t.Fatalf("ClaimLease() error = %v", err)
+	}
+	if ttl := time.Until(lease.ExpireAt); ttl > time.Second {
+		t.Errorf("lease expires in %s, want at most 1s", ttl)
+	}
+
+	saturatedTaskIds, err := leaseService.GetSaturatedTaskIds(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("GetSaturatedTaskIds() error = %v", err)
+	}
+	if !slices.Contains(saturatedTaskIds, task.ID) {
+		t.Error("task not saturated while leased")
+	}
+
+	time.Sleep(time.Until(lease.ExpireAt) + 100*time.Millisecond)
+
+	saturatedTaskIds, err = leaseService.GetSaturatedTaskIds(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("GetSaturatedTaskIds() error = %v", err)
+	}
+	if slices.Contains(saturatedTaskIds, task.ID) {
+		t.Error("task still saturated after the lease expired")
+	}
+	canSubmit, err := leaseService.CanSubmit(ctx, task, other.ID)
+	if err != nil {
+		t.Fatalf("CanSubmit() error = %v", err)
+	}
+	if !canSubmit {
+		t.Error("CanSubmit() = false after the lease expired, want true")
+	}
+	// the expired lease no longer reserves the slot, so another worker can claim it
+	if _, err := leaseService.ClaimLease(ctx, task, other); err != nil {
+		t.Errorf("ClaimLease() after expiry error = %v", err)
+	}
+}
+
+func TestReleaseTaskLeases(t *testing.T) {
+	client := testutil.NewClient(t)
+	ctx := context.Background()
+
+	task := testutil.CreateTask(t, client, 1)
+	holder, other := testutil.CreateWorker(t, client), testutil.CreateWorker(t, client)
+	leaseService := NewLeaseService()
+
+	if _, err := leaseService.ClaimLease(ctx, task, holder); err != nil {
+		t.Fatalf("ClaimLease() error = %v", err)
+	}
+	if err := leaseService.ReleaseTaskLeases(ctx, task.ID); err != nil {
+		t.Fatalf("ReleaseTaskLeases() error = %v", err)
+	}
+
+	holders, isHolder, err := leaseService.GetLeaseHolders(ctx, task.ID, holder.ID)
+	if err != nil {
+		t.Fatalf("GetLeaseHolders() error = %v", err)
+	}
+	if isHolder || len(holders) != 0 {
+		t.Errorf("GetLeaseHolders() = %v, %v after releasing the task's leases, want none", holders, isHolder)
+	}
+	saturatedTaskIds, err := leaseService.GetSaturatedTaskIds(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("GetSaturatedTaskIds() error = %v", err)
+	}
+	if slices.Contains(saturatedTaskIds, task.ID) {
+		t.Error("task still saturated after releasing its leases")
+	}
+}
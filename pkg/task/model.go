@@ -26,6 +26,8 @@ type TaskResponse struct {
 type TaskPaginationResponse struct {
 	TaskResponse
 	IsCompletedByWorker bool `json:"isCompletedByWorker"`
+	// total reward per result, 0 for tasks without a reward
+	Yield float64 `json:"yield"`
 }
 
 type SortField string
@@ -37,8 +39,45 @@ const (
 	SENTINEL_VALUE   float64   = -math.MaxFloat64
 )
 
+// tolerance for floating point error when checking a score is on a step increment
+const scoreStepTolerance = 1e-9
+
+var (
+	ErrTaskNotFound        = errors.New("task not found")
+	ErrTaskNotOwnedByMiner = errors.New("task does not belong to miner")
+	ErrTaskNotCancellable  = errors.New("only in progress tasks can be cancelled")
+
+	ErrInsufficientReputation = errors.New("task requires a higher reputation")
+
+	ErrInvalidResultSignature = errors.New("invalid result signature")
+
+	ErrDraftNotFound        = errors.New("draft not found")
+	ErrTaskNotDraftable     = errors.New("drafts can only be saved while the task is in progress")
+	ErrDraftTooLarge        = errors.New("draft is too large")
+	ErrTaskAlreadySubmitted = errors.New("task result is already submitted, revise it instead")
+
+	ErrTaskNotCommitted        = errors.New("task results are not committed until the task is completed")
+	ErrTaskCommitmentPending   = errors.New("task results have not been committed yet")
+	ErrCommitmentBatchNotFound = errors.New("commitment batch not found")
+	ErrCommitmentHashMismatch  = errors.New("task results do not match their commitment")
+
+	ErrInvalidTieHandling  = errors.New("ties must be skip or keep")
+	ErrInvalidTieMargin    = errors.New("tieMargin must be between 0 and 1")
+	ErrInvalidMinAgreement = errors.New("minAgreement must be between 0 and 1")
+	ErrInvalidMinRaters    = errors.New("minRaters must be a positive integer")
+
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	ErrInvalidSortField  = errors.New("sort must be createdAt, numResult or highestYield")
+	ErrInvalidYieldRange = errors.New("yieldMin must not be greater than yieldMax")
+)
+
 var ValidTaskModalities = []db.TaskModality{db.TaskModalityCodeGeneration, db.TaskModalityImage, db.TaskModalityThreeD}
 
+var ValidTaskStatuses = []db.TaskStatus{db.TaskStatusInProgress, db.TaskStatusCompleted, db.TaskStatusExpired, db.TaskStatusCancelled}
+
+var ValidSortFields = []SortField{SortCreatedAt, SortNumResult, SortHighestYield}
+
 type Pagination struct {
 	Page       int `json:"pageNumber"`
 	Limit      int `json:"pageSize"`
@@ -47,8 +86,13 @@ type Pagination struct {
 }
 
 type TaskPagination struct {
-	Tasks      []TaskPaginationResponse `json:"tasks"`
-	Pagination Pagination               `json:"pagination"`
+	Tasks []TaskPaginationResponse `json:"tasks"`
+	// only set for page based requests, kept for clients that predate cursors
+	Pagination *Pagination `json:"pagination,omitempty"`
+	// empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+	// only set for cursor based requests with includeTotal
+	TotalItems *int `json:"totalItems,omitempty"`
 }
 
 type CreateTaskRequest struct {
@@ -58,12 +102,43 @@ type CreateTaskRequest struct {
 	TaskData     []TaskData  `json:"taskData"`
 	MaxResults   int         `json:"maxResults"`
 	TotalRewards float64     `json:"totalRewards"`
+	IsGold       bool        `json:"isGold"`
+	// optional, between 0 and 1
+	MinReputation *float64 `json:"minReputation,omitempty"`
 }
 
 type TaskData struct {
 	Prompt       string          `json:"prompt"`
 	Responses    []ModelResponse `json:"responses,omitempty"`
 	TaskModality db.TaskModality `json:"task_modality"`
+	// only set on gold task requests, stored separately from the task data so workers never see it
+	GoldAnswers GoldAnswers `json:"goldAnswers,omitempty"`
+}
+
+// GoldAnswers holds the expected answers of a gold task, keyed by model then criteria ID
+type GoldAnswers map[string]map[string]GoldExpectation
+
+// GoldExpectation is the expected answer for a single criteria, ranges are inclusive and on the worker's input scale
+type GoldExpectation struct {
+	// score criteria
+	Score *GoldRange `json:"score,omitempty"`
+	// multi-score criteria score, or ranking criteria rank, of each option
+	Options map[string]GoldRange `json:"options,omitempty"`
+	// multi-select criteria, the exact set of options to be selected
+	Selected []string `json:"selected,omitempty"`
+}
+
+type GoldRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// GoldCriteriaGrade is the outcome of grading a single criteria of a gold task result
+type GoldCriteriaGrade struct {
+	Model      string `json:"model"`
+	CriteriaId string `json:"criteriaId"`
+	Passed     bool   `json:"passed"`
+	Reason     string `json:"reason,omitempty"`
 }
 
 type ModelResponse struct {
@@ -85,28 +160,71 @@ type Message struct {
 
 type Criteria interface {
 	GetType() CriteriaType
+	GetId() string
 	Validate() error
 }
 
+// ScoreCriteria is rated by the worker on the input scale [Min, Max] in increments of Step,
+// and stored normalised onto the output scale [OutputMin, OutputMax]. The output scale defaults to the input scale.
 type ScoreCriteria struct {
+	ID         string       `json:"id,omitempty"`
 	Type       CriteriaType `json:"type"`
 	Min        float64      `json:"min,omitempty"`
 	Max        float64      `json:"max,omitempty"`
+	Step       float64      `json:"step,omitempty"`
+	OutputMin  float64      `json:"output_min,omitempty"`
+	OutputMax  float64      `json:"output_max,omitempty"`
 	Text       string       `json:"text,omitempty"`
 	MinerScore float64      `json:"value,omitempty"`
+	RawScore   *float64     `json:"raw_value,omitempty"`
 }
 
 type TextCriteria struct {
+	ID           string       `json:"id,omitempty"`
 	Type         CriteriaType `json:"type"`
 	Query        string       `json:"query,omitempty"`
 	TextFeedback string       `json:"text_feedback"`
 }
 
+// RankingCriteria orders a set of options, e.g. the models of a task, from best to worst.
+// The submitted Value maps each rank position, starting from "1", to an option.
+type RankingCriteria struct {
+	ID      string       `json:"id,omitempty"`
+	Type    CriteriaType `json:"type"`
+	Options []string     `json:"options"`
+	Value   RankingValue `json:"value,omitempty"`
+}
+
+// MultiScoreCriteria scores each option independently, using the same scales as ScoreCriteria
+type MultiScoreCriteria struct {
+	ID        string          `json:"id,omitempty"`
+	Type      CriteriaType    `json:"type"`
+	Options   []string        `json:"options"`
+	Min       float64         `json:"min,omitempty"`
+	Max       float64         `json:"max,omitempty"`
+	Step      float64         `json:"step,omitempty"`
+	OutputMin float64         `json:"output_min,omitempty"`
+	OutputMax float64         `json:"output_max,omitempty"`
+	Value     MultiScoreValue `json:"value,omitempty"`
+	RawValue  MultiScoreValue `json:"raw_value,omitempty"`
+}
+
+// MultiSelectCriteria selects any number of labels from Options
+type MultiSelectCriteria struct {
+	ID      string           `json:"id,omitempty"`
+	Type    CriteriaType     `json:"type"`
+	Options []string         `json:"options"`
+	Value   MultiSelectValue `json:"value,omitempty"`
+}
+
 type CriteriaType string
 
 const (
-	CriteriaTypeScore CriteriaType = "score"
-	CriteriaTypeText  CriteriaType = "text"
+	CriteriaTypeScore       CriteriaType = "score"
+	CriteriaTypeText        CriteriaType = "text"
+	CriteriaTypeRanking     CriteriaType = "ranking"
+	CriteriaTypeMultiScore  CriteriaType = "multi-score"
+	CriteriaTypeMultiSelect CriteriaType = "multi-select"
 )
 
 type Result struct {
@@ -127,12 +245,108 @@ type TaskResultResponse struct {
 
 type SubmitTaskResultRequest struct {
 	ResultData []Result `json:"resultData" binding:"required"`
+	// optional signature by the worker's wallet over the task ID and the RFC 8785 canonical encoding of resultData
+	Signature *ResultSignature `json:"signature,omitempty"`
+}
+
+type SignatureScheme string
+
+const (
+	// personal_sign of ResultSigningMessage
+	SignatureSchemeEIP191 SignatureScheme = "eip191"
+	// eth_signTypedData_v4 of the typed data hashed by ResultTypedDataHash
+	SignatureSchemeEIP712 SignatureScheme = "eip712"
+)
+
+type ResultSignature struct {
+	Scheme    SignatureScheme `json:"scheme" binding:"required"`
+	Signature string          `json:"signature" binding:"required"`
+}
+
+// SignedResult is a verified result signature and the exact result data bytes it covers
+type SignedResult struct {
+	Signature           ResultSignature
+	CanonicalResultData []byte
+}
+
+// SaveTaskDraftRequest takes the same, possibly incomplete, resultData as SubmitTaskResultRequest
+type SaveTaskDraftRequest struct {
+	ResultData []Result `json:"resultData" binding:"required"`
+}
+
+type TaskDraftResponse struct {
+	TaskId     string    `json:"taskId"`
+	ResultData []Result  `json:"resultData"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	ExpireAt   time.Time `json:"expireAt"`
 }
 
 type SubmitTaskResultResponse struct {
 	NumResults int `json:"numResults"`
 }
 
+// TaskAggregateResponse summarises the completed results of a task per model and criteria
+type TaskAggregateResponse struct {
+	TaskId     string           `json:"taskId"`
+	NumResults int              `json:"numResults"`
+	Models     []ModelAggregate `json:"models"`
+}
+
+type ModelAggregate struct {
+	Model    string              `json:"model"`
+	Criteria []CriteriaAggregate `json:"criteria"`
+}
+
+// CriteriaAggregate holds the aggregate for a single criteria, only the fields relevant to its type are set.
+// Ranking criteria report the statistics of the rank each option was given.
+type CriteriaAggregate struct {
+	ID           string                `json:"id,omitempty"`
+	Type         CriteriaType          `json:"type"`
+	Stats        *ScoreStats           `json:"stats,omitempty"`
+	OptionStats  map[string]ScoreStats `json:"optionStats,omitempty"`
+	OptionCounts map[string]int        `json:"optionCounts,omitempty"`
+	TextFeedback []string              `json:"textFeedback,omitempty"`
+}
+
+type ScoreStats struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stdDev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// TaskAgreement measures how consistently workers rated a task, a nil metric means
+// there was not enough data or no variation in the ratings to compute it
+type TaskAgreement struct {
+	TaskId     string `json:"taskId"`
+	NumResults int    `json:"numResults"`
+	// Krippendorff's alpha with the interval metric, over every score and multi-score item
+	ScoreAlpha *float64 `json:"scoreAlpha"`
+	// Krippendorff's alpha with the nominal metric, over every multi-select option
+	SelectionAlpha *float64 `json:"selectionAlpha"`
+	// Mean Kendall's W of the ranking criteria
+	KendallW *float64           `json:"kendallW"`
+	Rankings []RankingAgreement `json:"rankings"`
+}
+
+type RankingAgreement struct {
+	Model      string   `json:"model"`
+	CriteriaId string   `json:"criteriaId,omitempty"`
+	NumRaters  int      `json:"numRaters"`
+	KendallW   *float64 `json:"kendallW"`
+}
+
+// MinerAgreement is the mean agreement over a miner's most recently completed tasks
+type MinerAgreement struct {
+	MinerUserId    string   `json:"minerUserId"`
+	NumTasks       int      `json:"numTasks"`
+	ScoreAlpha     *float64 `json:"scoreAlpha"`
+	SelectionAlpha *float64 `json:"selectionAlpha"`
+	KendallW       *float64 `json:"kendallW"`
+}
+
 type (
 	ScoreValue       float64
 	RankingValue     map[string]string
@@ -140,6 +354,130 @@ type (
 	MultiSelectValue []string
 )
 
+type TieHandling string
+
+const (
+	// drop pairs whose responses scored the same
+	TieHandlingSkip TieHandling = "skip"
+	// keep tied pairs, flagged with IsTie, in task data order
+	TieHandlingKeep TieHandling = "keep"
+)
+
+// PreferencePairOptions controls which pairs of model responses are turned into preference pairs
+type PreferencePairOptions struct {
+	TieHandling TieHandling
+	// responses whose mean scores, on [0, 1], differ by at most TieMargin are tied
+	TieMargin float64
+	// minimum fraction of raters whose own scores agree with the pair
+	MinAgreement float64
+	// minimum number of workers that scored both responses
+	MinRaters int
+}
+
+// PreferencePair is a chosen and rejected completion of the same prompt, in the prompt/chosen/rejected layout
+// used by DPO and reward model training
+type PreferencePair struct {
+	Prompt        string  `json:"prompt"`
+	Chosen        string  `json:"chosen"`
+	Rejected      string  `json:"rejected"`
+	TaskId        string  `json:"taskId"`
+	ChosenModel   string  `json:"chosenModel"`
+	RejectedModel string  `json:"rejectedModel"`
+	ChosenScore   float64 `json:"chosenScore"`
+	RejectedScore float64 `json:"rejectedScore"`
+	Agreement     float64 `json:"agreement"`
+	NumRaters     int     `json:"numRaters"`
+	IsTie         bool    `json:"isTie"`
+}
+
+const (
+	DefaultMinerTaskPageSize = 20
+	MaxMinerTaskPageSize     = 100
+)
+
+// MinerTaskResponse is a row of a miner's task listing
+type MinerTaskResponse struct {
+	TaskId      string          `json:"taskId"`
+	Title       string          `json:"title"`
+	Modality    db.TaskModality `json:"modality"`
+	Status      db.TaskStatus   `json:"status"`
+	NumResults  int             `json:"numResults"`
+	MaxResults  int             `json:"maxResults"`
+	TotalReward *float64        `json:"totalReward"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	ExpireAt    time.Time       `json:"expireAt"`
+	CompletedAt *time.Time      `json:"completedAt"`
+	// seconds from creation to completion, null until the task completes
+	CompletionSeconds *float64 `json:"completionSeconds"`
+}
+
+type MinerTaskListResponse struct {
+	Tasks []MinerTaskResponse `json:"tasks"`
+	// pass as cursor to get the next page, null on the last page
+	NextCursor *string `json:"nextCursor"`
+	// number of tasks matching every filter
+	TotalTasks int `json:"totalTasks"`
+	// number of tasks of each status matching every filter but status
+	StatusCounts map[db.TaskStatus]int `json:"statusCounts"`
+}
+
+type CancelTaskResponse struct {
+	TaskId string        `json:"taskId"`
+	Status db.TaskStatus `json:"status"`
+}
+
+// TaskLease is a worker's reservation of one of a task's result slots
+type TaskLease struct {
+	TaskId   string    `json:"taskId"`
+	WorkerId string    `json:"workerId"`
+	ExpireAt time.Time `json:"expireAt"`
+}
+
+// ResultCommitment is the leaf hash of one COMPLETED result, in the order it is hashed into the task's results hash
+type ResultCommitment struct {
+	TaskResultId string `json:"taskResultId"`
+	WorkerId     string `json:"workerId"`
+	LeafHash     string `json:"leafHash"`
+}
+
+// TaskCommitmentResponse has everything needed to check a task's results against a commitment batch's merkle root.
+// The batch fields are null until the task is included in a batch.
+type TaskCommitmentResponse struct {
+	TaskId       string             `json:"taskId"`
+	ResultsHash  string             `json:"resultsHash"`
+	Results      []ResultCommitment `json:"results"`
+	TaskLeafHash string             `json:"taskLeafHash"`
+	BatchId      *string            `json:"batchId"`
+	MerkleRoot   *string            `json:"merkleRoot"`
+	Proof        []string           `json:"proof"`
+	CommittedAt  *time.Time         `json:"committedAt"`
+}
+
+type CommitmentBatchResponse struct {
+	BatchId    string    `json:"batchId"`
+	MerkleRoot string    `json:"merkleRoot"`
+	NumTasks   int       `json:"numTasks"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ModalityTimeOnTask summarises the time between workers first opening or claiming and submitting tasks of a modality.
+// Timings only cover results of tasks that were opened or claimed, the others are counted in NumUntimedResults.
+type ModalityTimeOnTask struct {
+	Modality           db.TaskModality `json:"modality"`
+	NumResults         int             `json:"numResults"`
+	AverageSeconds     float64         `json:"averageSeconds"`
+	MedianSeconds      float64         `json:"medianSeconds"`
+	P90Seconds         float64         `json:"p90Seconds"`
+	NumFastSubmissions int             `json:"numFastSubmissions"`
+	NumUntimedResults  int             `json:"numUntimedResults"`
+	// submissions faster than this are flagged as fast
+	MinSeconds int `json:"minSeconds"`
+}
+
+type TimeOnTaskMetricsResponse struct {
+	Modalities []ModalityTimeOnTask `json:"modalities"`
+}
+
 type NextTaskResponse struct {
 	NextInProgressTaskId string `json:"nextInProgressTaskId"`
 }
@@ -150,6 +488,11 @@ type PaginationParams struct {
 	Modalities []string     `json:"modalities"`
 	Sort       string       `json:"sort"`
 	Order      db.SortOrder `json:"order"`
+	YieldMin   *float64     `json:"yieldMin"`
+	YieldMax   *float64     `json:"yieldMax"`
+	// when set, Page is ignored and the page after the cursor is returned
+	Cursor       string `json:"cursor"`
+	IncludeTotal bool   `json:"includeTotal"`
 }
 
 // Implement GetType for all criteria types
@@ -162,6 +505,75 @@ func (t TextCriteria) GetType() CriteriaType {
 	return CriteriaTypeText
 }
 
+// GetType for RankingCriteria
+func (r RankingCriteria) GetType() CriteriaType {
+	return CriteriaTypeRanking
+}
+
+// GetType for MultiScoreCriteria
+func (m MultiScoreCriteria) GetType() CriteriaType {
+	return CriteriaTypeMultiScore
+}
+
+// GetType for MultiSelectCriteria
+func (m MultiSelectCriteria) GetType() CriteriaType {
+	return CriteriaTypeMultiSelect
+}
+
+// Implement GetId for all criteria types, the ID is assigned at task creation and
+// used to match submitted criteria when a response has several criteria of the same type
+func (s ScoreCriteria) GetId() string {
+	return s.ID
+}
+
+func (t TextCriteria) GetId() string {
+	return t.ID
+}
+
+func (r RankingCriteria) GetId() string {
+	return r.ID
+}
+
+func (m MultiScoreCriteria) GetId() string {
+	return m.ID
+}
+
+func (m MultiSelectCriteria) GetId() string {
+	return m.ID
+}
+
+// withCriteriaId returns a copy of the criteria with its ID set
+func withCriteriaId(criteria Criteria, id string) Criteria {
+	switch c := criteria.(type) {
+	case ScoreCriteria:
+		c.ID = id
+		return c
+	case TextCriteria:
+		c.ID = id
+		return c
+	case RankingCriteria:
+		c.ID = id
+		return c
+	case MultiScoreCriteria:
+		c.ID = id
+		return c
+	case MultiSelectCriteria:
+		c.ID = id
+		return c
+	default:
+		return criteria
+	}
+}
+
+// criteriaKey identifies a criteria within a model response. Tasks created before criteria IDs
+// were introduced have a single criteria per type, so fall back to the type for those.
+func criteriaKey(criteria Criteria) string {
+	if criteria.GetId() != "" {
+		return criteria.GetId()
+	}
+	return string(criteria.GetType())
+}
+
 // Implement Validate for each type
 func (c ScoreCriteria) Validate() error {
 	if (c.Min < 0 || c.Max < 0) || (c.Min == 0 && c.Max == 0) {
@@ -170,7 +582,7 @@ func (c ScoreCriteria) Validate() error {
 	if c.Min >= c.Max {
 		return errors.New("min must be less than max for score criteria")
 	}
-	return nil
+	return validateScale(c.Min, c.Max, c.Step, c.OutputMin, c.OutputMax)
 }
 
 // Validate for TextCriteria
@@ -182,6 +594,120 @@ func (t TextCriteria) Validate() error {
 	return nil
 }
 
+// Validate for RankingCriteria
+func (r RankingCriteria) Validate() error {
+	if len(r.Options) < 2 {
+		return errors.New("at least 2 options are required for ranking criteria")
+	}
+	return validateOptions(r.Options)
+}
+
+// Validate for MultiScoreCriteria
+func (m MultiScoreCriteria) Validate() error {
+	if len(m.Options) == 0 {
+		return errors.New("options are required for multi-score criteria")
+	}
+	if (m.Min < 0 || m.Max < 0) || (m.Min == 0 && m.Max == 0) {
+		return errors.New("valid min and max are required for multi-score criteria")
+	}
+	if m.Min >= m.Max {
+		return errors.New("min must be less than max for multi-score criteria")
+	}
+	if err := validateScale(m.Min, m.Max, m.Step, m.OutputMin, m.OutputMax); err != nil {
+		return err
+	}
+	return validateOptions(m.Options)
+}
+
+// Validate for MultiSelectCriteria
+func (m MultiSelectCriteria) Validate() error {
+	if len(m.Options) == 0 {
+		return errors.New("options are required for multi-select criteria")
+	}
+	return validateOptions(m.Options)
+}
+
+// validateScale checks the optional step size and output scale of a score criteria
+func validateScale(inputMin, inputMax, step, outputMin, outputMax float64) error {
+	if step < 0 || step > inputMax-inputMin {
+		return fmt.Errorf("step must be between 0 and %v", inputMax-inputMin)
+	}
+	if (outputMin != 0 || outputMax != 0) && outputMin >= outputMax {
+		return errors.New("output_min must be less than output_max")
+	}
+	return nil
+}
+
+// validateScoreOnScale checks a raw score lies within [min, max] and on a step increment
+func validateScoreOnScale(score, inputMin, inputMax, step float64) error {
+	if score < inputMin || score > inputMax {
+		return fmt.Errorf("score %v is out of the valid range [%v, %v]", score, inputMin, inputMax)
+	}
+	if step > 0 && math.Abs(math.Remainder(score-inputMin, step)) > scoreStepTolerance {
+		return fmt.Errorf("score %v must be in increments of %v from %v", score, step, inputMin)
+	}
+	return nil
+}
+
+// normaliseScore maps a raw score from the input scale onto the output scale,
+// an unset output scale keeps the raw score as is
+func normaliseScore(score, inputMin, inputMax, outputMin, outputMax float64) float64 {
+	if outputMin == 0 && outputMax == 0 {
+		return score
+	}
+	return scaleScore(score, inputMin, inputMax, outputMin, outputMax)
+}
+
+// validateOptions ensures options are non-empty and unique
+func validateOptions(options []string) error {
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		if option == "" {
+			return errors.New("options cannot be empty")
+		}
+		if seen[option] {
+			return fmt.Errorf("duplicate option: %s", option)
+		}
+		seen[option] = true
+	}
+	return nil
+}
+
+// unmarshalCriteria peeks at the type field and unmarshals into the matching concrete criteria
+func unmarshalCriteria(data json.RawMessage) (Criteria, error) {
+	var temp struct {
+		Type CriteriaType `json:"type"`
+	}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return nil, err
+	}
+
+	switch temp.Type {
+	case CriteriaTypeScore:
+		var sc ScoreCriteria
+		err := json.Unmarshal(data, &sc)
+		return sc, err
+	case CriteriaTypeText:
+		var tc TextCriteria
+		err := json.Unmarshal(data, &tc)
+		return tc, err
+	case CriteriaTypeRanking:
+		var rc RankingCriteria
+		err := json.Unmarshal(data, &rc)
+		return rc, err
+	case CriteriaTypeMultiScore:
+		var msc MultiScoreCriteria
+		err := json.Unmarshal(data, &msc)
+		return msc, err
+	case CriteriaTypeMultiSelect:
+		var msc MultiSelectCriteria
+		err := json.Unmarshal(data, &msc)
+		return msc, err
+	default:
+		return nil, fmt.Errorf("unknown criteria type: %s", temp.Type)
+	}
+}
+
 // Add custom unmarshaling for ModelResponse
 func (mr *ModelResponse) UnmarshalJSON(data []byte) error {
 	var raw rawModelResponse
@@ -194,33 +720,11 @@ func (mr *ModelResponse) UnmarshalJSON(data []byte) error {
 	mr.Criteria = make([]Criteria, 0)
 
 	for _, criteriaData := range raw.Criteria {
-		// unmarshal to get the type
-		var temp struct {
-			Type CriteriaType `json:"type"`
-		}
-		if err := json.Unmarshal(criteriaData, &temp); err != nil {
+		criteria, err := unmarshalCriteria(criteriaData)
+		if err != nil {
 			return err
 		}
 
-		// Based on the type, unmarshal to the correct criteria struct
-		var criteria Criteria
-		switch temp.Type {
-		case CriteriaTypeScore:
-			var sc ScoreCriteria
-			if err := json.Unmarshal(criteriaData, &sc); err != nil {
-				return err
-			}
-			criteria = sc
-		case CriteriaTypeText:
-			var tc TextCriteria
-			if err := json.Unmarshal(criteriaData, &tc); err != nil {
-				return err
-			}
-			criteria = tc
-		default:
-			return fmt.Errorf("unknown criteria type: %s", temp.Type)
-		}
-
 		mr.Criteria = append(mr.Criteria, criteria)
 	}
 
@@ -241,31 +745,11 @@ func (r *Result) UnmarshalJSON(data []byte) error {
 	r.Criteria = make([]Criteria, 0)
 
 	for _, criteriaData := range raw.Criteria {
-		// Peek at the type field first
-		var temp struct {
-			Type CriteriaType `json:"type"`
-		}
-		if err := json.Unmarshal(criteriaData, &temp); err != nil {
+		criteria, err := unmarshalCriteria(criteriaData)
+		if err != nil {
 			return err
 		}
-
-		// Based on the type, unmarshal into the correct concrete type
-		switch temp.Type {
-		case CriteriaTypeScore:
-			var sc ScoreCriteria
-			if err := json.Unmarshal(criteriaData, &sc); err != nil {
-				return err
-			}
-			r.Criteria = append(r.Criteria, sc)
-		case CriteriaTypeText:
-			var tc TextCriteria
-			if err := json.Unmarshal(criteriaData, &tc); err != nil {
-				return err
-			}
-			r.Criteria = append(r.Criteria, tc)
-		default:
-			return fmt.Errorf("unknown criteria type: %s", temp.Type)
-		}
+		r.Criteria = append(r.Criteria, criteria)
 	}
 
 	return nil
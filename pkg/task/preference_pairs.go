@@ -0,0 +1,236 @@
+package task
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"dojo-api/db"
+
+	"github.com/rs/zerolog/log"
+)
+
+var DefaultPreferencePairOptions = PreferencePairOptions{
+	TieHandling:  TieHandlingSkip,
+	TieMargin:    0,
+	MinAgreement: 0,
+	MinRaters:    1,
+}
+
+// BuildPreferencePairs turns every pair of the task's model responses into a chosen and rejected completion of the
+// task's prompt, ordered by the responses' mean scores over the COMPLETED results.
+//
+// Each worker's score of a response is the mean, on [0, 1], of the score criteria of the response and of any
+// multi-score or ranking criteria with the response's model as an option, where the best rank scores 1.
+// A pair's agreement is the fraction of workers who scored both responses and whose own scores order them the
+// same way as the pair, or tie them for a tied pair. TieMargin applies to each worker's scores as to the means.
+func BuildPreferencePairs(task *db.TaskModel, taskResults []db.TaskResultModel, options PreferencePairOptions) ([]PreferencePair, error) {
+	var taskData TaskData
+	if err := json.Unmarshal(task.TaskData, &taskData); err != nil {
+		log.Error().Err(err).Str("taskId", task.ID).Msg("Error unmarshaling task data")
+		return nil, err
+	}
+
+	workerScores := collectWorkerModelScores(taskData, taskResults)
+	tieMargin := options.TieMargin + scoreStepTolerance
+
+	pairs := make([]PreferencePair, 0)
+	for i := range taskData.Responses {
+		for j := i + 1; j < len(taskData.Responses); j++ {
+			a, b := taskData.Responses[i], taskData.Responses[j]
+
+			var aScores, bScores []float64
+			aWins, bWins, ties := 0, 0, 0
+			for _, scores := range workerScores {
+				aScore, aOk := scores[a.Model]
+				bScore, bOk := scores[b.Model]
+				if !aOk || !bOk {
+					continue
+				}
+				aScores = append(aScores, aScore)
+				bScores = append(bScores, bScore)
+				switch {
+				case aScore-bScore > tieMargin:
+					aWins++
+				case bScore-aScore > tieMargin:
+					bWins++
+				default:
+					ties++
+				}
+			}
+
+			numRaters := len(aScores)
+			if numRaters == 0 || numRaters < options.MinRaters {
+				continue
+			}
+
+			aMean, bMean := *meanOf(aScores), *meanOf(bScores)
+			chosen, rejected := a, b
+			chosenScore, rejectedScore, agreeing := aMean, bMean, aWins
+			isTie := math.Abs(aMean-bMean) <= tieMargin
+			switch {
+			case isTie:
+				if options.TieHandling != TieHandlingKeep {
+					continue
+				}
+				agreeing = ties
+			case bMean > aMean:
+				chosen, rejected = b, a
+				chosenScore, rejectedScore, agreeing = bMean, aMean, bWins
+			}
+
+			agreement := float64(agreeing) / float64(numRaters)
+			if agreement < options.MinAgreement {
+				continue
+			}
+
+			chosenText, err := completionText(chosen.Completion)
+			if err != nil {
+				return nil, err
+			}
+			rejectedText, err := completionText(rejected.Completion)
+			if err != nil {
+				return nil, err
+			}
+
+			pairs = append(pairs, PreferencePair{
+				Prompt:        taskData.Prompt,
+				Chosen:        chosenText,
+				Rejected:      rejectedText,
+				TaskId:        task.ID,
+				ChosenModel:   chosen.Model,
+				RejectedModel: rejected.Model,
+				ChosenScore:   chosenScore,
+				RejectedScore: rejectedScore,
+				Agreement:     agreement,
+				NumRaters:     numRaters,
+				IsTie:         isTie,
+			})
+		}
+	}
+
+	return pairs, nil
+}
+
+// collectWorkerModelScores returns each worker's score of each model response on [0, 1], keyed by worker ID then
+// model. Results that are not COMPLETED are ignored.
+func collectWorkerModelScores(taskData TaskData, taskResults []db.TaskResultModel) map[string]map[string]float64 {
+	modelCriteriaMap := make(map[string]map[string]Criteria)
+	for _, response := range taskData.Responses {
+		criteriaMap := make(map[string]Criteria)
+		for _, criteria := range response.Criteria {
+			criteriaMap[criteriaKey(criteria)] = criteria
+		}
+		modelCriteriaMap[response.Model] = criteriaMap
+	}
+
+	workerScores := make(map[string]map[string]float64)
+	for _, taskResult := range taskResults {
+		if taskResult.Status != db.TaskResultStatusCompleted {
+			continue
+		}
+
+		var results []Result
+		if err := json.Unmarshal(taskResult.ResultData, &results); err != nil {
+			log.Warn().Err(err).Str("taskResultId", taskResult.ID).Msg("Skipping task result with invalid result data")
+			continue
+		}
+
+		modelScores := make(map[string][]float64)
+		addScore := func(model string, score float64) {
+			if _, ok := modelCriteriaMap[model]; ok {
+				modelScores[model] = append(modelScores[model], score)
+			}
+		}
+
+		for _, result := range results {
+			for _, criteria := range result.Criteria {
+				taskCriteria, ok := modelCriteriaMap[result.Model][criteriaKey(criteria)]
+				if !ok || taskCriteria.GetType() != criteria.GetType() {
+					continue
+				}
+
+				switch submitted := criteria.(type) {
+				case ScoreCriteria:
+					tc := taskCriteria.(ScoreCriteria)
+					addScore(result.Model, fractionOfScale(submitted.MinerScore, tc.Min, tc.Max, tc.OutputMin, tc.OutputMax))
+				case MultiScoreCriteria:
+					tc := taskCriteria.(MultiScoreCriteria)
+					for option, score := range submitted.Value {
+						addScore(option, fractionOfScale(score, tc.Min, tc.Max, tc.OutputMin, tc.OutputMax))
+					}
+				case RankingCriteria:
+					tc := taskCriteria.(RankingCriteria)
+					ranks, ok := ranksByOption(submitted.Value, tc.Options)
+					if !ok || len(tc.Options) < 2 {
+						continue
+					}
+					n := float64(len(tc.Options))
+					for k, option := range tc.Options {
+						addScore(option, (n-ranks[k])/(n-1))
+					}
+				}
+			}
+		}
+
+		if len(modelScores) == 0 {
+			continue
+		}
+		workerScores[taskResult.WorkerID] = make(map[string]float64, len(modelScores))
+		for model, scores := range modelScores {
+			workerScores[taskResult.WorkerID][model] = *meanOf(scores)
+		}
+	}
+
+	return workerScores
+}
+
+// completionText returns text completions as is and JSON encodes any other completion, e.g. generated code files
+func completionText(completion interface{}) (string, error) {
+	if text, ok := completion.(string); ok {
+		return text, nil
+	}
+	encoded, err := json.Marshal(completion)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// ParsePreferencePairOptions reads the options from query values, using DefaultPreferencePairOptions for any that
+// are empty
+func ParsePreferencePairOptions(tieHandling, tieMargin, minAgreement, minRaters string) (PreferencePairOptions, error) {
+	options := DefaultPreferencePairOptions
+
+	switch TieHandling(tieHandling) {
+	case "":
+	case TieHandlingSkip, TieHandlingKeep:
+		options.TieHandling = TieHandling(tieHandling)
+	default:
+		return options, ErrInvalidTieHandling
+	}
+
+	if tieMargin != "" {
+		value, err := strconv.ParseFloat(tieMargin, 64)
+		if err != nil || value < 0 || value > 1 {
+			return options, ErrInvalidTieMargin
+		}
+		options.TieMargin = value
+	}
+	if minAgreement != "" {
+		value, err := strconv.ParseFloat(minAgreement, 64)
+		if err != nil || value < 0 || value > 1 {
+			return options, ErrInvalidMinAgreement
+		}
+		options.MinAgreement = value
+	}
+	if minRaters != "" {
+		value, err := strconv.Atoi(minRaters)
+		if err != nil || value < 1 {
+			return options, ErrInvalidMinRaters
+		}
+		options.MinRaters = value
+	}
+
+	return options, nil
+}
@@ -0,0 +1,120 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"dojo-api/db"
+)
+
+// preferencePairTask has two responses, a and b, each scored on a 0-100 scale
+func preferencePairTask() *db.TaskModel {
+	return &db.TaskModel{
+		InnerTask: db.InnerTask{
+			ID: "task-1",
+			TaskData: db.JSON(`{
+				"prompt": "prompt",
+				"responses": [
+					{"model": "a", "completion": "completion a", "criteria": [{"type": "score", "min": 0, "max": 100}]},
+					{"model": "b", "completion": "completion b", "criteria": [{"type": "score", "min": 0, "max": 100}]}
+				]
+			}`),
+		},
+	}
+}
+
+// preferencePairResults returns a COMPLETED result per pair of scores of a and b
+func preferencePairResults(scores ...[2]float64) []db.TaskResultModel {
+	taskResults := make([]db.TaskResultModel, len(scores))
+	for i, score := range scores {
+		taskResults[i] = db.TaskResultModel{
+			InnerTaskResult: db.InnerTaskResult{
+				ID:       fmt.Sprintf("result-%d", i),
+				WorkerID: fmt.Sprintf("worker-%d", i),
+				Status:   db.TaskResultStatusCompleted,
+				ResultData: db.JSON(fmt.Sprintf(
+					`[{"model":"a","criteria":[{"type":"score","value":%g}]},{"model":"b","criteria":[{"type":"score","value":%g}]}]`,
+					score[0], score[1],
+				)),
+			},
+		}
+	}
+	return taskResults
+}
+
+func TestBuildPreferencePairs(t *testing.T) {
+	keepTies := func(margin float64) PreferencePairOptions {
+		options := DefaultPreferencePairOptions
+		options.TieHandling = TieHandlingKeep
+		options.TieMargin = margin
+		return options
+	}
+	skipTies := func(margin float64) PreferencePairOptions {
+		options := DefaultPreferencePairOptions
+		options.TieMargin = margin
+		return options
+	}
+	minAgreement := func(value float64) PreferencePairOptions {
+		options := DefaultPreferencePairOptions
+		options.MinAgreement = value
+		return options
+	}
+	minRaters := DefaultPreferencePairOptions
+	minRaters.MinRaters = 3
+
+	tests := []struct {
+		name          string
+		scores        [][2]float64
+		options       PreferencePairOptions
+		wantPair      bool
+		wantChosen    string
+		wantTie       bool
+		wantAgreement float64
+	}{
+		{"clear preference", [][2]float64{{80, 20}, {70, 30}}, DefaultPreferencePairOptions, true, "a", false, 1},
+		{"reversed preference", [][2]float64{{20, 80}, {30, 70}}, DefaultPreferencePairOptions, true, "b", false, 1},
+		{"tie skipped", [][2]float64{{50, 50}, {60, 60}}, DefaultPreferencePairOptions, false, "", false, 0},
+		{"tie kept", [][2]float64{{50, 50}, {60, 60}}, keepTies(0), true, "a", true, 1},
+		{"near tie without margin", [][2]float64{{52, 50}, {50, 51}}, keepTies(0), true, "a", false, 0.5},
+		{"near tie within margin", [][2]float64{{52, 50}, {50, 51}}, keepTies(0.05), true, "a", true, 1},
+		{"near tie within margin skipped", [][2]float64{{52, 50}, {50, 51}}, skipTies(0.05), false, "", false, 0},
+		{"rater ties within margin do not agree", [][2]float64{{90, 10}, {52, 50}, {51, 50}}, keepTies(0.05), true, "a", false, 1.0 / 3},
+		{"rater ties within margin agree with a tie", [][2]float64{{54, 50}, {50, 52}, {80, 20}}, keepTies(0.25), true, "a", true, 2.0 / 3},
+		{"agreement above threshold", [][2]float64{{80, 20}, {70, 30}, {20, 80}}, minAgreement(0.6), true, "a", false, 2.0 / 3},
+		{"agreement below threshold", [][2]float64{{80, 20}, {70, 30}, {20, 80}}, minAgreement(0.7), false, "", false, 0},
+		{"agreement at threshold", [][2]float64{{80, 20}, {20, 70}}, minAgreement(0.5), true, "a", false, 0.5},
+		{"too few raters", [][2]float64{{80, 20}, {70, 30}}, minRaters, false, "", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := BuildPreferencePairs(preferencePairTask(), preferencePairResults(tt.scores...), tt.options)
+			if err != nil {
+				t.Fatalf("BuildPreferencePairs() error = %v", err)
+			}
+			if !tt.wantPair {
+				if len(pairs) != 0 {
+					t.Errorf("BuildPreferencePairs() = %+v, want no pairs", pairs)
+				}
+				return
+			}
+			if len(pairs) != 1 {
+				t.Fatalf("BuildPreferencePairs() returned %d pairs, want 1", len(pairs))
+			}
+
+			pair := pairs[0]
+			if pair.ChosenModel != tt.wantChosen {
+				t.Errorf("ChosenModel = %s, want %s", pair.ChosenModel, tt.wantChosen)
+			}
+			if pair.IsTie != tt.wantTie {
+				t.Errorf("IsTie = %v, want %v", pair.IsTie, tt.wantTie)
+			}
+			if math.Abs(pair.Agreement-tt.wantAgreement) > 1e-9 {
+				t.Errorf("Agreement = %v, want %v", pair.Agreement, tt.wantAgreement)
+			}
+			if pair.NumRaters != len(tt.scores) {
+				t.Errorf("NumRaters = %d, want %d", pair.NumRaters, len(tt.scores))
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package task
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// reputation of a worker without any history
+	DefaultReputation = 0.5
+
+	// number of recent results and gold grades the reputation is computed from
+	reputationWindow = 100
+	// submissions closer together than this are considered anomalously fast
+	defaultMinSubmissionIntervalSeconds = 10
+)
+
+// relative weight of each reputation signal, signals without data are left out
+const (
+	goldAccuracyWeight = 0.4
+	consensusWeight    = 0.3
+	validityWeight     = 0.15
+	speedWeight        = 0.15
+)
+
+type ReputationService struct {
+	taskORM               *orm.TaskORM
+	taskResultORM         *orm.TaskResultORM
+	goldTaskGradeORM      *orm.GoldTaskGradeORM
+	dojoWorkerORM         *orm.DojoWorkerORM
+	minSubmissionInterval time.Duration
+}
+
+func NewReputationService() *ReputationService {
+	minInterval := getPositiveIntEnv("REPUTATION_MIN_SUBMISSION_SECONDS", defaultMinSubmissionIntervalSeconds)
+	return &ReputationService{
+		taskORM:               orm.NewTaskORM(),
+		taskResultORM:         orm.NewTaskResultORM(),
+		goldTaskGradeORM:      orm.NewGoldTaskGradeORM(),
+		dojoWorkerORM:         orm.NewDojoWorkerORM(),
+		minSubmissionInterval: time.Duration(minInterval) * time.Second,
+	}
+}
+
+// ReputationSignals are each between 0 and 1 where higher is better, nil if there is no data for the signal
+type ReputationSignals struct {
+	GoldAccuracy *float64 `json:"goldAccuracy"`
+	Consensus    *float64 `json:"consensus"`
+	Validity     *float64 `json:"validity"`
+	Speed        *float64 `json:"speed"`
+}
+
+// UpdateReputation recomputes the worker's reputation from their recent history and stores it
+func (s *ReputationService) UpdateReputation(ctx context.Context, workerId string) (float64, error) {
+	signals, err := s.GetReputationSignals(ctx, workerId)
+	if err != nil {
+		return 0, err
+	}
+
+	reputation := signals.Reputation()
+	if _, err := s.dojoWorkerORM.UpdateReputation(ctx, workerId, reputation); err != nil {
+		log.Error().Err(err).Str("workerId", workerId).Msg("Error updating worker reputation")
+		return 0, err
+	}
+
+	log.Info().Str("workerId", workerId).Float64("reputation", reputation).Interface("signals", signals).Msg("Worker reputation updated")
+	return reputation, nil
+}
+
+// GetReputationSignals computes each reputation signal from the worker's recent results and gold grades
+func (s *ReputationService) GetReputationSignals(ctx context.Context, workerId string) (*ReputationSignals, error) {
+	signals := &ReputationSignals{}
+
+	grades, err := s.goldTaskGradeORM.GetRecentGradesByWorker(ctx, workerId, reputationWindow)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", workerId).Msg("Error fetching gold task grades")
+		return nil, err
+	}
+	if len(grades) > 0 {
+		numPassed := 0
+		for _, grade := range grades {
+			if grade.Passed {
+				numPassed++
+			}
+		}
+		goldAccuracy := float64(numPassed) / float64(len(grades))
+		signals.GoldAccuracy = &goldAccuracy
+	}
+
+	taskResults, err := s.taskResultORM.GetRecentTResultsByWorker(ctx, workerId, reputationWindow)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", workerId).Msg("Error fetching worker task results")
+		return nil, err
+	}
+	if len(taskResults) == 0 {
+		return signals, nil
+	}
+
+	numInvalid := 0
+	completedTaskIds := make([]string, 0, len(taskResults))
+	for _, taskResult := range taskResults {
+		switch taskResult.Status {
+		case db.TaskResultStatusInvalid:
+			numInvalid++
+		case db.TaskResultStatusCompleted:
+			completedTaskIds = append(completedTaskIds, taskResult.TaskID)
+		}
+	}
+	validity := 1 - float64(numInvalid)/float64(len(taskResults))
+	signals.Validity = &validity
+	signals.Speed = submissionSpeedSignal(taskResults, s.minSubmissionInterval)
+
+	if len(completedTaskIds) > 0 {
+		consensus, err := s.consensusSignal(ctx, workerId, completedTaskIds)
+		if err != nil {
+			return nil, err
+		}
+		signals.Consensus = consensus
+	}
+
+	return signals, nil
+}
+
+// consensusSignal is 1 minus the mean distance between the worker's ratings and the mean rating
+// of the other workers on the same units, with every rating scaled to [0, 1]
+func (s *ReputationService) consensusSignal(ctx context.Context, workerId string, taskIds []string) (*float64, error) {
+	tasks, err := s.taskORM.GetByIds(ctx, taskIds)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching tasks for consensus")
+		return nil, err
+	}
+
+	taskResults, err := s.taskResultORM.GetCompletedTResultByTaskIds(ctx, taskIds)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching task results for consensus")
+		return nil, err
+	}
+
+	resultsByTask := make(map[string][]db.TaskResultModel)
+	for _, taskResult := range taskResults {
+		resultsByTask[taskResult.TaskID] = append(resultsByTask[taskResult.TaskID], taskResult)
+	}
+
+	var distances []float64
+	for i := range tasks {
+		ratings, err := collectTaskRatings(&tasks[i], resultsByTask[tasks[i].ID])
+		if err != nil {
+			log.Warn().Err(err).Str("taskId", tasks[i].ID).Msg("Skipping task for consensus")
+			continue
+		}
+
+		for _, units := range []map[string]map[string]float64{ratings.scores, ratings.selections} {
+			for _, byWorker := range units {
+				workerValue, ok := byWorker[workerId]
+				if !ok || len(byWorker) < 2 {
+					continue
+				}
+
+				othersSum := 0.0
+				for otherWorkerId, value := range byWorker {
+					if otherWorkerId != workerId {
+						othersSum += value
+					}
+				}
+				othersMean := othersSum / float64(len(byWorker)-1)
+				distances = append(distances, math.Abs(workerValue-othersMean))
+			}
+		}
+	}
+
+	meanDistance := meanOf(distances)
+	if meanDistance == nil {
+		return nil, nil
+	}
+	consensus := math.Max(0, 1-*meanDistance)
+	return &consensus, nil
+}
+
+// submissionSpeedSignal is the fraction of the worker's submissions that did not follow
+// their previous submission within minInterval
+func submissionSpeedSignal(taskResults []db.TaskResultModel, minInterval time.Duration) *float64 {
+	if len(taskResults) < 2 {
+		return nil
+	}
+
+	createdAt := make([]time.Time, 0, len(taskResults))
+	for _, taskResult := range taskResults {
+		createdAt = append(createdAt, taskResult.CreatedAt)
+	}
+	sort.Slice(createdAt, func(i, j int) bool { return createdAt[i].Before(createdAt[j]) })
+
+	numAnomalies := 0
+	for i := 1; i < len(createdAt); i++ {
+		if createdAt[i].Sub(createdAt[i-1]) < minInterval {
+			numAnomalies++
+		}
+	}
+
+	speed := 1 - float64(numAnomalies)/float64(len(createdAt)-1)
+	return &speed
+}
+
+// Reputation is the weighted mean of the available signals, or DefaultReputation if there are none
+func (r *ReputationSignals) Reputation() float64 {
+	weightedSum, totalWeight := 0.0, 0.0
+	for _, signal := range []struct {
+		value  *float64
+		weight float64
+	}{
+		{r.GoldAccuracy, goldAccuracyWeight},
+		{r.Consensus, consensusWeight},
+		{r.Validity, validityWeight},
+		{r.Speed, speedWeight},
+	} {
+		if signal.value == nil {
+			continue
+		}
+		weightedSum += *signal.value * signal.weight
+		totalWeight += signal.weight
+	}
+
+	if totalWeight == 0 {
+		return DefaultReputation
+	}
+	return weightedSum / totalWeight
+}
@@ -0,0 +1,298 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	RewardPolicyEqual      = "equal"
+	RewardPolicyConsensus  = "consensus"
+	RewardPolicyReputation = "reputation"
+
+	settlementBatchSize = 100
+)
+
+// RewardPolicy decides how a task's total_reward is split across its COMPLETED results
+type RewardPolicy interface {
+	// Weights returns a non-negative weight per task result ID, rewards are split in proportion to the weights
+	Weights(ctx context.Context, task *db.TaskModel, completedResults []db.TaskResultModel) (map[string]float64, error)
+}
+
+var (
+	rewardPoliciesMu sync.RWMutex
+	rewardPolicies   = map[string]func() RewardPolicy{
+		RewardPolicyEqual:      func() RewardPolicy { return EqualSplitPolicy{} },
+		RewardPolicyConsensus:  func() RewardPolicy { return ConsensusWeightedPolicy{} },
+		RewardPolicyReputation: func() RewardPolicy { return ReputationWeightedPolicy{dojoWorkerORM: orm.NewDojoWorkerORM()} },
+	}
+)
+
+// RegisterRewardPolicy makes a policy selectable by name through the REWARD_POLICY env var
+func RegisterRewardPolicy(name string, newPolicy func() RewardPolicy) {
+	rewardPoliciesMu.Lock()
+	defer rewardPoliciesMu.Unlock()
+	rewardPolicies[name] = newPolicy
+}
+
+type SettlementService struct {
+	taskORM       *orm.TaskORM
+	taskResultORM *orm.TaskResultORM
+	ledgerORM     *orm.LedgerORM
+	policy        RewardPolicy
+}
+
+func NewSettlementService() *SettlementService {
+	policyName := os.Getenv("REWARD_POLICY")
+	if policyName == "" {
+		policyName = RewardPolicyEqual
+	}
+
+	rewardPoliciesMu.RLock()
+	newPolicy, ok := rewardPolicies[policyName]
+	rewardPoliciesMu.RUnlock()
+	if !ok {
+		log.Warn().Str("REWARD_POLICY", policyName).Msg("Unknown reward policy, using equal split")
+		newPolicy = rewardPolicies[RewardPolicyEqual]
+	}
+
+	return &SettlementService{
+		taskORM:       orm.NewTaskORM(),
+		taskResultORM: orm.NewTaskResultORM(),
+		ledgerORM:     orm.NewLedgerORM(),
+		policy:        newPolicy(),
+	}
+}
+
+// SettleTask splits the total reward of a COMPLETED or EXPIRED task across its results.
+// Settling is idempotent, a task that is already settled or still in progress is left untouched.
+func (s *SettlementService) SettleTask(ctx context.Context, taskId string) error {
+	task, err := s.taskORM.GetById(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+
+	if task.Status != db.TaskStatusCompleted && task.Status != db.TaskStatusExpired {
+		log.Debug().Str("taskId", taskId).Str("status", string(task.Status)).Msg("Task has not finished, skipping settlement")
+		return nil
+	}
+
+	return s.settle(ctx, task)
+}
+
+func (s *SettlementService) settle(ctx context.Context, task *db.TaskModel) error {
+	taskResults, err := s.taskResultORM.GetTaskResultsByTaskId(ctx, task.ID)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", task.ID).Msg("Error fetching task results for settlement")
+		return err
+	}
+
+	settlements, err := s.computeSettlements(ctx, task, taskResults)
+	if err != nil {
+		return err
+	}
+
+	settled, err := s.taskResultORM.SettleTask(ctx, task.ID, settlements)
+	if err != nil {
+		return err
+	}
+
+	if settled {
+		log.Info().Str("taskId", task.ID).Int("numResults", len(settlements)).Msg("Task settled")
+	} else {
+		log.Debug().Str("taskId", task.ID).Msg("Task already settled")
+	}
+
+	// posting is idempotent, so already settled tasks are posted again in case it failed before
+	return s.ledgerORM.AccrueSettledTask(ctx, task.ID)
+}
+
+// computeSettlements gives COMPLETED results their share of the total reward. INVALID results get no reward
+// and lose their stake, if any.
+func (s *SettlementService) computeSettlements(ctx context.Context, task *db.TaskModel, taskResults []db.TaskResultModel) ([]orm.TaskResultSettlement, error) {
+	completedResults := make([]db.TaskResultModel, 0, len(taskResults))
+	settlements := make([]orm.TaskResultSettlement, 0, len(taskResults))
+	for _, taskResult := range taskResults {
+		switch taskResult.Status {
+		case db.TaskResultStatusCompleted:
+			completedResults = append(completedResults, taskResult)
+		case db.TaskResultStatusInvalid:
+			stakeAmount, _ := taskResult.StakeAmount()
+			settlements = append(settlements, orm.TaskResultSettlement{
+				TaskResultId:    taskResult.ID,
+				FinalisedReward: 0,
+				FinalisedLoss:   stakeAmount,
+			})
+		}
+	}
+
+	if len(completedResults) == 0 {
+		return settlements, nil
+	}
+
+	totalReward, _ := task.TotalReward()
+	weights := make(map[string]float64, len(completedResults))
+	if totalReward > 0 {
+		var err error
+		weights, err = s.policy.Weights(ctx, task, completedResults)
+		if err != nil {
+			log.Error().Err(err).Str("taskId", task.ID).Msg("Error computing reward weights")
+			return nil, err
+		}
+	}
+
+	totalWeight := 0.0
+	for _, taskResult := range completedResults {
+		totalWeight += math.Max(0, weights[taskResult.ID])
+	}
+
+	for _, taskResult := range completedResults {
+		reward := 0.0
+		if totalReward > 0 {
+			if totalWeight > 0 {
+				reward = totalReward * math.Max(0, weights[taskResult.ID]) / totalWeight
+			} else {
+				// nobody earned any weight, fall back to an equal split
+				reward = totalReward / float64(len(completedResults))
+			}
+		}
+		settlements = append(settlements, orm.TaskResultSettlement{
+			TaskResultId:    taskResult.ID,
+			FinalisedReward: reward,
+			FinalisedLoss:   0,
+		})
+	}
+
+	return settlements, nil
+}
+
+// SettlePendingTasks periodically settles finished tasks that have not been settled yet,
+// e.g. tasks that expired or whose settlement failed when they completed, and reconciles the ledger
+func (s *SettlementService) SettlePendingTasks(ctx context.Context) {
+	for range time.Tick(10 * time.Minute) {
+		log.Info().Msg("Checking for unsettled tasks")
+		startTime := time.Now()
+		numSettled := 0
+
+		for {
+			tasks, err := s.taskORM.GetUnsettledTasks(ctx, settlementBatchSize)
+			if err != nil {
+				log.Error().Err(err).Msg("Error finding unsettled tasks")
+				break
+			}
+
+			numFailed := 0
+			for i := range tasks {
+				if err := s.settle(ctx, &tasks[i]); err != nil {
+					log.Error().Err(err).Str("taskId", tasks[i].ID).Msg("Error settling task")
+					numFailed++
+					continue
+				}
+				numSettled++
+			}
+
+			// stop once there is nothing left, or when every task in the batch keeps failing
+			if len(tasks) < settlementBatchSize || numFailed == len(tasks) {
+				break
+			}
+		}
+
+		numCorrected, err := s.ledgerORM.AccrueMissingSettlements(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Error reconciling ledger")
+		} else if numCorrected > 0 {
+			log.Warn().Int("numWorkers", numCorrected).Msg("Corrected worker stakes that did not match the ledger")
+		}
+
+		log.Info().Int("numSettled", numSettled).Msgf("Total time taken to settle tasks: %s", time.Since(startTime))
+	}
+}
+
+// EqualSplitPolicy gives every COMPLETED result the same share
+type EqualSplitPolicy struct{}
+
+func (EqualSplitPolicy) Weights(_ context.Context, _ *db.TaskModel, completedResults []db.TaskResultModel) (map[string]float64, error) {
+	weights := make(map[string]float64, len(completedResults))
+	for _, taskResult := range completedResults {
+		weights[taskResult.ID] = 1
+	}
+	return weights, nil
+}
+
+// ConsensusWeightedPolicy weighs each result by how close its ratings are to the other workers' mean rating.
+// Results without any ratings shared with other workers get a full weight.
+type ConsensusWeightedPolicy struct{}
+
+func (ConsensusWeightedPolicy) Weights(_ context.Context, task *db.TaskModel, completedResults []db.TaskResultModel) (map[string]float64, error) {
+	ratings, err := collectTaskRatings(task, completedResults)
+	if err != nil {
+		return nil, err
+	}
+
+	distances := make(map[string][]float64)
+	for _, units := range []map[string]map[string]float64{ratings.scores, ratings.selections} {
+		for _, byWorker := range units {
+			if len(byWorker) < 2 {
+				continue
+			}
+
+			sum := 0.0
+			for _, value := range byWorker {
+				sum += value
+			}
+			for workerId, value := range byWorker {
+				othersMean := (sum - value) / float64(len(byWorker)-1)
+				distances[workerId] = append(distances[workerId], math.Abs(value-othersMean))
+			}
+		}
+	}
+
+	weights := make(map[string]float64, len(completedResults))
+	for _, taskResult := range completedResults {
+		weights[taskResult.ID] = 1
+		if meanDistance := meanOf(distances[taskResult.WorkerID]); meanDistance != nil {
+			weights[taskResult.ID] = math.Max(0, 1-*meanDistance)
+		}
+	}
+	return weights, nil
+}
+
+// ReputationWeightedPolicy weighs each result by the reputation of its worker
+type ReputationWeightedPolicy struct {
+	dojoWorkerORM *orm.DojoWorkerORM
+}
+
+func (p ReputationWeightedPolicy) Weights(ctx context.Context, _ *db.TaskModel, completedResults []db.TaskResultModel) (map[string]float64, error) {
+	workerIds := make([]string, 0, len(completedResults))
+	for _, taskResult := range completedResults {
+		workerIds = append(workerIds, taskResult.WorkerID)
+	}
+
+	workers, err := p.dojoWorkerORM.GetDojoWorkersByIds(ctx, workerIds)
+	if err != nil {
+		return nil, err
+	}
+
+	reputations := make(map[string]float64, len(workers))
+	for _, worker := range workers {
+		reputations[worker.ID] = worker.Reputation
+	}
+
+	weights := make(map[string]float64, len(completedResults))
+	for _, taskResult := range completedResults {
+		weights[taskResult.ID] = reputations[taskResult.WorkerID]
+	}
+	return weights, nil
+}
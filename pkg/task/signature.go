@@ -0,0 +1,85 @@
+package task
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	resultSignatureDomainName    = "Dojo"
+	resultSignatureDomainVersion = "1"
+)
+
+// ResultSigningMessage is the EIP-191 personal_sign message for a result submission
+func ResultSigningMessage(taskId string, canonicalResultData []byte) string {
+	return fmt.Sprintf("Dojo task result\nTask ID: %s\nResult hash: %s", taskId, crypto.Keccak256Hash(canonicalResultData).Hex())
+}
+
+// personalSignHash is the EIP-191 version 0x45 hash that wallets sign for personal_sign
+func personalSignHash(message string) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+}
+
+var (
+	resultDomainTypeHash = crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId)"))
+	resultTypeHash       = crypto.Keccak256([]byte("TaskResult(string taskId,bytes32 resultHash)"))
+)
+
+// ResultTypedDataHash is the EIP-712 hash of TaskResult(string taskId, bytes32 resultHash) under the
+// domain {name: "Dojo", version: "1", chainId}, as signed with eth_signTypedData_v4
+func ResultTypedDataHash(taskId string, canonicalResultData []byte, chainId *big.Int) []byte {
+	domainSeparator := crypto.Keccak256(
+		resultDomainTypeHash,
+		crypto.Keccak256([]byte(resultSignatureDomainName)),
+		crypto.Keccak256([]byte(resultSignatureDomainVersion)),
+		common.LeftPadBytes(chainId.Bytes(), common.HashLength),
+	)
+	structHash := crypto.Keccak256(
+		resultTypeHash,
+		crypto.Keccak256([]byte(taskId)),
+		crypto.Keccak256(canonicalResultData),
+	)
+	return crypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+}
+
+// VerifyResultSignature checks that the signature over the task ID and canonical result data was made by walletAddress
+func VerifyResultSignature(taskId string, canonicalResultData []byte, signature ResultSignature, walletAddress string, chainId string) error {
+	var hash []byte
+	switch signature.Scheme {
+	case SignatureSchemeEIP191:
+		hash = personalSignHash(ResultSigningMessage(taskId, canonicalResultData))
+	case SignatureSchemeEIP712:
+		chainIdInt, ok := new(big.Int).SetString(chainId, 0)
+		if !ok || chainIdInt.Sign() < 0 {
+			return fmt.Errorf("%w: worker chain id %s cannot be used for EIP-712", ErrInvalidResultSignature, chainId)
+		}
+		hash = ResultTypedDataHash(taskId, canonicalResultData, chainIdInt)
+	default:
+		return fmt.Errorf("%w: unsupported scheme %s", ErrInvalidResultSignature, signature.Scheme)
+	}
+
+	sig, err := hexutil.Decode(signature.Signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("%w: signature must be 65 hex encoded bytes", ErrInvalidResultSignature)
+	}
+	// wallets return v as 27 or 28, go-ethereum expects 0 or 1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResultSignature, err)
+	}
+
+	recoveredAddr := crypto.PubkeyToAddress(*publicKey)
+	if !common.IsHexAddress(walletAddress) || !strings.EqualFold(recoveredAddr.Hex(), common.HexToAddress(walletAddress).Hex()) {
+		return fmt.Errorf("%w: signer %s does not match worker wallet address", ErrInvalidResultSignature, recoveredAddr.Hex())
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"math"
 	"mime/multipart"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
@@ -17,23 +18,30 @@ import (
 	"dojo-api/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
 type TaskService struct {
-	taskORM       *orm.TaskORM
-	taskResultORM *orm.TaskResultORM
+	taskORM           *orm.TaskORM
+	taskResultORM     *orm.TaskResultORM
+	leaseService      *LeaseService
+	goldService       *GoldService
+	timeOnTaskService *TimeOnTaskService
 }
 
 func NewTaskService() *TaskService {
 	return &TaskService{
-		taskORM:       orm.NewTaskORM(),
-		taskResultORM: orm.NewTaskResultORM(),
+		taskORM:           orm.NewTaskORM(),
+		taskResultORM:     orm.NewTaskResultORM(),
+		leaseService:      NewLeaseService(),
+		goldService:       NewGoldService(),
+		timeOnTaskService: NewTimeOnTaskService(),
 	}
 }
 
-// get task by id
-func (taskService *TaskService) GetTaskResponseById(ctx context.Context, id string) (*TaskResponse, error) {
+// get task by id, worker is nil for anonymous readers
+func (taskService *TaskService) GetTaskResponseById(ctx context.Context, id string, worker *db.DojoWorkerModel) (*TaskResponse, error) {
 	taskORM := orm.NewTaskORM()
 
 	task, err := taskORM.GetById(ctx, id)
@@ -45,6 +53,9 @@ func (taskService *TaskService) GetTaskResponseById(ctx context.Context, id stri
 	if task == nil {
 		return nil, fmt.Errorf("no task found with ID %s", id)
 	}
+	if err := CheckMinReputation(task, worker); err != nil {
+		return nil, err
+	}
 
 	var rawJSON json.RawMessage
 	err = json.Unmarshal([]byte(task.TaskData), &rawJSON)
@@ -65,20 +76,40 @@ func (taskService *TaskService) GetTaskResponseById(ctx context.Context, id stri
 	}, nil
 }
 
-// TODO: Implement yieldMin, yieldMax
+// GetTasksByPagination returns the page after params.Cursor when it is set, otherwise the page at params.Page.
+// Both return a cursor of the next page, the total count is only computed for page based requests and when asked for.
 func (taskService *TaskService) GetTasksByPagination(ctx context.Context, workerId string, params PaginationParams) (*TaskPagination, []error) {
-	// Calculate offset based on the page and limit
-	offset := (params.Page - 1) * params.Limit
-
 	// Determine the sort order dynamically
-	var sortQuery db.TaskOrderByParam
-	switch params.Sort {
-	case "createdAt":
-		sortQuery = db.Task.CreatedAt.Order(params.Order)
-	case "numResults":
-		sortQuery = db.Task.NumResults.Order(params.Order)
+	sort := SortField(params.Sort)
+	var sortKey orm.WorkerTaskSortKey
+	switch sort {
+	case SortCreatedAt:
+		sortKey = orm.WorkerTaskSortCreatedAt
+	case SortNumResult:
+		sortKey = orm.WorkerTaskSortNumResults
+	case SortHighestYield:
+		sortKey = orm.WorkerTaskSortYield
 	default:
-		sortQuery = db.Task.CreatedAt.Order(params.Order)
+		return nil, []error{ErrInvalidSortField}
+	}
+
+	// Calculate offset based on the page and limit, unless paging by cursor
+	var after *orm.WorkerTaskKeyset
+	offset := (params.Page - 1) * params.Limit
+	if params.Cursor != "" {
+		cursor, err := DecodeTaskCursor(params.Cursor)
+		if err != nil {
+			return nil, []error{err}
+		}
+		if cursor.Sort != sort || cursor.Order != params.Order {
+			return nil, []error{ErrInvalidCursor}
+		}
+		after = &orm.WorkerTaskKeyset{CreatedAt: cursor.CreatedAt, Value: cursor.Value, Id: cursor.Id}
+		offset = 0
+	}
+
+	if params.YieldMin != nil && params.YieldMax != nil && *params.YieldMin > *params.YieldMax {
+		return nil, []error{ErrInvalidYieldRange}
 	}
 
 	taskModalities, errs := convertStringToTaskModalities(params.Modalities)
@@ -91,12 +122,32 @@ func (taskService *TaskService) GetTasksByPagination(ctx context.Context, worker
 
 	log.Debug().Interface("completedTaskMap", completedTaskMap).Msg("Completed Task Mapping -------")
 
-	tasks, totalTasks, err := taskService.taskORM.GetTasksByWorkerSubscription(ctx, workerId, offset, params.Limit, sortQuery, taskModalities)
+	// Hide tasks whose remaining slots are all leased by other workers
+	saturatedTaskIds, err := taskService.leaseService.GetSaturatedTaskIds(ctx, workerId)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting saturated tasks from leases")
+		return nil, []error{err}
+	}
+
+	filter := orm.WorkerTaskFilter{
+		Modalities:     taskModalities,
+		ExcludeTaskIds: saturatedTaskIds,
+		YieldMin:       params.YieldMin,
+		YieldMax:       params.YieldMax,
+	}
+	// fetch one more task than the limit to know whether there is a next page
+	tasks, err := taskService.taskORM.GetTasksByWorkerSubscription(ctx, workerId, filter, sortKey, params.Order, after, offset, params.Limit+1)
 	if err != nil {
 		log.Error().Err(err).Msg("Error getting tasks by pagination")
 		return nil, []error{err}
 	}
 
+	var nextCursor string
+	if len(tasks) > params.Limit {
+		tasks = tasks[:params.Limit]
+		nextCursor = EncodeTaskCursor(newWorkerTaskCursor(tasks[len(tasks)-1], sort, params.Order))
+	}
+
 	// Convert tasks to TaskResponse model
 	taskResponses := make([]TaskPaginationResponse, 0)
 	for _, task := range tasks {
@@ -123,24 +174,48 @@ func (taskService *TaskService) GetTasksByPagination(ctx context.Context, worker
 				MaxResults: task.MaxResults,
 			},
 			IsCompletedByWorker: completedTaskMap[task.ID], // Set the completion status.
+			Yield:               TaskYield(task),
 		}
 		taskResponses = append(taskResponses, taskResponse)
 	}
 
+	taskPagination := &TaskPagination{
+		Tasks:      taskResponses,
+		NextCursor: nextCursor,
+	}
+	if params.Cursor != "" && !params.IncludeTotal {
+		return taskPagination, []error{}
+	}
+
+	totalTasks, err := taskService.taskORM.CountTasksByWorkerSubscription(ctx, workerId, filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Error counting tasks by pagination")
+		return nil, []error{err}
+	}
+	log.Info().Int("totalTasks", totalTasks).Msgf("Successfully fetched total tasks fetched for worker ID %v", workerId)
+
+	if params.Cursor != "" {
+		taskPagination.TotalItems = &totalTasks
+		return taskPagination, []error{}
+	}
+
 	totalPages := int(math.Ceil(float64(totalTasks) / float64(params.Limit)))
 
 	// Construct pagination metadata
-	pagination := Pagination{
+	taskPagination.Pagination = &Pagination{
 		Page:       params.Page,
 		Limit:      params.Limit,
 		TotalPages: totalPages,
 		TotalItems: totalTasks,
 	}
+	return taskPagination, []error{}
+}
 
-	return &TaskPagination{
-		Tasks:      taskResponses,
-		Pagination: pagination,
-	}, []error{}
+// TaskYield is the task's total reward per result, computed the same way as the yield the worker task listing is
+// sorted and filtered by
+func TaskYield(task db.TaskModel) float64 {
+	totalReward, _ := task.TotalReward()
+	return totalReward / float64(max(task.MaxResults, 1))
 }
 
 func convertStringToTaskModalities(taskModalities []string) ([]db.TaskModality, []error) {
@@ -188,7 +263,7 @@ func IsValidTaskModality(taskModality interface{}) (bool, error) {
 
 func IsValidCriteriaType(criteriaType CriteriaType) bool {
 	switch criteriaType {
-	case CriteriaTypeScore, CriteriaTypeText:
+	case CriteriaTypeScore, CriteriaTypeText, CriteriaTypeRanking, CriteriaTypeMultiScore, CriteriaTypeMultiSelect:
 		return true
 	default:
 		return false
@@ -237,6 +312,10 @@ func (s *TaskService) CreateTasks(ctx context.Context, request CreateTaskRequest
 	for _, currTask := range request.TaskData {
 		taskModality := db.TaskModality(currTask.TaskModality)
 
+		// keep gold answers out of the task data, which is returned to workers
+		goldAnswers := currTask.GoldAnswers
+		currTask.GoldAnswers = nil
+
 		taskData, err := json.Marshal(currTask)
 		if err != nil {
 			log.Error().Err(err).Msgf("Error marshaling task data")
@@ -266,6 +345,21 @@ func (s *TaskService) CreateTasks(ctx context.Context, request CreateTaskRequest
 			taskToCreate.TotalReward = &request.TotalRewards
 		}
 
+		if request.MinReputation != nil {
+			taskToCreate.MinReputation = request.MinReputation
+		}
+
+		if request.IsGold {
+			goldAnswersJSON, err := json.Marshal(goldAnswers)
+			if err != nil {
+				log.Error().Err(err).Msg("Error marshaling gold answers")
+				errors = append(errors, err)
+				continue
+			}
+			taskToCreate.IsGold = true
+			taskToCreate.GoldAnswers = (*db.JSON)(&goldAnswersJSON)
+		}
+
 		task, err := taskORM.CreateTask(ctx, taskToCreate, minerUserId)
 		if err != nil {
 			log.Error().Msgf("Error creating task: %v", err)
@@ -276,11 +370,35 @@ func (s *TaskService) CreateTasks(ctx context.Context, request CreateTaskRequest
 	return tasks, errors
 }
 
+// GetNextInProgressTask returns the next task the worker can work on, skipping tasks fully leased by other workers
+func (t *TaskService) GetNextInProgressTask(ctx context.Context, taskId string, workerId string) (*db.TaskModel, error) {
+	saturatedTaskIds, err := t.leaseService.GetSaturatedTaskIds(ctx, workerId)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting saturated tasks from leases")
+		return nil, err
+	}
+
+	return t.taskORM.GetNextInProgressTask(ctx, taskId, workerId, saturatedTaskIds)
+}
+
+// CheckMinReputation returns ErrInsufficientReputation if the task requires a higher reputation than the worker's.
+// A nil worker is an anonymous reader, who only has access to tasks without a minimum reputation.
+func CheckMinReputation(task *db.TaskModel, worker *db.DojoWorkerModel) error {
+	minReputation, ok := task.MinReputation()
+	if !ok {
+		return nil
+	}
+	if worker == nil || worker.Reputation < minReputation {
+		return ErrInsufficientReputation
+	}
+	return nil
+}
+
 func (t *TaskService) GetTaskById(ctx context.Context, id string) (*db.TaskModel, error) {
 	task, err := t.taskORM.GetById(ctx, id)
 	if err != nil {
 		if errors.Is(err, db.ErrNotFound) {
-			return nil, fmt.Errorf("task with ID %s not found", id)
+			return nil, ErrTaskNotFound
 		}
 		return nil, err
 	}
@@ -288,32 +406,137 @@ func (t *TaskService) GetTaskById(ctx context.Context, id string) (*db.TaskModel
 	return task, nil
 }
 
-// TODO: Update this function with the new Resultdata structure
-func (t *TaskService) UpdateTaskResults(ctx context.Context, task *db.TaskModel, dojoWorkerId string, results []Result) (*db.TaskModel, error) {
-	validatedResults, err := ValidateResultData(results, task)
+// CancelTask withdraws an in-progress task on behalf of the miner that created it
+func (t *TaskService) CancelTask(ctx context.Context, taskId string, minerUserId string) (*db.TaskModel, error) {
+	task, err := t.taskORM.GetById(ctx, taskId)
 	if err != nil {
-		log.Error().Err(err).Msg("Error validating result data")
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, ErrTaskNotFound
+		}
 		return nil, err
 	}
 
-	// Process and scale the scores
-	processedResults, err := ProcessResults(validatedResults, task)
+	if ownerId, ok := task.MinerUserID(); !ok || ownerId != minerUserId {
+		return nil, ErrTaskNotOwnedByMiner
+	}
+
+	if task.Status != db.TaskStatusInProgress {
+		return nil, ErrTaskNotCancellable
+	}
+
+	numUpdated, err := t.taskORM.CancelTask(ctx, taskId, minerUserId)
 	if err != nil {
-		log.Error().Err(err).Msg("Error processing scores")
 		return nil, err
 	}
 
-	jsonResults, err := json.Marshal(processedResults)
+	// task changed status between the read and the update
+	if numUpdated == 0 {
+		return nil, ErrTaskNotCancellable
+	}
+
+	// the task is already cancelled, leftover leases expire on their own
+	if err := t.leaseService.ReleaseTaskLeases(ctx, taskId); err != nil {
+		log.Warn().Err(err).Str("taskId", taskId).Msg("Failed to release leases of cancelled task")
+	}
+
+	task.Status = db.TaskStatusCancelled
+	return task, nil
+}
+
+// GetMinerTasks lists a page of the miner's tasks newest first, starting after cursor when it is not empty,
+// along with the number of matching tasks per status
+func (t *TaskService) GetMinerTasks(ctx context.Context, minerUserId string, filter orm.MinerTaskFilter, cursor string, limit int) (*MinerTaskListResponse, error) {
+	var beforeCreatedAt time.Time
+	beforeId := ""
+	if cursor != "" {
+		decoded, err := DecodeTaskCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		beforeCreatedAt, beforeId = decoded.CreatedAt, decoded.Id
+	}
+
+	// fetch one extra task to know whether there is a next page
+	tasks, err := t.taskORM.GetMinerTasksBefore(ctx, minerUserId, filter, beforeCreatedAt, beforeId, limit+1)
 	if err != nil {
-		log.Error().Err(err).Msg("Error marshaling result items")
+		log.Error().Err(err).Str("minerUserId", minerUserId).Msg("Error fetching miner tasks")
 		return nil, err
 	}
 
-	newTaskResultData := db.InnerTaskResult{
-		Status:     db.TaskResultStatusCompleted,
-		ResultData: jsonResults,
-		TaskID:     task.ID,
-		WorkerID:   dojoWorkerId,
+	statusCounts, err := t.taskORM.CountMinerTasksByStatus(ctx, minerUserId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &MinerTaskListResponse{
+		Tasks:        make([]MinerTaskResponse, 0, limit),
+		StatusCounts: statusCounts,
+	}
+	for status, count := range statusCounts {
+		if len(filter.Statuses) == 0 || slices.Contains(filter.Statuses, status) {
+			response.TotalTasks += count
+		}
+	}
+
+	if len(tasks) > limit {
+		last := tasks[limit-1]
+		nextCursor := EncodeTaskCursor(TaskCursor{CreatedAt: last.CreatedAt, Id: last.ID})
+		response.NextCursor = &nextCursor
+		tasks = tasks[:limit]
+	}
+
+	for _, task := range tasks {
+		row := MinerTaskResponse{
+			TaskId:     task.ID,
+			Title:      task.Title,
+			Modality:   task.Modality,
+			Status:     task.Status,
+			NumResults: task.NumResults,
+			MaxResults: task.MaxResults,
+			CreatedAt:  task.CreatedAt,
+			ExpireAt:   task.ExpireAt,
+		}
+		if totalReward, ok := task.TotalReward(); ok {
+			row.TotalReward = &totalReward
+		}
+		if completedAt, ok := task.CompletedAt(); ok {
+			completionSeconds := completedAt.Sub(task.CreatedAt).Seconds()
+			row.CompletedAt = &completedAt
+			row.CompletionSeconds = &completionSeconds
+		}
+		response.Tasks = append(response.Tasks, row)
+	}
+
+	return response, nil
+}
+
+// TODO: Update this function with the new Resultdata structure
+// UpdateTaskResults validates and stores the worker's results, signedResult is nil for unsigned submissions
+func (t *TaskService) UpdateTaskResults(ctx context.Context, task *db.TaskModel, worker *db.DojoWorkerModel, results []Result, signedResult *SignedResult) (*db.TaskModel, error) {
+	if err := CheckMinReputation(task, worker); err != nil {
+		return nil, err
+	}
+	dojoWorkerId := worker.ID
+
+	newTaskResultData, processedResults, err := buildTaskResult(task, dojoWorkerId, results, signedResult)
+	if err != nil {
+		return nil, err
+	}
+
+	// timing is best effort, a result without it is still recorded
+	timeOnTask, err := t.timeOnTaskService.Measure(ctx, task, dojoWorkerId)
+	if err != nil {
+		log.Warn().Err(err).Str("taskId", task.ID).Str("workerId", dojoWorkerId).Msg("Error measuring time on task")
+	} else if timeOnTask != nil {
+		newTaskResultData.OpenedAt = &timeOnTask.OpenedAt
+		newTaskResultData.TimeOnTaskSeconds = &timeOnTask.Seconds
+		newTaskResultData.IsFastSubmission = timeOnTask.IsFastSubmission
+		if timeOnTask.IsFastSubmission {
+			log.Info().Str("taskId", task.ID).Str("workerId", dojoWorkerId).Float64("seconds", timeOnTask.Seconds).Msg("Fast submission flagged")
+		}
+	} else {
+		// the worker neither claimed nor opened the task signed in, the result is counted as untimed rather than fast
+		log.Info().Str("taskId", task.ID).Str("workerId", dojoWorkerId).Msg("Submission without a recorded task open")
 	}
 
 	// Check if the task has reached the max results, no way we can have greater than max results, or something's wrong
@@ -322,17 +545,88 @@ func (t *TaskService) UpdateTaskResults(ctx context.Context, task *db.TaskModel,
 		newTaskResultData.Status = db.TaskResultStatusInvalid
 	}
 
+	// The slots leased by other workers are checked again inside the insert, CanSubmit alone races with other submissions
+	leaseHolders, _, err := t.leaseService.GetLeaseHolders(ctx, task.ID, dojoWorkerId)
+	if err != nil {
+		log.Error().Err(err).Str("taskId", task.ID).Msg("Error getting task lease holders")
+		return nil, err
+	}
+
 	// Insert the task result data
 	taskResultORM := orm.NewTaskResultORM()
-	createdTaskResult, err := taskResultORM.CreateTaskResult(ctx, &newTaskResultData)
+	createdTaskResult, err := taskResultORM.CreateTaskResult(ctx, newTaskResultData, leaseHolders)
 	if err != nil {
+		if errors.Is(err, orm.ErrSlotsReserved) {
+			return nil, ErrNoLeaseSlotAvailable
+		}
 		return nil, err
 	}
 
+	// grading is best effort, the worker's result is already recorded
+	if task.IsGold && createdTaskResult.Status == db.TaskResultStatusCompleted {
+		if _, err := t.goldService.GradeSubmission(ctx, task, createdTaskResult, processedResults); err != nil {
+			log.Error().Err(err).Str("taskId", task.ID).Str("workerId", dojoWorkerId).Msg("Error grading gold task submission")
+		}
+	}
+
 	return createdTaskResult.Task(), nil
 }
 
-func ValidateResultData(results []Result, task *db.TaskModel) ([]Result, error) {
+// ReviseTaskResult replaces the worker's COMPLETED result of an IN_PROGRESS task, the previous version is kept as a
+// revision. Gold tasks keep the grade of the first submission, so revisions cannot be used to probe gold answers.
+func (t *TaskService) ReviseTaskResult(ctx context.Context, task *db.TaskModel, worker *db.DojoWorkerModel, results []Result, signedResult *SignedResult) (*db.TaskResultModel, error) {
+	if err := CheckMinReputation(task, worker); err != nil {
+		return nil, err
+	}
+
+	revisedTaskResultData, _, err := buildTaskResult(task, worker.ID, results, signedResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.taskResultORM.ReviseTaskResult(ctx, revisedTaskResultData)
+}
+
+// buildTaskResult validates and processes the worker's results into a COMPLETED task result
+func buildTaskResult(task *db.TaskModel, dojoWorkerId string, results []Result, signedResult *SignedResult) (*db.InnerTaskResult, []Result, error) {
+	validatedResults, err := ValidateResultData(results, task)
+	if err != nil {
+		log.Error().Err(err).Msg("Error validating result data")
+		return nil, nil, err
+	}
+
+	// Process and scale the scores
+	processedResults, err := ProcessResults(validatedResults, task)
+	if err != nil {
+		log.Error().Err(err).Msg("Error processing scores")
+		return nil, nil, err
+	}
+
+	jsonResults, err := json.Marshal(processedResults)
+	if err != nil {
+		log.Error().Err(err).Msg("Error marshaling result items")
+		return nil, nil, err
+	}
+
+	taskResultData := &db.InnerTaskResult{
+		Status:     db.TaskResultStatusCompleted,
+		ResultData: jsonResults,
+		TaskID:     task.ID,
+		WorkerID:   dojoWorkerId,
+	}
+	if signedResult != nil {
+		signature := signedResult.Signature.Signature
+		scheme := string(signedResult.Signature.Scheme)
+		signedResultData := string(signedResult.CanonicalResultData)
+		taskResultData.Signature = &signature
+		taskResultData.SignatureScheme = &scheme
+		taskResultData.SignedResultData = &signedResultData
+	}
+	return taskResultData, processedResults, nil
+}
+
+// buildModelCriteriaMap indexes the task's criteria by model, then by criteria key for faster lookup
+func buildModelCriteriaMap(task *db.TaskModel) (map[string]map[string]Criteria, error) {
 	var taskData TaskData
 	err := json.Unmarshal(task.TaskData, &taskData)
 	if err != nil {
@@ -340,41 +634,79 @@ func ValidateResultData(results []Result, task *db.TaskModel) ([]Result, error)
 		return nil, err
 	}
 
-	// Pre-process task criteria for faster lookup
-	modelCriteriaMap := make(map[string]map[CriteriaType]Criteria)
+	modelCriteriaMap := make(map[string]map[string]Criteria)
 	for _, response := range taskData.Responses {
-		criteriaMap := make(map[CriteriaType]Criteria)
+		criteriaMap := make(map[string]Criteria)
 		for _, criteria := range response.Criteria {
-			criteriaMap[criteria.GetType()] = criteria
+			criteriaMap[criteriaKey(criteria)] = criteria
 		}
 		modelCriteriaMap[response.Model] = criteriaMap
 	}
+	return modelCriteriaMap, nil
+}
+
+func ValidateResultData(results []Result, task *db.TaskModel) ([]Result, error) {
+	modelCriteriaMap, err := buildModelCriteriaMap(task)
+	if err != nil {
+		return nil, err
+	}
 
 	// Validate results
+	submittedModels := make(map[string]bool)
 	for _, result := range results {
 		criteriaMap, exists := modelCriteriaMap[result.Model]
 		if !exists {
 			return nil, fmt.Errorf("model %s not found in task data", result.Model)
 		}
+		if submittedModels[result.Model] {
+			return nil, fmt.Errorf("duplicate results for model %s", result.Model)
+		}
+		submittedModels[result.Model] = true
 
+		submittedCriteria := make(map[string]bool)
 		for _, criteria := range result.Criteria {
-			if err := validateCriteria(criteria, criteriaMap); err != nil {
+			key := criteriaKey(criteria)
+			taskCriteria, exists := criteriaMap[key]
+			if !exists {
+				return nil, fmt.Errorf("validation failed for model %s: unknown criteria %s", result.Model, key)
+			}
+			if submittedCriteria[key] {
+				return nil, fmt.Errorf("validation failed for model %s: duplicate criteria %s", result.Model, key)
+			}
+			submittedCriteria[key] = true
+
+			if err := validateCriteria(criteria, taskCriteria); err != nil {
 				return nil, fmt.Errorf("validation failed for model %s: %w", result.Model, err)
 			}
 		}
+
+		for key := range criteriaMap {
+			if !submittedCriteria[key] {
+				return nil, fmt.Errorf("validation failed for model %s: missing criteria %s", result.Model, key)
+			}
+		}
+	}
+
+	for model := range modelCriteriaMap {
+		if !submittedModels[model] {
+			return nil, fmt.Errorf("missing results for model %s", model)
+		}
 	}
 
 	log.Info().Str("resultData", fmt.Sprintf("%v", results)).Msgf("Result data validated successfully")
 	return results, nil
 }
 
-// Helper function to validate individual criteria
-// TODO: Might need to update this, if necessary to add criteria id since we might have multiple same criteria types
-func validateCriteria(criteria Criteria, criteriaMap map[CriteriaType]Criteria) error {
+// Helper function to validate individual criteria against the matching task criteria
+func validateCriteria(criteria Criteria, matchedCriteria Criteria) error {
 	if err := criteria.Validate(); err != nil {
 		return err
 	}
 
+	if criteria.GetType() != matchedCriteria.GetType() {
+		return fmt.Errorf("criteria %s should be of type %s, got %s", criteriaKey(criteria), matchedCriteria.GetType(), criteria.GetType())
+	}
+
 	switch criteria.GetType() {
 	case CriteriaTypeScore:
 		submitted, ok := criteria.(ScoreCriteria)
@@ -382,14 +714,13 @@ func validateCriteria(criteria Criteria, criteriaMap map[CriteriaType]Criteria)
 			return fmt.Errorf("invalid score criteria type")
 		}
 
-		taskCriteria, ok := criteriaMap[CriteriaTypeScore].(ScoreCriteria)
+		taskCriteria, ok := matchedCriteria.(ScoreCriteria)
 		if !ok {
 			return fmt.Errorf("no matching score criteria found in task")
 		}
 
-		if submitted.MinerScore < taskCriteria.Min || submitted.MinerScore > taskCriteria.Max {
-			return fmt.Errorf("score %v is out of the valid range [%v, %v]",
-				submitted.MinerScore, taskCriteria.Min, taskCriteria.Max)
+		if err := validateScoreOnScale(submitted.MinerScore, taskCriteria.Min, taskCriteria.Max, taskCriteria.Step); err != nil {
+			return err
 		}
 	case CriteriaTypeText:
 		submitted, ok := criteria.(TextCriteria)
@@ -401,6 +732,70 @@ func validateCriteria(criteria Criteria, criteriaMap map[CriteriaType]Criteria)
 		if submitted.TextFeedback == "" {
 			return fmt.Errorf("text feedback is required")
 		}
+	case CriteriaTypeRanking:
+		submitted, ok := criteria.(RankingCriteria)
+		if !ok {
+			return fmt.Errorf("invalid ranking criteria type")
+		}
+
+		taskCriteria, ok := matchedCriteria.(RankingCriteria)
+		if !ok {
+			return fmt.Errorf("no matching ranking criteria found in task")
+		}
+
+		if err := validateRankingValue(submitted.Value, taskCriteria.Options); err != nil {
+			return err
+		}
+	case CriteriaTypeMultiScore:
+		submitted, ok := criteria.(MultiScoreCriteria)
+		if !ok {
+			return fmt.Errorf("invalid multi-score criteria type")
+		}
+
+		taskCriteria, ok := matchedCriteria.(MultiScoreCriteria)
+		if !ok {
+			return fmt.Errorf("no matching multi-score criteria found in task")
+		}
+
+		if len(submitted.Value) != len(taskCriteria.Options) {
+			return fmt.Errorf("expected scores for %d options, got %d", len(taskCriteria.Options), len(submitted.Value))
+		}
+
+		for _, option := range taskCriteria.Options {
+			score, ok := submitted.Value[option]
+			if !ok {
+				return fmt.Errorf("missing score for option %s", option)
+			}
+			if err := validateScoreOnScale(score, taskCriteria.Min, taskCriteria.Max, taskCriteria.Step); err != nil {
+				return fmt.Errorf("invalid score for option %s: %w", option, err)
+			}
+		}
+	case CriteriaTypeMultiSelect:
+		submitted, ok := criteria.(MultiSelectCriteria)
+		if !ok {
+			return fmt.Errorf("invalid multi-select criteria type")
+		}
+
+		taskCriteria, ok := matchedCriteria.(MultiSelectCriteria)
+		if !ok {
+			return fmt.Errorf("no matching multi-select criteria found in task")
+		}
+
+		validOptions := make(map[string]bool, len(taskCriteria.Options))
+		for _, option := range taskCriteria.Options {
+			validOptions[option] = true
+		}
+
+		selected := make(map[string]bool, len(submitted.Value))
+		for _, option := range submitted.Value {
+			if !validOptions[option] {
+				return fmt.Errorf("selected option %s is not one of the task options", option)
+			}
+			if selected[option] {
+				return fmt.Errorf("option %s is selected more than once", option)
+			}
+			selected[option] = true
+		}
 
 	default:
 		return fmt.Errorf("unknown criteria type: %s", criteria.GetType())
@@ -409,21 +804,38 @@ func validateCriteria(criteria Criteria, criteriaMap map[CriteriaType]Criteria)
 	return nil
 }
 
-func ProcessResults(results []Result, task *db.TaskModel) ([]Result, error) {
-	var taskData TaskData
-	err := json.Unmarshal(task.TaskData, &taskData)
-	if err != nil {
-		log.Error().Err(err).Msg("Error unmarshaling task data")
-		return nil, err
+// validateRankingValue ensures the ranking assigns every option exactly one of the positions 1..len(options)
+func validateRankingValue(value RankingValue, options []string) error {
+	if len(value) != len(options) {
+		return fmt.Errorf("expected %d ranked options, got %d", len(options), len(value))
 	}
 
-	modelCriteriaMap := make(map[string]map[CriteriaType]Criteria)
-	for _, response := range taskData.Responses {
-		criteriaMap := make(map[CriteriaType]Criteria)
-		for _, criteria := range response.Criteria {
-			criteriaMap[criteria.GetType()] = criteria
+	validOptions := make(map[string]bool, len(options))
+	for _, option := range options {
+		validOptions[option] = true
+	}
+
+	rankedOptions := make(map[string]bool, len(value))
+	for rankStr, option := range value {
+		rank, err := strconv.Atoi(rankStr)
+		if err != nil || rank < 1 || rank > len(options) {
+			return fmt.Errorf("invalid rank %s, must be between 1 and %d", rankStr, len(options))
 		}
-		modelCriteriaMap[response.Model] = criteriaMap
+		if !validOptions[option] {
+			return fmt.Errorf("ranked option %s is not one of the task options", option)
+		}
+		if rankedOptions[option] {
+			return fmt.Errorf("option %s is ranked more than once", option)
+		}
+		rankedOptions[option] = true
+	}
+	return nil
+}
+
+func ProcessResults(results []Result, task *db.TaskModel) ([]Result, error) {
+	modelCriteriaMap, err := buildModelCriteriaMap(task)
+	if err != nil {
+		return nil, err
 	}
 
 	for i, result := range results {
@@ -433,6 +845,11 @@ func ProcessResults(results []Result, task *db.TaskModel) ([]Result, error) {
 		}
 
 		for j, submittedCriteria := range result.Criteria {
+			matchedCriteria, exists := criteriaMap[criteriaKey(submittedCriteria)]
+			if !exists {
+				return nil, fmt.Errorf("criteria %s not found in task for model %s", criteriaKey(submittedCriteria), result.Model)
+			}
+
 			switch submittedCriteria.GetType() {
 			case CriteriaTypeScore:
 				submitted, ok := submittedCriteria.(ScoreCriteria)
@@ -440,17 +857,24 @@ func ProcessResults(results []Result, task *db.TaskModel) ([]Result, error) {
 					return nil, fmt.Errorf("invalid score criteria type for model %s", result.Model)
 				}
 
-				taskCriteria, ok := criteriaMap[CriteriaTypeScore].(ScoreCriteria)
+				taskCriteria, ok := matchedCriteria.(ScoreCriteria)
 				if !ok {
 					return nil, fmt.Errorf("no matching score criteria found in task for model %s", result.Model)
 				}
 
-				scaledScore := scaleScore(submitted.MinerScore, 1, 10, taskCriteria.Min, taskCriteria.Max)
+				// keep the worker's original rating alongside the normalised score
+				rawScore := submitted.MinerScore
+				normalisedScore := normaliseScore(rawScore, taskCriteria.Min, taskCriteria.Max, taskCriteria.OutputMin, taskCriteria.OutputMax)
 				results[i].Criteria[j] = ScoreCriteria{
+					ID:         matchedCriteria.GetId(),
 					Type:       CriteriaTypeScore,
 					Min:        taskCriteria.Min,
 					Max:        taskCriteria.Max,
-					MinerScore: scaledScore,
+					Step:       taskCriteria.Step,
+					OutputMin:  taskCriteria.OutputMin,
+					OutputMax:  taskCriteria.OutputMax,
+					MinerScore: normalisedScore,
+					RawScore:   &rawScore,
 				}
 			case CriteriaTypeText:
 				submitted, ok := submittedCriteria.(TextCriteria)
@@ -459,10 +883,95 @@ func ProcessResults(results []Result, task *db.TaskModel) ([]Result, error) {
 				}
 
 				results[i].Criteria[j] = TextCriteria{
+					ID:           matchedCriteria.GetId(),
 					Type:         CriteriaTypeText,
 					Query:        submitted.Query,
 					TextFeedback: submitted.TextFeedback,
 				}
+			case CriteriaTypeRanking:
+				submitted, ok := submittedCriteria.(RankingCriteria)
+				if !ok {
+					return nil, fmt.Errorf("invalid ranking criteria type for model %s", result.Model)
+				}
+
+				taskCriteria, ok := matchedCriteria.(RankingCriteria)
+				if !ok {
+					return nil, fmt.Errorf("no matching ranking criteria found in task for model %s", result.Model)
+				}
+
+				// canonicalise rank keys, e.g. "01" -> "1"
+				ranking := make(RankingValue, len(submitted.Value))
+				for rankStr, option := range submitted.Value {
+					rank, _ := strconv.Atoi(rankStr)
+					ranking[strconv.Itoa(rank)] = option
+				}
+
+				results[i].Criteria[j] = RankingCriteria{
+					ID:      matchedCriteria.GetId(),
+					Type:    CriteriaTypeRanking,
+					Options: taskCriteria.Options,
+					Value:   ranking,
+				}
+			case CriteriaTypeMultiScore:
+				submitted, ok := submittedCriteria.(MultiScoreCriteria)
+				if !ok {
+					return nil, fmt.Errorf("invalid multi-score criteria type for model %s", result.Model)
+				}
+
+				taskCriteria, ok := matchedCriteria.(MultiScoreCriteria)
+				if !ok {
+					return nil, fmt.Errorf("no matching multi-score criteria found in task for model %s", result.Model)
+				}
+
+				rawScores := make(MultiScoreValue, len(taskCriteria.Options))
+				normalisedScores := make(MultiScoreValue, len(taskCriteria.Options))
+				for _, option := range taskCriteria.Options {
+					rawScores[option] = submitted.Value[option]
+					normalisedScores[option] = normaliseScore(submitted.Value[option],
+						taskCriteria.Min, taskCriteria.Max, taskCriteria.OutputMin, taskCriteria.OutputMax)
+				}
+
+				results[i].Criteria[j] = MultiScoreCriteria{
+					ID:        matchedCriteria.GetId(),
+					Type:      CriteriaTypeMultiScore,
+					Options:   taskCriteria.Options,
+					Min:       taskCriteria.Min,
+					Max:       taskCriteria.Max,
+					Step:      taskCriteria.Step,
+					OutputMin: taskCriteria.OutputMin,
+					OutputMax: taskCriteria.OutputMax,
+					Value:     normalisedScores,
+					RawValue:  rawScores,
+				}
+			case CriteriaTypeMultiSelect:
+				submitted, ok := submittedCriteria.(MultiSelectCriteria)
+				if !ok {
+					return nil, fmt.Errorf("invalid multi-select criteria type for model %s", result.Model)
+				}
+
+				taskCriteria, ok := matchedCriteria.(MultiSelectCriteria)
+				if !ok {
+					return nil, fmt.Errorf("no matching multi-select criteria found in task for model %s", result.Model)
+				}
+
+				// store selections in the same order as the task options
+				selected := make(map[string]bool, len(submitted.Value))
+				for _, option := range submitted.Value {
+					selected[option] = true
+				}
+				selections := make(MultiSelectValue, 0, len(selected))
+				for _, option := range taskCriteria.Options {
+					if selected[option] {
+						selections = append(selections, option)
+					}
+				}
+
+				results[i].Criteria[j] = MultiSelectCriteria{
+					ID:      matchedCriteria.GetId(),
+					Type:    CriteriaTypeMultiSelect,
+					Options: taskCriteria.Options,
+					Value:   selections,
+				}
 			}
 		}
 	}
@@ -531,10 +1040,18 @@ func ValidateTaskData(taskData TaskData) error {
 		}
 
 		// Validate each criteria
+		criteriaIds := make(map[string]bool)
 		for _, criteria := range taskresponse.Criteria {
 			if err := criteria.Validate(); err != nil {
 				return fmt.Errorf("invalid criteria for model %s: %w", taskresponse.Model, err)
 			}
+
+			if id := criteria.GetId(); id != "" {
+				if criteriaIds[id] {
+					return fmt.Errorf("duplicate criteria id %s for model %s", id, taskresponse.Model)
+				}
+				criteriaIds[id] = true
+			}
 		}
 	}
 
@@ -559,18 +1076,31 @@ func ValidateTaskRequest(request CreateTaskRequest) error {
 		if err != nil {
 			return err
 		}
+
+		if request.IsGold {
+			if err := validateGoldAnswers(currTask); err != nil {
+				return err
+			}
+		} else if len(currTask.GoldAnswers) > 0 {
+			return errors.New("goldAnswers can only be set on gold tasks")
+		}
 	}
 
 	if request.MaxResults == 0 {
 		return errors.New("maxResults is required")
 	}
 
+	if request.MinReputation != nil && (*request.MinReputation < 0 || *request.MinReputation > 1) {
+		return errors.New("minReputation must be between 0 and 1")
+	}
+
 	return nil
 }
 
 func ProcessTaskRequest(taskData CreateTaskRequest) (CreateTaskRequest, error) {
 	processedTaskData := make([]TaskData, 0)
 	for _, taskInterface := range taskData.TaskData {
+		taskInterface = assignCriteriaIds(taskInterface)
 		if taskInterface.TaskModality == db.TaskModalityCodeGeneration {
 			processedTaskEntry, err := ProcessCodeCompletion(taskInterface)
 			if err != nil {
@@ -586,6 +1116,19 @@ func ProcessTaskRequest(taskData CreateTaskRequest) (CreateTaskRequest, error) {
 	return taskData, nil
 }
 
+// assignCriteriaIds gives every criteria without an ID a stable one, so workers can reference
+// each criteria in their results even when a response has several criteria of the same type
+func assignCriteriaIds(taskData TaskData) TaskData {
+	for i, response := range taskData.Responses {
+		for j, criteria := range response.Criteria {
+			if criteria.GetId() == "" {
+				taskData.Responses[i].Criteria[j] = withCriteriaId(criteria, uuid.NewString())
+			}
+		}
+	}
+	return taskData
+}
+
 func ProcessCodeCompletion(taskData TaskData) (TaskData, error) {
 	responses := taskData.Responses
 	for i, response := range responses {
@@ -662,6 +1205,17 @@ func ProcessRequestBody(c *gin.Context) (CreateTaskRequest, error) {
 	expireAt := c.PostForm("expireAt")
 	maxResults, _ := strconv.Atoi(c.PostForm("maxResults"))
 	totalRewards, _ := strconv.ParseFloat(c.PostForm("totalRewards"), 64)
+	isGold, _ := strconv.ParseBool(c.PostForm("isGold"))
+
+	var minReputation *float64
+	if minReputationStr := c.PostForm("minReputation"); minReputationStr != "" {
+		value, err := strconv.ParseFloat(minReputationStr, 64)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid minReputation")
+			return reqbody, fmt.Errorf("invalid minReputation: %w", err)
+		}
+		minReputation = &value
+	}
 
 	var taskData []TaskData
 	if err := json.Unmarshal([]byte(c.PostForm("taskData")), &taskData); err != nil {
@@ -670,12 +1224,14 @@ func ProcessRequestBody(c *gin.Context) (CreateTaskRequest, error) {
 	}
 
 	reqbody = CreateTaskRequest{
-		Title:        title,
-		Body:         body,
-		ExpireAt:     expireAt,
-		TaskData:     taskData,
-		MaxResults:   maxResults,
-		TotalRewards: totalRewards,
+		Title:         title,
+		Body:          body,
+		ExpireAt:      expireAt,
+		TaskData:      taskData,
+		MaxResults:    maxResults,
+		TotalRewards:  totalRewards,
+		IsGold:        isGold,
+		MinReputation: minReputation,
 	}
 
 	return reqbody, nil
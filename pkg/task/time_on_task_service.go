@@ -0,0 +1,96 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+
+	"github.com/rs/zerolog/log"
+)
+
+// submissions within this many seconds of opening the task are flagged as fast, unless configured per modality
+const defaultMinTimeOnTaskSeconds = 10
+
+// TimeOnTask is how long a worker spent on a task, from first opening it to submitting
+type TimeOnTask struct {
+	OpenedAt         time.Time
+	Seconds          float64
+	IsFastSubmission bool
+}
+
+type TimeOnTaskService struct {
+	taskOpenORM *orm.TaskOpenORM
+	minSeconds  map[db.TaskModality]int
+}
+
+// NewTimeOnTaskService reads the minimum time on task from MIN_TIME_ON_TASK_SECONDS, which can be overridden per
+// modality with e.g. MIN_TIME_ON_TASK_SECONDS_CODE_GENERATION
+func NewTimeOnTaskService() *TimeOnTaskService {
+	defaultMinSeconds := getPositiveIntEnv("MIN_TIME_ON_TASK_SECONDS", defaultMinTimeOnTaskSeconds)
+	minSeconds := make(map[db.TaskModality]int, len(ValidTaskModalities))
+	for _, modality := range ValidTaskModalities {
+		minSeconds[modality] = getPositiveIntEnv("MIN_TIME_ON_TASK_SECONDS_"+string(modality), defaultMinSeconds)
+	}
+
+	return &TimeOnTaskService{
+		taskOpenORM: orm.NewTaskOpenORM(),
+		minSeconds:  minSeconds,
+	}
+}
+
+// RecordTaskOpen stores when the worker first opened or claimed a task, later opens do not reset it
+func (s *TimeOnTaskService) RecordTaskOpen(ctx context.Context, taskId string, workerId string) error {
+	return s.taskOpenORM.RecordOpen(ctx, taskId, workerId)
+}
+
+// Measure returns the worker's time on task up to now, nil if the worker never opened nor claimed the task
+func (s *TimeOnTaskService) Measure(ctx context.Context, task *db.TaskModel, workerId string) (*TimeOnTask, error) {
+	taskOpen, err := s.taskOpenORM.GetOpen(ctx, task.ID, workerId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	seconds := time.Since(taskOpen.OpenedAt).Seconds()
+	return &TimeOnTask{
+		OpenedAt:         taskOpen.OpenedAt,
+		Seconds:          seconds,
+		IsFastSubmission: seconds < float64(s.MinSeconds(task.Modality)),
+	}, nil
+}
+
+func (s *TimeOnTaskService) MinSeconds(modality db.TaskModality) int {
+	if minSeconds, ok := s.minSeconds[modality]; ok {
+		return minSeconds
+	}
+	return defaultMinTimeOnTaskSeconds
+}
+
+// GetTimeOnTaskMetrics summarises the time on task per modality, of every worker if workerId is empty
+func (s *TimeOnTaskService) GetTimeOnTaskMetrics(ctx context.Context, workerId string) (*TimeOnTaskMetricsResponse, error) {
+	stats, err := s.taskOpenORM.GetTimeOnTaskStats(ctx, workerId)
+	if err != nil {
+		log.Error().Err(err).Str("workerId", workerId).Msg("Error getting time on task stats")
+		return nil, err
+	}
+
+	modalities := make([]ModalityTimeOnTask, 0, len(stats))
+	for _, stat := range stats {
+		modalities = append(modalities, ModalityTimeOnTask{
+			Modality:           stat.Modality,
+			NumResults:         stat.NumResults,
+			AverageSeconds:     stat.AverageSeconds,
+			MedianSeconds:      stat.MedianSeconds,
+			P90Seconds:         stat.P90Seconds,
+			NumFastSubmissions: stat.NumFastSubmissions,
+			NumUntimedResults:  stat.NumUntimedResults,
+			MinSeconds:         s.MinSeconds(stat.Modality),
+		})
+	}
+	return &TimeOnTaskMetricsResponse{Modalities: modalities}, nil
+}
@@ -0,0 +1,309 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"dojo-api/db"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	deliveryPollInterval = 2 * time.Second
+	deliveryTimeout      = 10 * time.Second
+	// a claimed delivery becomes due again if the replica that claimed it dies before finishing it
+	deliveryClaimTimeout = 60 * time.Second
+	deliveryBatchSize    = 50
+	deliveryConcurrency  = 10
+
+	// retries wait 30s, 1m, 2m, ... up to 6h, plus up to 20% jitter
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+
+	// queued deliveries are dropped if they are somehow never processed
+	deliveryTTL       = 7 * 24 * time.Hour
+	deliveryLogTTL    = 7 * 24 * time.Hour
+	maxLogEntries     = 100
+	maxDeadLetters    = 1000
+	maxResponseToRead = 64 << 10
+)
+
+// The queue is a sorted set of delivery IDs scored by when they are next due (unix ms). Claiming pushes the
+// score of due deliveries out by the claim timeout, so concurrent replicas never attempt the same delivery.
+//
+// KEYS[1] = queue
+// ARGV[1] = now, ARGV[2] = claim expiry, ARGV[3] = limit
+var claimDeliveriesScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[3]))
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+var errPrivateAddress = errors.New("webhook url resolves to a private address")
+
+// newDeliveryClient does not follow redirects, and unless allowInsecure refuses to connect to loopback,
+// private and link local addresses. The check runs on the resolved address, so DNS cannot be used to get around it.
+func newDeliveryClient(allowInsecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowInsecure {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   deliveryTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (s *WebhookService) deliveryKey(deliveryId string) string {
+	return s.cache.BuildCacheKey(s.cache.Keys.WebhookDelivery, deliveryId)
+}
+
+func (s *WebhookService) logKey(webhookId string) string {
+	return s.cache.BuildCacheKey(s.cache.Keys.WebhookLog, webhookId)
+}
+
+func (s *WebhookService) deadLetterKey(webhookId string) string {
+	return s.cache.BuildCacheKey(s.cache.Keys.WebhookDeadLetter, webhookId)
+}
+
+// enqueue stores the delivery and makes it due immediately
+func (s *WebhookService) enqueue(ctx context.Context, webhookId string, payload Payload) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(delivery{
+		Id:        payload.DeliveryId,
+		WebhookId: webhookId,
+		Event:     payload.Event,
+		Payload:   payloadJSON,
+		CreatedAt: payload.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	pipe := s.cache.Redis.TxPipeline()
+	pipe.Set(ctx, s.deliveryKey(payload.DeliveryId), data, deliveryTTL)
+	pipe.ZAdd(ctx, string(s.cache.Keys.WebhookQueue), redis.Z{Score: float64(time.Now().UnixMilli()), Member: payload.DeliveryId})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	log.Debug().Str("deliveryId", payload.DeliveryId).Str("webhookId", webhookId).Str("event", string(payload.Event)).Msg("Webhook delivery queued")
+	return nil
+}
+
+// ProcessDeliveries continuously attempts the deliveries that are due, on every replica
+func (s *WebhookService) ProcessDeliveries(ctx context.Context) {
+	for range time.Tick(deliveryPollInterval) {
+		for {
+			numClaimed, err := s.processDueDeliveries(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Error processing webhook deliveries")
+				break
+			}
+			if numClaimed < deliveryBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// processDueDeliveries claims a batch of due deliveries and attempts them concurrently
+func (s *WebhookService) processDueDeliveries(ctx context.Context) (int, error) {
+	now := time.Now()
+	deliveryIds, err := claimDeliveriesScript.Run(ctx, &s.cache.Redis,
+		[]string{string(s.cache.Keys.WebhookQueue)},
+		now.UnixMilli(), now.Add(deliveryClaimTimeout).UnixMilli(), deliveryBatchSize,
+	).StringSlice()
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, deliveryConcurrency)
+	for _, deliveryId := range deliveryIds {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(deliveryId string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := s.processDelivery(ctx, deliveryId); err != nil {
+				log.Error().Err(err).Str("deliveryId", deliveryId).Msg("Error processing webhook delivery")
+			}
+		}(deliveryId)
+	}
+	wg.Wait()
+
+	return len(deliveryIds), nil
+}
+
+func (s *WebhookService) processDelivery(ctx context.Context, deliveryId string) error {
+	queueKey := string(s.cache.Keys.WebhookQueue)
+
+	data, err := s.cache.Redis.Get(ctx, s.deliveryKey(deliveryId)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return s.cache.Redis.ZRem(ctx, queueKey, deliveryId).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	var d delivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		log.Error().Err(err).Str("deliveryId", deliveryId).Msg("Dropping invalid webhook delivery")
+		return s.drop(ctx, deliveryId)
+	}
+
+	webhook, err := s.webhookORM.GetActiveWebhook(ctx, d.WebhookId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			log.Info().Str("deliveryId", deliveryId).Str("webhookId", d.WebhookId).Msg("Webhook was deleted, dropping delivery")
+			return s.drop(ctx, deliveryId)
+		}
+		return err
+	}
+
+	d.Attempts++
+	attempt := DeliveryAttempt{
+		DeliveryId:  d.Id,
+		Event:       d.Event,
+		Attempt:     d.Attempts,
+		AttemptedAt: time.Now().UTC(),
+	}
+	statusCode, err := s.post(ctx, webhook.URL, webhook.Secret, &d)
+	attempt.DurationMs = time.Since(attempt.AttemptedAt).Milliseconds()
+	attempt.StatusCode = statusCode
+
+	pipe := s.cache.Redis.TxPipeline()
+	switch {
+	case err == nil:
+		attempt.Outcome = DeliveryOutcomeDelivered
+		pipe.Del(ctx, s.deliveryKey(d.Id))
+		pipe.ZRem(ctx, queueKey, d.Id)
+	case d.Attempts >= s.maxAttempts:
+		attempt.Error = err.Error()
+		attempt.Outcome = DeliveryOutcomeDead
+		deadLetter, marshalErr := json.Marshal(DeadLetter{
+			DeliveryId: d.Id,
+			Event:      d.Event,
+			Attempts:   d.Attempts,
+			CreatedAt:  d.CreatedAt,
+			DeadAt:     time.Now().UTC(),
+			LastError:  err.Error(),
+			Payload:    d.Payload,
+		})
+		if marshalErr != nil {
+			return marshalErr
+		}
+		pipe.LPush(ctx, s.deadLetterKey(d.WebhookId), deadLetter)
+		pipe.LTrim(ctx, s.deadLetterKey(d.WebhookId), 0, maxDeadLetters-1)
+		pipe.Del(ctx, s.deliveryKey(d.Id))
+		pipe.ZRem(ctx, queueKey, d.Id)
+	default:
+		attempt.Error = err.Error()
+		attempt.Outcome = DeliveryOutcomeRetrying
+		nextAttemptAt := time.Now().Add(retryDelay(d.Attempts)).UTC()
+		attempt.NextAttemptAt = &nextAttemptAt
+		d.LastError = err.Error()
+		updated, marshalErr := json.Marshal(d)
+		if marshalErr != nil {
+			return marshalErr
+		}
+		pipe.Set(ctx, s.deliveryKey(d.Id), updated, deliveryTTL)
+		pipe.ZAdd(ctx, queueKey, redis.Z{Score: float64(nextAttemptAt.UnixMilli()), Member: d.Id})
+	}
+
+	attemptJSON, err := json.Marshal(attempt)
+	if err != nil {
+		return err
+	}
+	pipe.LPush(ctx, s.logKey(d.WebhookId), attemptJSON)
+	pipe.LTrim(ctx, s.logKey(d.WebhookId), 0, maxLogEntries-1)
+	pipe.Expire(ctx, s.logKey(d.WebhookId), deliveryLogTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	log.Info().Str("deliveryId", d.Id).Str("webhookId", d.WebhookId).Str("event", string(d.Event)).Int("attempt", d.Attempts).Int("statusCode", statusCode).Str("outcome", string(attempt.Outcome)).Msg("Webhook delivery attempted")
+	return nil
+}
+
+// post signs and sends the delivery's payload, any response other than 2xx is an error
+func (s *WebhookService) post(ctx context.Context, url string, secret string, d *delivery) (int, error) {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Dojo-Webhook/1.0")
+	req.Header.Set(HeaderEvent, string(d.Event))
+	req.Header.Set(HeaderDelivery, d.Id)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain some of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseToRead))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// drop removes a delivery that can no longer be delivered without dead lettering it
+func (s *WebhookService) drop(ctx context.Context, deliveryId string) error {
+	pipe := s.cache.Redis.TxPipeline()
+	pipe.Del(ctx, s.deliveryKey(deliveryId))
+	pipe.ZRem(ctx, string(s.cache.Keys.WebhookQueue), deliveryId)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// retryDelay doubles from retryBaseDelay after every failed attempt, up to retryMaxDelay
+func retryDelay(attempts int) time.Duration {
+	delay := retryMaxDelay
+	if attempts <= 20 {
+		delay = min(retryBaseDelay<<(attempts-1), retryMaxDelay)
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,380 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/orm"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// receivedDelivery is a request made to the test receiver
+type receivedDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// testReceiver is a webhook endpoint responding with a configurable status code
+type testReceiver struct {
+	server     *httptest.Server
+	statusCode atomic.Int32
+	mu         sync.Mutex
+	received   []receivedDelivery
+}
+
+func newTestReceiver(t *testing.T) *testReceiver {
+	t.Helper()
+	receiver := &testReceiver{}
+	receiver.statusCode.Store(http.StatusOK)
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.received = append(receiver.received, receivedDelivery{header: r.Header.Clone(), body: body})
+		receiver.mu.Unlock()
+		w.WriteHeader(int(receiver.statusCode.Load()))
+	}))
+	t.Cleanup(receiver.server.Close)
+	return receiver
+}
+
+// take returns and forgets the requests received so far
+func (r *testReceiver) take() []receivedDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	received := r.received
+	r.received = nil
+	return received
+}
+
+// verifyDelivery checks the headers and signature of a delivery and returns its payload
+func verifyDelivery(t *testing.T, delivery receivedDelivery, secret string, event Event) Payload {
+	t.Helper()
+	if got := delivery.header.Get(HeaderEvent); got != string(event) {
+		t.Errorf("%s = %q, want %q", HeaderEvent, got, event)
+	}
+	if delivery.header.Get(HeaderDelivery) == "" {
+		t.Errorf("%s is empty", HeaderDelivery)
+	}
+	if got := delivery.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	timestamp, err := strconv.ParseInt(delivery.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", HeaderTimestamp, err)
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age < -time.Minute || age > time.Minute {
+		t.Errorf("%s is %s old", HeaderTimestamp, age)
+	}
+	if !VerifySignature(secret, timestamp, delivery.body, delivery.header.Get(HeaderSignature)) {
+		t.Errorf("invalid %s %q", HeaderSignature, delivery.header.Get(HeaderSignature))
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(delivery.body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload.Event != event {
+		t.Errorf("payload event = %q, want %q", payload.Event, event)
+	}
+	if payload.DeliveryId != delivery.header.Get(HeaderDelivery) {
+		t.Errorf("payload deliveryId = %q, want the %s header %q", payload.DeliveryId, HeaderDelivery, delivery.header.Get(HeaderDelivery))
+	}
+	return payload
+}
+
+func TestPostSignsPayload(t *testing.T) {
+	receiver := newTestReceiver(t)
+	s := &WebhookService{client: newDeliveryClient(true)}
+
+	deliveryId := uuid.NewString()
+	payload, err := json.Marshal(Payload{DeliveryId: deliveryId, Event: EventTaskCompleted, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("marshalling payload: %v", err)
+	}
+	d := &delivery{Id: deliveryId, Event: EventTaskCompleted, Payload: payload}
+	statusCode, err := s.post(context.Background(), receiver.server.URL, "whsec_test", d)
+	if err != nil {
+		t.Fatalf("post() error = %v", err)
+	}
+	if statusCode != http.StatusOK {
+		t.Errorf("post() status = %d, want %d", statusCode, http.StatusOK)
+	}
+
+	received := receiver.take()
+	if len(received) != 1 {
+		t.Fatalf("received %d requests, want 1", len(received))
+	}
+	verifyDelivery(t, received[0], "whsec_test", EventTaskCompleted)
+	if string(received[0].body) != string(d.Payload) {
+		t.Errorf("body = %s, want %s", received[0].body, d.Payload)
+	}
+}
+
+func TestPostFailsOnNon2xx(t *testing.T) {
+	receiver := newTestReceiver(t)
+	s := &WebhookService{client: newDeliveryClient(true)}
+
+	for _, code := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		receiver.statusCode.Store(int32(code))
+		d := &delivery{Id: uuid.NewString(), Event: EventTaskExpired, Payload: []byte(`{}`)}
+		statusCode, err := s.post(context.Background(), receiver.server.URL, "whsec_test", d)
+		if err == nil {
+			t.Errorf("post() with status %d succeeded, want an error", code)
+		}
+		if statusCode != code {
+			t.Errorf("post() status = %d, want %d", statusCode, code)
+		}
+	}
+}
+
+func TestDeliveryClientRefusesPrivateAddresses(t *testing.T) {
+	receiver := newTestReceiver(t)
+	s := &WebhookService{client: newDeliveryClient(false)}
+
+	d := &delivery{Id: uuid.NewString(), Event: EventTaskCompleted, Payload: []byte(`{}`)}
+	_, err := s.post(context.Background(), receiver.server.URL, "whsec_test", d)
+	if !errors.Is(err, errPrivateAddress) {
+		t.Errorf("post() to loopback error = %v, want %v", err, errPrivateAddress)
+	}
+	if received := receiver.take(); len(received) != 0 {
+		t.Errorf("loopback receiver got %d requests, want none", len(received))
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, retryBaseDelay},
+		{2, 2 * retryBaseDelay},
+		{3, 4 * retryBaseDelay},
+		{6, 32 * retryBaseDelay},
+		{9, 256 * retryBaseDelay},
+		{10, 512 * retryBaseDelay},
+		{11, retryMaxDelay},
+		{50, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := retryDelay(tt.attempts)
+			// up to 20% jitter on top of the base delay
+			if delay < tt.base || delay > tt.base+tt.base/5 {
+				t.Errorf("retryDelay(%d) = %s, want between %s and %s", tt.attempts, delay, tt.base, tt.base+tt.base/5)
+				break
+			}
+		}
+	}
+}
+
+// newTestService connects to the database and redis of the environment, tests needing them are skipped without one
+func newTestService(t *testing.T) *WebhookService {
+	t.Helper()
+	for _, name := range []string{"DB_HOST", "DB_NAME", "REDIS_HOST", "REDIS_PORT"} {
+		if os.Getenv(name) == "" {
+			t.Skipf("%s not set, skipping webhook delivery test", name)
+		}
+	}
+	if os.Getenv("RUNTIME_ENV") == "" {
+		t.Setenv("RUNTIME_ENV", "local")
+	}
+	// the test receiver listens on loopback over http
+	t.Setenv("WEBHOOK_ALLOW_INSECURE", "true")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "2")
+	return newWebhookService()
+}
+
+// createTestWebhook registers a webhook subscribed to every event for a new miner and creates a task of that miner
+func createTestWebhook(t *testing.T, s *WebhookService, url string) (*WebhookResponse, string, *db.TaskModel) {
+	t.Helper()
+	ctx := context.Background()
+	client := orm.GetPrismaClient().Client
+
+	miner, err := client.MinerUser.CreateOne(db.MinerUser.Hotkey.Set("test-" + uuid.NewString())).Exec(ctx)
+	if err != nil {
+		t.Fatalf("creating miner: %v", err)
+	}
+	apiKey, err := client.APIKey.CreateOne(
+		db.APIKey.Key.Set("test-"+uuid.NewString()),
+		db.APIKey.MinerUser.Link(db.MinerUser.ID.Equals(miner.ID)),
+	).Exec(ctx)
+	if err != nil {
+		t.Fatalf("creating api key: %v", err)
+	}
+
+	webhook, err := s.CreateWebhook(ctx, apiKey.ID, miner.ID, CreateWebhookRequest{URL: url, Events: ValidEvents})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+
+	task, err := client.Task.CreateOne(
+		db.Task.ExpireAt.Set(time.Now().Add(time.Hour)),
+		db.Task.Title.Set("test task"),
+		db.Task.Body.Set("test task"),
+		db.Task.Modality.Set(db.TaskModalityCodeGeneration),
+		db.Task.TaskData.Set(db.JSON(`{"task_data":[]}`)),
+		db.Task.Status.Set(db.TaskStatusInProgress),
+		db.Task.MaxResults.Set(1),
+		db.Task.NumResults.Set(0),
+		db.Task.MinerUser.Link(db.MinerUser.ID.Equals(miner.ID)),
+	).Exec(ctx)
+	if err != nil {
+		t.Fatalf("creating task: %v", err)
+	}
+	return webhook, apiKey.ID, task
+}
+
+// makeDue moves a queued delivery to the front of the queue, skipping its retry delay
+func makeDue(t *testing.T, s *WebhookService, deliveryId string) {
+	t.Helper()
+	err := s.cache.Redis.ZAdd(context.Background(), string(s.cache.Keys.WebhookQueue), redis.Z{Score: 0, Member: deliveryId}).Err()
+	if err != nil {
+		t.Fatalf("making delivery due: %v", err)
+	}
+}
+
+func processDue(t *testing.T, s *WebhookService) {
+	t.Helper()
+	if _, err := s.processDueDeliveries(context.Background()); err != nil {
+		t.Fatalf("processDueDeliveries() error = %v", err)
+	}
+}
+
+func TestDeliverTaskEvents(t *testing.T) {
+	s := newTestService(t)
+	receiver := newTestReceiver(t)
+	webhook, _, task := createTestWebhook(t, s, receiver.server.URL)
+	ctx := context.Background()
+
+	for _, event := range ValidEvents {
+		t.Run(string(event), func(t *testing.T) {
+			if err := s.NotifyTaskEvent(ctx, event, task); err != nil {
+				t.Fatalf("NotifyTaskEvent() error = %v", err)
+			}
+			processDue(t, s)
+
+			received := receiver.take()
+			if len(received) != 1 {
+				t.Fatalf("received %d deliveries, want 1", len(received))
+			}
+			payload := verifyDelivery(t, received[0], webhook.Secret, event)
+			if payload.Data.TaskId != task.ID {
+				t.Errorf("payload taskId = %q, want %q", payload.Data.TaskId, task.ID)
+			}
+		})
+	}
+}
+
+func TestDeliveryRetriesServerErrors(t *testing.T) {
+	s := newTestService(t)
+	receiver := newTestReceiver(t)
+	webhook, apiKeyId, task := createTestWebhook(t, s, receiver.server.URL)
+	ctx := context.Background()
+
+	receiver.statusCode.Store(http.StatusServiceUnavailable)
+	if err := s.NotifyTaskEvent(ctx, EventTaskCompleted, task); err != nil {
+		t.Fatalf("NotifyTaskEvent() error = %v", err)
+	}
+	processDue(t, s)
+
+	received := receiver.take()
+	if len(received) != 1 {
+		t.Fatalf("received %d deliveries, want 1", len(received))
+	}
+	deliveryId := received[0].header.Get(HeaderDelivery)
+
+	deliveryLog, err := s.GetDeliveryLog(ctx, webhook.Id, apiKeyId)
+	if err != nil {
+		t.Fatalf("GetDeliveryLog() error = %v", err)
+	}
+	if len(deliveryLog.Attempts) != 1 {
+		t.Fatalf("%d attempts logged, want 1", len(deliveryLog.Attempts))
+	}
+	first := deliveryLog.Attempts[0]
+	if first.Outcome != DeliveryOutcomeRetrying || first.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("first attempt = %s with status %d, want %s with status %d", first.Outcome, first.StatusCode, DeliveryOutcomeRetrying, http.StatusServiceUnavailable)
+	}
+	if first.NextAttemptAt == nil || first.NextAttemptAt.Before(first.AttemptedAt.Add(retryBaseDelay)) {
+		t.Errorf("next attempt at %v, want at least %s after %v", first.NextAttemptAt, retryBaseDelay, first.AttemptedAt)
+	}
+
+	// not retried before its delay
+	processDue(t, s)
+	if received := receiver.take(); len(received) != 0 {
+		t.Fatalf("retried before the retry delay, received %d deliveries", len(received))
+	}
+
+	receiver.statusCode.Store(http.StatusOK)
+	makeDue(t, s, deliveryId)
+	processDue(t, s)
+
+	received = receiver.take()
+	if len(received) != 1 {
+		t.Fatalf("received %d retries, want 1", len(received))
+	}
+	verifyDelivery(t, received[0], webhook.Secret, EventTaskCompleted)
+	if got := received[0].header.Get(HeaderDelivery); got != deliveryId {
+		t.Errorf("retry delivery id = %q, want %q", got, deliveryId)
+	}
+
+	deliveryLog, err = s.GetDeliveryLog(ctx, webhook.Id, apiKeyId)
+	if err != nil {
+		t.Fatalf("GetDeliveryLog() error = %v", err)
+	}
+	if latest := deliveryLog.Attempts[0]; latest.Outcome != DeliveryOutcomeDelivered || latest.Attempt != 2 {
+		t.Errorf("latest attempt = %s attempt %d, want %s attempt 2", latest.Outcome, latest.Attempt, DeliveryOutcomeDelivered)
+	}
+}
+
+func TestDeliveryDeadLettersAfterMaxAttempts(t *testing.T) {
+	s := newTestService(t)
+	receiver := newTestReceiver(t)
+	webhook, apiKeyId, task := createTestWebhook(t, s, receiver.server.URL)
+	ctx := context.Background()
+
+	receiver.statusCode.Store(http.StatusInternalServerError)
+	if err := s.NotifyTaskEvent(ctx, EventTaskExpired, task); err != nil {
+		t.Fatalf("NotifyTaskEvent() error = %v", err)
+	}
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		processDue(t, s)
+		received := receiver.take()
+		if len(received) != 1 {
+			t.Fatalf("attempt %d: received %d deliveries, want 1", attempt, len(received))
+		}
+		if attempt < s.maxAttempts {
+			makeDue(t, s, received[0].header.Get(HeaderDelivery))
+		}
+	}
+
+	// dead lettered deliveries are not attempted again
+	processDue(t, s)
+	if received := receiver.take(); len(received) != 0 {
+		t.Errorf("received %d deliveries after the last attempt, want none", len(received))
+	}
+
+	deliveryLog, err := s.GetDeliveryLog(ctx, webhook.Id, apiKeyId)
+	if err != nil {
+		t.Fatalf("GetDeliveryLog() error = %v", err)
+	}
+	if len(deliveryLog.DeadLetters) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(deliveryLog.DeadLetters))
+	}
+	if deadLetter := deliveryLog.DeadLetters[0]; deadLetter.Attempts != s.maxAttempts || deadLetter.Event != EventTaskExpired {
+		t.Errorf("dead letter = %s after %d attempts, want %s after %d", deadLetter.Event, deadLetter.Attempts, EventTaskExpired, s.maxAttempts)
+	}
+}
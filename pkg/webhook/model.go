@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"dojo-api/db"
+)
+
+type Event string
+
+const (
+	EventTaskCompleted   Event = "task.completed"
+	EventTaskExpired     Event = "task.expired"
+	EventResultSubmitted Event = "task.result_submitted"
+)
+
+var ValidEvents = []Event{EventTaskCompleted, EventTaskExpired, EventResultSubmitted}
+
+// request headers of every delivery
+const (
+	HeaderEvent     = "X-Dojo-Event"
+	HeaderDelivery  = "X-Dojo-Delivery"
+	HeaderTimestamp = "X-Dojo-Timestamp"
+	HeaderSignature = "X-Dojo-Signature"
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("url must be an absolute https URL")
+	ErrInvalidEvents     = errors.New("events must be one or more of task.completed, task.expired and task.result_submitted")
+	ErrWebhookNotFound   = errors.New("webhook not found")
+	ErrWebhookExists     = errors.New("a webhook with this url is already registered with this API key")
+	ErrTooManyWebhooks   = errors.New("too many webhooks are registered with this API key")
+)
+
+type CreateWebhookRequest struct {
+	URL    string  `json:"url" binding:"required"`
+	Events []Event `json:"events" binding:"required"`
+}
+
+type WebhookResponse struct {
+	Id        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []Event   `json:"events"`
+	CreatedAt time.Time `json:"createdAt"`
+	// only returned when the webhook is created, used to verify the signature of every delivery
+	Secret string `json:"secret,omitempty"`
+}
+
+// Payload is the JSON body POSTed to a webhook
+type Payload struct {
+	DeliveryId string        `json:"deliveryId"`
+	Event      Event         `json:"event"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	Data       TaskEventData `json:"data"`
+}
+
+type TaskEventData struct {
+	TaskId     string        `json:"taskId"`
+	Status     db.TaskStatus `json:"status"`
+	NumResults int           `json:"numResults"`
+	MaxResults int           `json:"maxResults"`
+	ExpireAt   time.Time     `json:"expireAt"`
+}
+
+// delivery is a payload queued for a webhook, stored in redis until it is delivered or dead lettered
+type delivery struct {
+	Id        string          `json:"id"`
+	WebhookId string          `json:"webhookId"`
+	Event     Event           `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"createdAt"`
+	LastError string          `json:"lastError,omitempty"`
+}
+
+type DeliveryOutcome string
+
+const (
+	DeliveryOutcomeDelivered DeliveryOutcome = "delivered"
+	DeliveryOutcomeRetrying  DeliveryOutcome = "retrying"
+	DeliveryOutcomeDead      DeliveryOutcome = "dead"
+)
+
+// DeliveryAttempt is an entry of a webhook's delivery log
+type DeliveryAttempt struct {
+	DeliveryId    string          `json:"deliveryId"`
+	Event         Event           `json:"event"`
+	Attempt       int             `json:"attempt"`
+	AttemptedAt   time.Time       `json:"attemptedAt"`
+	DurationMs    int64           `json:"durationMs"`
+	StatusCode    int             `json:"statusCode,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	Outcome       DeliveryOutcome `json:"outcome"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+}
+
+// DeadLetter is a delivery that failed every attempt
+type DeadLetter struct {
+	DeliveryId string          `json:"deliveryId"`
+	Event      Event           `json:"event"`
+	Attempts   int             `json:"attempts"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	DeadAt     time.Time       `json:"deadAt"`
+	LastError  string          `json:"lastError"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type DeliveryLogResponse struct {
+	WebhookId string `json:"webhookId"`
+	// most recent first
+	Attempts    []DeliveryAttempt `json:"attempts"`
+	DeadLetters []DeadLetter      `json:"deadLetters"`
+}
@@ -0,0 +1,39 @@
+package webhook
+
+import "testing"
+
+func TestSign(t *testing.T) {
+	// hmac-sha256 of "1700000000.{\"event\":\"task.completed\"}" keyed with "whsec_test"
+	const want = "sha256=72d60e3c2ab752b968d31a79e53de5d105671d4180995b39eaca9508bececd25"
+	if got := Sign("whsec_test", 1700000000, []byte(`{"event":"task.completed"}`)); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"event":"task.completed"}`)
+	signature := Sign("whsec_test", 1700000000, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"valid", "whsec_test", 1700000000, body, signature, true},
+		{"wrong secret", "whsec_other", 1700000000, body, signature, false},
+		{"wrong timestamp", "whsec_test", 1700000001, body, signature, false},
+		{"tampered body", "whsec_test", 1700000000, []byte(`{"event":"task.expired"}`), signature, false},
+		{"missing prefix", "whsec_test", 1700000000, body, signature[len(signaturePrefix):], false},
+		{"empty", "whsec_test", 1700000000, body, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.timestamp, tt.body, tt.signature); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
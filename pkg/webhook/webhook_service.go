@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"dojo-api/db"
+	"dojo-api/pkg/cache"
+	"dojo-api/pkg/orm"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxWebhooksPerApiKey = 10
+	defaultMaxAttempts   = 8
+)
+
+var (
+	webhookService     *WebhookService
+	webhookServiceOnce sync.Once
+)
+
+type WebhookService struct {
+	webhookORM  *orm.WebhookORM
+	cache       *cache.Cache
+	client      *http.Client
+	maxAttempts int
+	// allows plain http and private addresses, for local development only
+	allowInsecure bool
+}
+
+// newWebhookService reads the number of delivery attempts before a delivery is dead lettered from
+// WEBHOOK_MAX_ATTEMPTS. WEBHOOK_ALLOW_INSECURE=true allows http URLs and private addresses for local development.
+func newWebhookService() *WebhookService {
+	maxAttempts := defaultMaxAttempts
+	if maxAttemptsStr := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); maxAttemptsStr != "" {
+		if value, err := strconv.Atoi(maxAttemptsStr); err == nil && value > 0 {
+			maxAttempts = value
+		} else {
+			log.Warn().Str("WEBHOOK_MAX_ATTEMPTS", maxAttemptsStr).Msg("Invalid webhook max attempts, using default")
+		}
+	}
+	allowInsecure := os.Getenv("WEBHOOK_ALLOW_INSECURE") == "true"
+
+	return &WebhookService{
+		webhookORM:    orm.NewWebhookORM(),
+		cache:         cache.GetCacheInstance(),
+		client:        newDeliveryClient(allowInsecure),
+		maxAttempts:   maxAttempts,
+		allowInsecure: allowInsecure,
+	}
+}
+
+// GetWebhookService returns the service shared by the whole server, so every delivery goes through the same http
+// client and reuses its connections
+func GetWebhookService() *WebhookService {
+	webhookServiceOnce.Do(func() {
+		webhookService = newWebhookService()
+	})
+	return webhookService
+}
+
+// CreateWebhook registers a webhook with the miner's API key, the response holds the signing secret
+func (s *WebhookService) CreateWebhook(ctx context.Context, apiKeyId string, minerUserId string, request CreateWebhookRequest) (*WebhookResponse, error) {
+	if err := s.validateURL(request.URL); err != nil {
+		return nil, err
+	}
+	events, err := validateEvents(request.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookORM.GetWebhooksByApiKey(ctx, apiKeyId)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) >= maxWebhooksPerApiKey {
+		return nil, ErrTooManyWebhooks
+	}
+	for _, webhook := range webhooks {
+		if webhook.URL == request.URL {
+			return nil, ErrWebhookExists
+		}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	eventStrs := make([]string, 0, len(events))
+	for _, event := range events {
+		eventStrs = append(eventStrs, string(event))
+	}
+	webhook, err := s.webhookORM.CreateWebhook(ctx, apiKeyId, minerUserId, request.URL, secret, eventStrs)
+	if err != nil {
+		log.Error().Err(err).Str("minerUserId", minerUserId).Msg("Error creating webhook")
+		return nil, err
+	}
+
+	log.Info().Str("webhookId", webhook.ID).Str("minerUserId", minerUserId).Msg("Webhook created")
+	response := buildWebhookResponse(webhook)
+	response.Secret = secret
+	return &response, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, apiKeyId string) ([]WebhookResponse, error) {
+	webhooks, err := s.webhookORM.GetWebhooksByApiKey(ctx, apiKeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		responses = append(responses, buildWebhookResponse(&webhooks[i]))
+	}
+	return responses, nil
+}
+
+// DeleteWebhook stops deliveries to a webhook, deliveries already queued for it are dropped
+func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookId string, apiKeyId string) error {
+	deleted, err := s.webhookORM.DeleteWebhook(ctx, webhookId, apiKeyId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	log.Info().Str("webhookId", webhookId).Msg("Webhook deleted")
+	return nil
+}
+
+// NotifyTaskEvent queues a delivery of the event to every webhook of the task's miner subscribed to it
+func (s *WebhookService) NotifyTaskEvent(ctx context.Context, event Event, task *db.TaskModel) error {
+	minerUserId, ok := task.MinerUserID()
+	if !ok {
+		return nil
+	}
+
+	webhooks, err := s.webhookORM.GetWebhooksForEvent(ctx, minerUserId, string(event))
+	if err != nil {
+		log.Error().Err(err).Str("taskId", task.ID).Str("event", string(event)).Msg("Error fetching webhooks for event")
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		payload := Payload{
+			DeliveryId: uuid.New().String(),
+			Event:      event,
+			CreatedAt:  time.Now().UTC(),
+			Data: TaskEventData{
+				TaskId:     task.ID,
+				Status:     task.Status,
+				NumResults: task.NumResults,
+				MaxResults: task.MaxResults,
+				ExpireAt:   task.ExpireAt,
+			},
+		}
+		if err := s.enqueue(ctx, webhook.ID, payload); err != nil {
+			log.Error().Err(err).Str("webhookId", webhook.ID).Str("taskId", task.ID).Str("event", string(event)).Msg("Error queueing webhook delivery")
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyTasksExpired queues task.expired deliveries for tasks marked as EXPIRED by the expiry loop
+func (s *WebhookService) NotifyTasksExpired(ctx context.Context, tasks []db.TaskModel) {
+	for i := range tasks {
+		if err := s.NotifyTaskEvent(ctx, EventTaskExpired, &tasks[i]); err != nil {
+			log.Error().Err(err).Str("taskId", tasks[i].ID).Msg("Failed to notify webhooks of expired task")
+		}
+	}
+}
+
+// GetDeliveryLog returns the recent delivery attempts and dead letters of a webhook registered with the API key
+func (s *WebhookService) GetDeliveryLog(ctx context.Context, webhookId string, apiKeyId string) (*DeliveryLogResponse, error) {
+	webhooks, err := s.webhookORM.GetWebhooksByApiKey(ctx, apiKeyId)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(webhooks, func(webhook db.WebhookModel) bool { return webhook.ID == webhookId }) {
+		return nil, ErrWebhookNotFound
+	}
+
+	response := &DeliveryLogResponse{
+		WebhookId:   webhookId,
+		Attempts:    make([]DeliveryAttempt, 0),
+		DeadLetters: make([]DeadLetter, 0),
+	}
+
+	attempts, err := s.cache.Redis.LRange(ctx, s.logKey(webhookId), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range attempts {
+		var attempt DeliveryAttempt
+		if err := json.Unmarshal([]byte(data), &attempt); err != nil {
+			log.Warn().Err(err).Str("webhookId", webhookId).Msg("Skipping invalid webhook delivery log entry")
+			continue
+		}
+		response.Attempts = append(response.Attempts, attempt)
+	}
+
+	deadLetters, err := s.cache.Redis.LRange(ctx, s.deadLetterKey(webhookId), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range deadLetters {
+		var deadLetter DeadLetter
+		if err := json.Unmarshal([]byte(data), &deadLetter); err != nil {
+			log.Warn().Err(err).Str("webhookId", webhookId).Msg("Skipping invalid webhook dead letter")
+			continue
+		}
+		response.DeadLetters = append(response.DeadLetters, deadLetter)
+	}
+
+	return response, nil
+}
+
+func (s *WebhookService) validateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || parsed.User != nil {
+		return ErrInvalidWebhookURL
+	}
+	if parsed.Scheme == "https" || (s.allowInsecure && parsed.Scheme == "http") {
+		return nil
+	}
+	return ErrInvalidWebhookURL
+}
+
+// validateEvents returns the distinct events, which must all be valid
+func validateEvents(events []Event) ([]Event, error) {
+	if len(events) == 0 {
+		return nil, ErrInvalidEvents
+	}
+
+	distinct := make([]Event, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(ValidEvents, event) {
+			return nil, ErrInvalidEvents
+		}
+		if !slices.Contains(distinct, event) {
+			distinct = append(distinct, event)
+		}
+	}
+	return distinct, nil
+}
+
+func buildWebhookResponse(webhook *db.WebhookModel) WebhookResponse {
+	events := make([]Event, 0, len(webhook.Events))
+	for _, event := range webhook.Events {
+		events = append(events, Event(event))
+	}
+	return WebhookResponse{
+		Id:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    events,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
@@ -2,6 +2,10 @@ package worker
 
 import (
 	"time"
+
+	"dojo-api/pkg/task"
+
+	"github.com/shopspring/decimal"
 )
 
 type WorkerPartner struct {
@@ -60,3 +64,50 @@ type WorkerPartnerCreateRequest struct {
 type GenerateNonceResponse struct {
 	Nonce string `json:"nonce"`
 }
+
+const (
+	DefaultRewardsLimit = 50
+	MaxRewardsLimit     = 200
+
+	DefaultLedgerPageSize = 20
+	MaxLedgerPageSize     = 100
+)
+
+type WorkerReward struct {
+	TaskId          string    `json:"taskId"`
+	TaskResultId    string    `json:"taskResultId"`
+	Status          string    `json:"status"`
+	FinalisedReward float64   `json:"finalisedReward"`
+	FinalisedLoss   float64   `json:"finalisedLoss"`
+	SettledAt       time.Time `json:"settledAt"`
+}
+
+// totals are over the returned rewards only
+type WorkerRewardsResponse struct {
+	Rewards     []WorkerReward `json:"rewards"`
+	TotalReward float64        `json:"totalReward"`
+	TotalLoss   float64        `json:"totalLoss"`
+}
+
+// amounts are exact decimals, encoded as strings
+type WorkerBalanceResponse struct {
+	Available     decimal.Decimal `json:"available" swaggertype:"string"`
+	PendingPayout decimal.Decimal `json:"pendingPayout" swaggertype:"string"`
+	PaidOut       decimal.Decimal `json:"paidOut" swaggertype:"string"`
+}
+
+type LedgerEntry struct {
+	Id            string          `json:"id"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	TransactionId string          `json:"transactionId"`
+	Type          string          `json:"type"`
+	Account       string          `json:"account"`
+	Amount        decimal.Decimal `json:"amount" swaggertype:"string"`
+	TaskId        string          `json:"taskId,omitempty"`
+	TaskResultId  string          `json:"taskResultId,omitempty"`
+}
+
+type LedgerHistoryResponse struct {
+	Entries    []LedgerEntry   `json:"entries"`
+	Pagination task.Pagination `json:"pagination"`
+}